@@ -10,10 +10,12 @@ import (
 	"github.com/Dyleme/image-coverter/internal/handler"
 	"github.com/Dyleme/image-coverter/internal/jwt"
 	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/queue"
 	"github.com/Dyleme/image-coverter/internal/rabbitmq"
 	"github.com/Dyleme/image-coverter/internal/repository"
 	"github.com/Dyleme/image-coverter/internal/server"
 	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/signing"
 	"github.com/Dyleme/image-coverter/internal/storage"
 )
 
@@ -39,28 +41,57 @@ func main() {
 		logger.Fatalf("failed to initialize storage: %s", err)
 	}
 
-	rabbitSender, err := rabbitmq.NewRabbitSender(conf.RabbitMQ)
-	if err != nil {
-		logger.Fatalf("failed to make connection to rabbitmq: %s", err)
+	ctx := logging.WithLogger(context.Background(), logger)
+
+	var q queue.Queue
+
+	switch conf.Queue.Kind {
+	case queue.KindRabbitMQ:
+		q, err = rabbitmq.NewQueue(ctx, conf.RabbitMQ)
+		if err != nil {
+			logger.Fatalf("failed to make connection to rabbitmq: %s", err)
+		}
+	case queue.KindPostgres:
+		q = queue.NewPostgres(db, conf.Queue)
+	case queue.KindMemory:
+		// There is no separate converter for the in-process queue, so images are converted here.
+		memQueue := queue.NewMemory()
+		convService := service.NewConvertRequest(repository.NewConvPostgres(db), stor, conf.Convert)
+
+		go func() {
+			if err := queue.Serve(ctx, memQueue, convService, conf.Queue); err != nil {
+				logger.Errorf("converting: %s", err)
+			}
+		}()
+
+		q = memQueue
+	default:
+		logger.Fatalf("unknown queue %q", conf.Queue.Kind)
 	}
 
+	// Requests are written to the outbox with the request and relayed to the queue.
+	relay := service.NewRelay(repository.NewOutboxPostgres(db), queue.NewSender(q), conf.Relay)
+	go relay.Run(ctx)
+
 	jwtGen := jwt.NewJwtGen(conf.JWT)
+	signer := signing.NewSigner(conf.Signing)
 
 	authService := service.NewAuth(authRep, &service.HashGen{}, jwtGen)
-	reqService := service.NewRequest(reqRep, stor, rabbitSender)
+	reqService := service.NewRequest(reqRep, stor)
 	downService := service.NewDownload(downRep, stor)
+	transService := service.NewTransform(downRep, stor)
 
 	authHandler := handler.NewAuth(authService, logger)
 	reqHandler := handler.NewRequest(reqService, logger)
 	downHandler := handler.NewDownload(downService, logger)
+	transHandler := handler.NewTransform(transService, conf.PublicURL, logger)
+	signHandler := handler.NewSigning(signer, logger)
 
-	handlers := handler.New(authHandler, reqHandler, downHandler, logger)
+	handlers := handler.New(authHandler, reqHandler, downHandler, transHandler, signHandler, logger)
 
 	srv := new(server.Server)
 
-	ctx := logging.WithLogger(context.Background(), logger)
-
-	if err := srv.Run(ctx, conf.Port, handlers.InitRouters(jwtGen)); err != nil {
+	if err := srv.Run(ctx, conf.Port, handlers.InitRouters(jwtGen, signer)); err != nil {
 		logger.Fatalf("error occurred runnging http server: %s", err.Error())
 	}
 }
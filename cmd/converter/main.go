@@ -10,6 +10,7 @@ import (
 
 	"github.com/Dyleme/image-coverter/internal/config"
 	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/queue"
 	"github.com/Dyleme/image-coverter/internal/rabbitmq"
 	"github.com/Dyleme/image-coverter/internal/repository"
 	"github.com/Dyleme/image-coverter/internal/service"
@@ -38,7 +39,7 @@ func main() {
 		logger.Fatalf("failed to initialize storage: %s", err)
 	}
 
-	convService := service.NewConvertRequest(convRep, stor)
+	convService := service.NewConvertRequest(convRep, stor, conf.Convert)
 
 	c := make(chan os.Signal, 1)
 
@@ -52,7 +53,25 @@ func main() {
 		cancel()
 	}()
 
-	err = rabbitmq.Receive(ctx, convService, conf.RabbitMQ)
+	reaper := service.NewReaper(repository.NewReaperPostgres(db), conf.Reaper)
+
+	go reaper.Run(ctx)
+
+	var q queue.Queue
+
+	switch conf.Queue.Kind {
+	case queue.KindRabbitMQ:
+		q, err = rabbitmq.NewQueue(ctx, conf.RabbitMQ)
+		if err != nil {
+			logger.Fatalf("failed to make connection to rabbitmq: %s", err)
+		}
+	case queue.KindPostgres:
+		q = queue.NewPostgres(db, conf.Queue)
+	default:
+		logger.Fatalf("queue %q can't be used by the separate converter", conf.Queue.Kind)
+	}
+
+	err = queue.Serve(ctx, q, convService, conf.Queue)
 	if err != nil {
 		logger.Fatalf("receiving: %s", err)
 	}
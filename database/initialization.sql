@@ -13,33 +13,90 @@ CREATE TABLE IF NOT EXISTS users (
 --   ('Jerdsfu', 'Gerry Mulligan@', 'dsjlm'),
 --   ('Sarasdefh Vaughan', 'Sarah Vaughan@', 'sdjfk');
 
-CREATE TYPE operation_status AS ENUM ('queued', 'processing', 'done');
+CREATE TYPE operation_status AS ENUM ('queued', 'processing', 'done', 'failed', 'cancelled');
 
-CREATE TYPE image_type AS ENUM ('jpeg', 'png');
+CREATE TYPE image_type AS ENUM ('jpeg', 'png', 'svg', 'ico', 'favicon', 'pdf', 'dzi');
+
+CREATE TYPE request_kind AS ENUM ('conversion', 'contact_sheet', 'pdf');
+
+CREATE TYPE request_priority AS ENUM ('interactive', 'bulk');
+
+CREATE TABLE IF NOT EXISTS images (
+  id               SERIAL UNIQUE PRIMARY KEY,
+  resoolution_x    INTEGER,
+  resoolution_y    INTEGER,
+  im_type          image_type NOT NULL,
+  image_url        VARCHAR(250) NOT NULL,
+  user_id          INTEGER NOT NULL,
+  request_id       INTEGER,
+  blurhash         VARCHAR(100),
+  preview          TEXT,
+  name             VARCHAR(100)
+);
 
 CREATE TABLE IF NOT EXISTS requests (
   id                  SERIAL UNIQUE PRIMARY KEY,
+  kind                request_kind NOT NULL DEFAULT 'conversion',
   op_status           operation_status NOT NULL DEFAULT 'queued',
+  priority            request_priority NOT NULL DEFAULT 'interactive',
   request_time        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
   completion_time     TIMESTAMP WITH TIME ZONE,
-  original_id         INTEGER NOT NULL,
+  start_time          TIMESTAMP WITH TIME ZONE,
+  heartbeat_at        TIMESTAMP WITH TIME ZONE,
+  worker_id           VARCHAR(100),
+  attempts            INTEGER NOT NULL DEFAULT 0,
+  original_id         INTEGER,
+  source_id           INTEGER REFERENCES images (id) ON DELETE SET NULL,
   processed_id        INTEGER,
   user_id             INTEGER NOT NULL,
   ratio               FLOAT NOT NULL DEFAULT 1,
-  original_type       image_type NOT NULL,
-  processed_type      image_type NOT NULL
+  original_type       image_type,
+  processed_type      image_type NOT NULL,
+  options             JSONB NOT NULL DEFAULT '{}',
+  crop                JSONB,
+  error_code          VARCHAR(50),
+  error_message       TEXT
 );
 
-CREATE TABLE IF NOT EXISTS images (
+CREATE INDEX IF NOT EXISTS requests_active_idx ON requests (user_id) WHERE op_status IN ('queued', 'processing');
+
+CREATE INDEX IF NOT EXISTS requests_processing_idx ON requests (heartbeat_at) WHERE op_status = 'processing';
+
+CREATE TABLE IF NOT EXISTS derivatives (
   id               SERIAL UNIQUE PRIMARY KEY,
-  resoolution_x    INTEGER,
-  resoolution_y    INTEGER,
-  im_type          image_type NOT NULL,
+  image_id         INTEGER NOT NULL REFERENCES images (id) ON DELETE CASCADE,
+  params           VARCHAR(100) NOT NULL,
   image_url        VARCHAR(250) NOT NULL,
+  created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (image_id, params)
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+  id               BIGSERIAL UNIQUE PRIMARY KEY,
+  request_id       INTEGER NOT NULL,
   user_id          INTEGER NOT NULL,
-  request_id       INTEGER 
+  priority         request_priority NOT NULL DEFAULT 'interactive',
+  payload          JSONB NOT NULL,
+  created_at       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sent_at          TIMESTAMP WITH TIME ZONE
 );
 
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (request_id) WHERE sent_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS jobs (
+  id               BIGSERIAL UNIQUE PRIMARY KEY,
+  body             BYTEA NOT NULL,
+  priority         SMALLINT NOT NULL DEFAULT 0,
+  attempts         INTEGER NOT NULL DEFAULT 0,
+  run_at           TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  locked_at        TIMESTAMP WITH TIME ZONE,
+  claim            BIGINT NOT NULL DEFAULT 0,
+  dead             BOOLEAN NOT NULL DEFAULT false,
+  last_error       TEXT
+);
+
+CREATE INDEX IF NOT EXISTS jobs_ready_idx ON jobs (priority DESC, run_at, id) WHERE NOT dead;
+
 -- INSERT INTO images(resoolution_x, resoolution_y, im_type, image_url, user_id, request_id)
 -- VALUES (1080, 720, 'JPEG', 'image.url', 1, 1);
 
@@ -1,12 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/queue"
 	"github.com/Dyleme/image-coverter/internal/rabbitmq"
 	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/signing"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 )
@@ -14,13 +19,21 @@ import (
 type CollectiveConfig struct {
 	DB            *repository.DBConfig
 	RabbitMQ      *rabbitmq.Config
+	Queue         *queue.Config
 	JWT           *jwt.Config
+	Signing       *signing.Config
 	AWS           *aws.Config
+	Convert       *service.ConvertConfig
+	Relay         *service.RelayConfig
+	Reaper        *service.ReaperConfig
 	AwsBucketName string
 	Port          string
+	PublicURL     string
 }
 
 func InitConfig() (*CollectiveConfig, error) {
+	var err error
+
 	db := &repository.DBConfig{
 		UserName: os.Getenv("DBUSERNAME"),
 		Password: os.Getenv("DBPASSWORD"),
@@ -37,6 +50,77 @@ func InitConfig() (*CollectiveConfig, error) {
 		Port:     os.Getenv("RBPORT"),
 	}
 
+	if delay := os.Getenv("RBRECONNECTDELAY"); delay != "" {
+		rabbitConfig.ReconnectDelay, err = time.ParseDuration(delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if maxDelay := os.Getenv("RBMAXRECONNECTDELAY"); maxDelay != "" {
+		rabbitConfig.MaxReconnectDelay, err = time.ParseDuration(maxDelay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if buffer := os.Getenv("RBPUBLISHBUFFER"); buffer != "" {
+		rabbitConfig.PublishBuffer, err = strconv.Atoi(buffer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	queueConfig := &queue.Config{
+		Kind: os.Getenv("QUEUE"),
+	}
+
+	if queueConfig.Kind == "" {
+		queueConfig.Kind = queue.KindRabbitMQ
+	}
+
+	if workers := os.Getenv("QUEUEWORKERS"); workers != "" {
+		queueConfig.Workers, err = strconv.Atoi(workers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout := os.Getenv("QUEUESHUTDOWNTIMEOUT"); timeout != "" {
+		queueConfig.ShutdownTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if delay := os.Getenv("QUEUERETRYDELAY"); delay != "" {
+		queueConfig.RetryDelay, err = time.ParseDuration(delay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if maxDelay := os.Getenv("QUEUEMAXRETRYDELAY"); maxDelay != "" {
+		queueConfig.MaxRetryDelay, err = time.ParseDuration(maxDelay)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if interval := os.Getenv("QUEUEPOLLINTERVAL"); interval != "" {
+		queueConfig.PollInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout := os.Getenv("QUEUECLAIMTIMEOUT"); timeout != "" {
+		queueConfig.ClaimTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ttl, err := time.ParseDuration(os.Getenv("TOKENTTL"))
 	if err != nil {
 		return nil, err
@@ -47,7 +131,97 @@ func InitConfig() (*CollectiveConfig, error) {
 		TTL:       ttl,
 	}
 
+	signingConfig := &signing.Config{
+		Key: os.Getenv("URLSIGNKEY"),
+	}
+
+	if maxTTL := os.Getenv("URLMAXTTL"); maxTTL != "" {
+		signingConfig.MaxTTL, err = time.ParseDuration(maxTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	port := os.Getenv("PORT")
+	publicURL := os.Getenv("PUBLICURL")
+
+	convertConfig := &service.ConvertConfig{
+		WorkerID:    os.Getenv("WORKERID"),
+		Concurrency: queueConfig.Workers,
+	}
+
+	if convertConfig.WorkerID == "" {
+		convertConfig.WorkerID = defaultWorkerID()
+	}
+
+	if maxAttempts := os.Getenv("MAXATTEMPTS"); maxAttempts != "" {
+		convertConfig.MaxAttempts, err = strconv.Atoi(maxAttempts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout := os.Getenv("JOBTIMEOUT"); timeout != "" {
+		convertConfig.Timeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if interval := os.Getenv("HEARTBEATINTERVAL"); interval != "" {
+		convertConfig.HeartbeatInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reaperConfig := &service.ReaperConfig{
+		MaxAttempts: convertConfig.MaxAttempts,
+	}
+
+	if interval := os.Getenv("REAPERINTERVAL"); interval != "" {
+		reaperConfig.Interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout := os.Getenv("HEARTBEATTIMEOUT"); timeout != "" {
+		reaperConfig.HeartbeatTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	relayConfig := &service.RelayConfig{}
+
+	if interval := os.Getenv("OUTBOXINTERVAL"); interval != "" {
+		relayConfig.Interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if batchSize := os.Getenv("OUTBOXBATCHSIZE"); batchSize != "" {
+		relayConfig.BatchSize, err = strconv.Atoi(batchSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if userLimit := os.Getenv("OUTBOXUSERLIMIT"); userLimit != "" {
+		relayConfig.UserLimit, err = strconv.Atoi(userLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if timeout := os.Getenv("OUTBOXINFLIGHTTIMEOUT"); timeout != "" {
+		relayConfig.InFlightTimeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	awsBucketName := os.Getenv("AWS_BUCKET_NAME")
 	awsConfig := &aws.Config{
@@ -62,9 +236,25 @@ func InitConfig() (*CollectiveConfig, error) {
 	return &CollectiveConfig{
 		DB:            db,
 		RabbitMQ:      rabbitConfig,
+		Queue:         queueConfig,
 		JWT:           jwtConfig,
+		Signing:       signingConfig,
 		Port:          port,
+		PublicURL:     publicURL,
 		AWS:           awsConfig,
 		AwsBucketName: awsBucketName,
+		Convert:       convertConfig,
+		Relay:         relayConfig,
+		Reaper:        reaperConfig,
 	}, nil
 }
+
+// defaultWorkerID function returns the host name with the process id.
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "converter"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,161 @@
+package conversion
+
+import (
+	"errors"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// Amount of the BlurHash components in wide and in height.
+	blurHashXComponents = 4
+	blurHashYComponents = 3
+
+	// Width of the image which is used to calculate BlurHash.
+	// Bigger images are downscaled because hash doesn't keep small details.
+	blurHashSampleWidth = 64
+
+	// Quantisation of the AC components. Values are taken from the BlurHash specification.
+	acMaxQuantisation  = 166
+	acMaxValueLimit    = 82
+	acComponentLevels  = 19
+	acComponentHalf    = 9
+	dcLength           = 4
+	acLength           = 2
+	componentsPerFlag  = 9
+	acComponentMaxStep = acComponentLevels - 1
+
+	base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+)
+
+var ErrEmptyImage = errors.New("image is empty")
+
+// BlurHash function returns BlurHash string of the image.
+// Hash is calculated with 4x3 components, which is enough for the placeholders.
+func BlurHash(im image.Image) (string, error) {
+	if im.Bounds().Empty() {
+		return "", ErrEmptyImage
+	}
+
+	if im.Bounds().Dx() > blurHashSampleWidth {
+		im = imaging.Resize(im, blurHashSampleWidth, 0, imaging.Box)
+	}
+
+	nrgba := imaging.Clone(im)
+	factors := make([][3]float64, 0, blurHashXComponents*blurHashYComponents)
+
+	for y := 0; y < blurHashYComponents; y++ {
+		for x := 0; x < blurHashXComponents; x++ {
+			factors = append(factors, blurHashFactor(nrgba, x, y))
+		}
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString(encodeBase83((blurHashXComponents-1)+(blurHashYComponents-1)*componentsPerFlag, 1))
+
+	maxValue := 1.0
+
+	if len(factors) > 1 {
+		actualMax := 0.0
+
+		for _, f := range factors[1:] {
+			for _, c := range f {
+				actualMax = math.Max(actualMax, math.Abs(c))
+			}
+		}
+
+		quantisedMax := int(math.Max(0, math.Min(acMaxValueLimit, math.Floor(actualMax*acMaxQuantisation-0.5))))
+		maxValue = float64(quantisedMax+1) / acMaxQuantisation
+
+		sb.WriteString(encodeBase83(quantisedMax, 1))
+	} else {
+		sb.WriteString(encodeBase83(0, 1))
+	}
+
+	sb.WriteString(encodeBase83(encodeDC(factors[0]), dcLength))
+
+	for _, f := range factors[1:] {
+		sb.WriteString(encodeBase83(encodeAC(f, maxValue), acLength))
+	}
+
+	return sb.String(), nil
+}
+
+// blurHashFactor calculates the cosine transform component of the image.
+func blurHashFactor(im *image.NRGBA, xComp, yComp int) [3]float64 {
+	width, height := im.Bounds().Dx(), im.Bounds().Dy()
+
+	var r, g, b float64
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			basis := math.Cos(math.Pi*float64(xComp*x)/float64(width)) *
+				math.Cos(math.Pi*float64(yComp*y)/float64(height))
+			c := im.NRGBAAt(x, y)
+			r += basis * srgbToLinear(c.R)
+			g += basis * srgbToLinear(c.G)
+			b += basis * srgbToLinear(c.B)
+		}
+	}
+
+	normalisation := 2.0
+	if xComp == 0 && yComp == 0 {
+		normalisation = 1
+	}
+
+	scale := normalisation / float64(width*height)
+
+	return [3]float64{r * scale, g * scale, b * scale}
+}
+
+func encodeDC(c [3]float64) int {
+	return linearToSRGB(c[0])<<16 + linearToSRGB(c[1])<<8 + linearToSRGB(c[2])
+}
+
+func encodeAC(c [3]float64, maxValue float64) int {
+	quant := func(v float64) int {
+		return int(math.Max(0, math.Min(acComponentMaxStep,
+			math.Floor(signPow(v/maxValue, 0.5)*acComponentHalf+acComponentHalf+0.5))))
+	}
+
+	return quant(c[0])*acComponentLevels*acComponentLevels + quant(c[1])*acComponentLevels + quant(c[2])
+}
+
+func encodeBase83(value, length int) string {
+	res := make([]byte, length)
+
+	for i := length - 1; i >= 0; i-- {
+		res[i] = base83Chars[value%83]
+		value /= 83
+	}
+
+	return string(res)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
+
+// srgbToLinear converts 8 bit sRGB value to the linear light in range [0, 1].
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / math.MaxUint8
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light in range [0, 1] to the 8 bit sRGB value.
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*math.MaxUint8 + 0.5)
+	}
+
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*math.MaxUint8 + 0.5)
+}
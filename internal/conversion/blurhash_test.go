@@ -0,0 +1,89 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlurHash(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		img        image.Image
+		wantSize   string
+		wantDC     string
+		wantLength int
+		wantHash   string
+		wantErr    error
+	}{
+		{
+			testName: "empty image",
+			img:      image.NewNRGBA(image.Rect(0, 0, 0, 0)),
+			wantErr:  conversion.ErrEmptyImage,
+		},
+		{
+			testName:   "solid color image",
+			img:        solidImage(100, 80, color.NRGBA{R: 255, A: 255}),
+			wantSize:   "L",
+			wantDC:     "TI:j",
+			wantLength: 28,
+		},
+		{
+			// Hash is calculated with the reference implementation of the BlurHash.
+			testName:   "gradient image",
+			img:        gradientImage(32, 24),
+			wantSize:   "L",
+			wantDC:     "H281",
+			wantLength: 28,
+			wantHash:   "LxH2812yw#XAmLWZjuf8gLfkfQfk",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			gotHash, gotErr := conversion.BlurHash(tc.img)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			assert.Len(t, gotHash, tc.wantLength)
+			assert.Equal(t, tc.wantSize, gotHash[:1])
+			assert.Equal(t, tc.wantDC, gotHash[2:6])
+
+			if tc.wantHash != "" {
+				assert.Equal(t, tc.wantHash, gotHash)
+			}
+		})
+	}
+}
+
+func solidImage(width, height int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// gradientImage function returns the image, which colors depend on the coordinates of the pixel.
+func gradientImage(width, height int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 10), B: uint8(255 - x*4 - y*3), A: 255})
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,49 @@
+package conversion
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Distance between black and white in the RGB space.
+var maxColorDistance = math.Sqrt(3 * math.MaxUint8 * math.MaxUint8)
+
+// ChromaKey describes the colour, which is made transparent.
+type ChromaKey struct {
+	Color color.NRGBA
+
+	// Tolerance is the distance to the colour in percents of the maximum distance.
+	// Pixels, which are closer, become fully transparent.
+	Tolerance float64
+
+	// Feather is the width of the band after the tolerance in percents of the maximum distance,
+	// where transparency fades out to keep the edges smooth. Band is measured by the colour distance only,
+	// so the pixels of the similar colour are faded anywhere in the image, not only on the edges.
+	Feather float64
+}
+
+// RemoveColor function makes pixels close to the key colour transparent.
+// Alpha of the other pixels is kept.
+func RemoveColor(im image.Image, key ChromaKey) *image.NRGBA {
+	res := imaging.Clone(im)
+
+	for i := 0; i < len(res.Pix); i += 4 {
+		dr := float64(res.Pix[i]) - float64(key.Color.R)
+		dg := float64(res.Pix[i+1]) - float64(key.Color.G)
+		db := float64(res.Pix[i+2]) - float64(key.Color.B)
+		dist := math.Sqrt(dr*dr+dg*dg+db*db) * percents / maxColorDistance
+
+		switch {
+		case dist <= key.Tolerance:
+			res.Pix[i+3] = 0
+		case dist < key.Tolerance+key.Feather:
+			opacity := (dist - key.Tolerance) / key.Feather
+			res.Pix[i+3] = uint8(math.Round(float64(res.Pix[i+3]) * opacity))
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,54 @@
+package conversion_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveColor(t *testing.T) {
+	green := color.NRGBA{G: 0xff, A: 0xff}
+
+	im := imaging.New(4, 1, color.Black)
+	im.SetNRGBA(0, 0, green)
+	im.SetNRGBA(1, 0, color.NRGBA{R: 0x10, G: 0xf0, B: 0x10, A: 0xff})
+	im.SetNRGBA(2, 0, color.NRGBA{R: 0x40, G: 0xc0, B: 0x40, A: 0xff})
+	im.SetNRGBA(3, 0, color.NRGBA{R: 0xff, A: 0xff})
+
+	testCases := []struct {
+		testName  string
+		key       conversion.ChromaKey
+		wantAlpha []uint8
+	}{
+		{
+			testName:  "exact colour",
+			key:       conversion.ChromaKey{Color: green},
+			wantAlpha: []uint8{0, 0xff, 0xff, 0xff},
+		},
+		{
+			testName:  "tolerance",
+			key:       conversion.ChromaKey{Color: green, Tolerance: 10},
+			wantAlpha: []uint8{0, 0, 0xff, 0xff},
+		},
+		{
+			testName:  "feather",
+			key:       conversion.ChromaKey{Color: green, Tolerance: 5, Feather: 30},
+			wantAlpha: []uint8{0, 0x0a, 0xaa, 0xff},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			got := conversion.RemoveColor(im, tc.key)
+
+			for x, want := range tc.wantAlpha {
+				assert.Equal(t, want, got.NRGBAAt(x, 0).A, "pixel %v", x)
+			}
+
+			assert.Equal(t, im.NRGBAAt(2, 0).G, got.NRGBAAt(2, 0).G)
+		})
+	}
+}
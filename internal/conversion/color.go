@@ -0,0 +1,33 @@
+package conversion
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+type InvalidColorError struct {
+	Color string
+}
+
+func (e *InvalidColorError) Error() string {
+	return fmt.Sprintf("invalid color %q, expected #rrggbb or #rrggbbaa", e.Color)
+}
+
+// ParseHexColor function parses color in #rrggbb or #rrggbbaa format.
+func ParseHexColor(s string) (color.NRGBA, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || !strings.HasPrefix(s, "#") {
+		return color.NRGBA{}, &InvalidColorError{s}
+	}
+
+	switch len(b) {
+	case 3: //nolint:gomnd // #rrggbb
+		return color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+	case 4: //nolint:gomnd // #rrggbbaa
+		return color.NRGBA{R: b[0], G: b[1], B: b[2], A: b[3]}, nil
+	default:
+		return color.NRGBA{}, &InvalidColorError{s}
+	}
+}
@@ -2,6 +2,7 @@ package conversion
 
 import (
 	"image"
+	"math"
 
 	"github.com/disintegration/imaging"
 )
@@ -13,3 +14,29 @@ func Resize(im image.Image, ratio float32) image.Image {
 
 	return imaging.Resize(im, newX, newY, imaging.Lanczos)
 }
+
+// Function that returns picture resized to the provided width, aspect ratio remains the same.
+func ResizeToWidth(im image.Image, width int) image.Image {
+	return imaging.Resize(im, width, 0, imaging.Lanczos)
+}
+
+// Function that returns picture scaled to fit into the box with provided size, aspect ratio remains the same.
+// If width or height is zero, it's calculated from the other side.
+func Contain(im image.Image, width, height int) image.Image {
+	if width == 0 || height == 0 {
+		return imaging.Resize(im, width, height, imaging.Lanczos)
+	}
+
+	srcW, srcH := float64(im.Bounds().Dx()), float64(im.Bounds().Dy())
+	scale := math.Min(float64(width)/srcW, float64(height)/srcH)
+
+	newW := maxInt(1, int(math.Round(srcW*scale)))
+	newH := maxInt(1, int(math.Round(srcH*scale)))
+
+	return imaging.Resize(im, newW, newH, imaging.Lanczos)
+}
+
+// Function that returns picture stretched to the provided size, aspect ratio is not kept.
+func Stretch(im image.Image, width, height int) image.Image {
+	return imaging.Resize(im, width, height, imaging.Lanczos)
+}
@@ -0,0 +1,42 @@
+package conversion_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContain(t *testing.T) {
+	testCases := []struct {
+		testName string
+		width    int
+		height   int
+		wantSize image.Point
+	}{
+		{testName: "limited by width", width: 100, height: 100, wantSize: image.Pt(100, 50)},
+		{testName: "limited by height", width: 300, height: 25, wantSize: image.Pt(50, 25)},
+		{testName: "upscaling", width: 400, height: 400, wantSize: image.Pt(400, 200)},
+		{testName: "only width", width: 40, height: 0, wantSize: image.Pt(40, 20)},
+		{testName: "only height", width: 0, height: 10, wantSize: image.Pt(20, 10)},
+	}
+
+	im := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			got := conversion.Contain(im, tc.width, tc.height)
+
+			assert.Equal(t, tc.wantSize, got.Bounds().Size())
+		})
+	}
+}
+
+func TestStretch(t *testing.T) {
+	im := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+
+	got := conversion.Stretch(im, 30, 70)
+
+	assert.Equal(t, image.Pt(30, 70), got.Bounds().Size())
+}
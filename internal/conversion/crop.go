@@ -0,0 +1,209 @@
+package conversion
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Anchor is a position in the image, relative to which the image is cropped.
+type Anchor string
+
+const (
+	AnchorCenter      Anchor = "center"
+	AnchorTopLeft     Anchor = "topLeft"
+	AnchorTop         Anchor = "top"
+	AnchorTopRight    Anchor = "topRight"
+	AnchorLeft        Anchor = "left"
+	AnchorRight       Anchor = "right"
+	AnchorBottomLeft  Anchor = "bottomLeft"
+	AnchorBottom      Anchor = "bottom"
+	AnchorBottomRight Anchor = "bottomRight"
+
+	// AnchorSmart chooses the part of the image with the biggest edge density.
+	AnchorSmart Anchor = "smart"
+)
+
+// Size of the bigger side of the image, which is used to find smart crop window.
+const smartAnalysisSize = 256
+
+type UnknownAnchorError struct {
+	Anchor string
+}
+
+func (e *UnknownAnchorError) Error() string {
+	return fmt.Sprintf("unknown anchor: %q", e.Anchor)
+}
+
+// ParseAnchor function returns the anchor by its name.
+// Empty name is parsed as AnchorCenter.
+func ParseAnchor(name string) (Anchor, error) {
+	switch a := Anchor(name); a {
+	case "":
+		return AnchorCenter, nil
+	case AnchorCenter, AnchorTopLeft, AnchorTop, AnchorTopRight, AnchorLeft,
+		AnchorRight, AnchorBottomLeft, AnchorBottom, AnchorBottomRight, AnchorSmart:
+		return a, nil
+	default:
+		return "", &UnknownAnchorError{name}
+	}
+}
+
+// Crop function cuts the window with provided size from the image.
+// Window position is defined by the anchor.
+// Returns cropped image and the window in the coordinates of the provided image.
+func Crop(im image.Image, width, height int, anchor Anchor) (image.Image, image.Rectangle, error) {
+	b := im.Bounds()
+
+	if width > b.Dx() {
+		width = b.Dx()
+	}
+
+	if height > b.Dy() {
+		height = b.Dy()
+	}
+
+	var origin image.Point
+
+	switch anchor {
+	case AnchorSmart:
+		origin = smartCropOrigin(im, width, height)
+	case AnchorCenter, "":
+		origin = image.Pt((b.Dx()-width)/2, (b.Dy()-height)/2)
+	case AnchorTopLeft:
+		origin = image.Pt(0, 0)
+	case AnchorTop:
+		origin = image.Pt((b.Dx()-width)/2, 0)
+	case AnchorTopRight:
+		origin = image.Pt(b.Dx()-width, 0)
+	case AnchorLeft:
+		origin = image.Pt(0, (b.Dy()-height)/2)
+	case AnchorRight:
+		origin = image.Pt(b.Dx()-width, (b.Dy()-height)/2)
+	case AnchorBottomLeft:
+		origin = image.Pt(0, b.Dy()-height)
+	case AnchorBottom:
+		origin = image.Pt((b.Dx()-width)/2, b.Dy()-height)
+	case AnchorBottomRight:
+		origin = image.Pt(b.Dx()-width, b.Dy()-height)
+	default:
+		return nil, image.Rectangle{}, &UnknownAnchorError{string(anchor)}
+	}
+
+	window := image.Rect(origin.X, origin.Y, origin.X+width, origin.Y+height)
+
+	return imaging.Crop(im, window.Add(b.Min)), window, nil
+}
+
+// Fill function resizes the image to cover the box with provided size
+// and cuts the box from it using the anchor.
+// Returns processed image and the window in the coordinates of the resized image.
+func Fill(im image.Image, width, height int, anchor Anchor) (image.Image, image.Rectangle, error) {
+	srcW, srcH := im.Bounds().Dx(), im.Bounds().Dy()
+
+	var newW, newH int
+
+	if srcW*height > srcH*width {
+		newH = height
+		newW = int(math.Round(float64(srcW) * float64(height) / float64(srcH)))
+	} else {
+		newW = width
+		newH = int(math.Round(float64(srcH) * float64(width) / float64(srcW)))
+	}
+
+	if newW < width {
+		newW = width
+	}
+
+	if newH < height {
+		newH = height
+	}
+
+	return Crop(imaging.Resize(im, newW, newH, imaging.Lanczos), width, height, anchor)
+}
+
+// smartCropOrigin function returns the top left point of the window with provided size,
+// which has the biggest edge density. Among equal windows the closest to the center is chosen.
+// Calculations are made on the downscaled grayscale copy of the image.
+func smartCropOrigin(im image.Image, width, height int) image.Point {
+	b := im.Bounds()
+
+	scale := math.Min(1, float64(smartAnalysisSize)/float64(maxInt(b.Dx(), b.Dy())))
+	sw := maxInt(1, int(math.Round(float64(b.Dx())*scale)))
+	sh := maxInt(1, int(math.Round(float64(b.Dy())*scale)))
+
+	small := imaging.Grayscale(imaging.Resize(im, sw, sh, imaging.Box))
+	integral := edgeIntegral(small)
+
+	ww := minInt(sw, maxInt(1, int(math.Round(float64(width)*scale))))
+	wh := minInt(sh, maxInt(1, int(math.Round(float64(height)*scale))))
+
+	var (
+		best       = image.Pt((sw-ww)/2, (sh-wh)/2)
+		bestScore  = -1.0
+		bestCenter = math.Inf(1)
+	)
+
+	for y := 0; y <= sh-wh; y++ {
+		for x := 0; x <= sw-ww; x++ {
+			score := integral[y+wh][x+ww] - integral[y][x+ww] - integral[y+wh][x] + integral[y][x]
+			center := math.Hypot(float64(2*x+ww-sw), float64(2*y+wh-sh))
+
+			if score > bestScore || (score == bestScore && center < bestCenter) {
+				best, bestScore, bestCenter = image.Pt(x, y), score, center
+			}
+		}
+	}
+
+	origin := image.Pt(int(math.Round(float64(best.X)/scale)), int(math.Round(float64(best.Y)/scale)))
+	origin.X = minInt(origin.X, b.Dx()-width)
+	origin.Y = minInt(origin.Y, b.Dy()-height)
+
+	return origin
+}
+
+// edgeIntegral function returns summed area table of the gradient magnitude of the grayscale image.
+// Element [y][x] contains the sum of all pixels above and to the left of (x, y).
+func edgeIntegral(gray *image.NRGBA) [][]float64 {
+	w, h := gray.Bounds().Dx(), gray.Bounds().Dy()
+
+	lum := func(x, y int) float64 {
+		x = minInt(maxInt(x, 0), w-1)
+		y = minInt(maxInt(y, 0), h-1)
+
+		return float64(gray.Pix[y*gray.Stride+x*4])
+	}
+
+	integral := make([][]float64, h+1)
+	integral[0] = make([]float64, w+1)
+
+	for y := 0; y < h; y++ {
+		integral[y+1] = make([]float64, w+1)
+		rowSum := 0.0
+
+		for x := 0; x < w; x++ {
+			rowSum += math.Abs(lum(x+1, y)-lum(x-1, y)) + math.Abs(lum(x, y+1)-lum(x, y-1))
+			integral[y+1][x+1] = integral[y][x+1] + rowSum
+		}
+	}
+
+	return integral
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,116 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+)
+
+// stripedImage returns white image with the black and white stripes in the provided rectangle.
+func stripedImage(width, height int, stripes image.Rectangle) image.Image {
+	img := solidImage(width, height, color.White).(*image.NRGBA)
+
+	for y := stripes.Min.Y; y < stripes.Max.Y; y++ {
+		for x := stripes.Min.X; x < stripes.Max.X; x += 2 {
+			img.Set(x, y, color.Black)
+		}
+	}
+
+	return img
+}
+
+func TestCrop(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		img        image.Image
+		width      int
+		height     int
+		anchor     conversion.Anchor
+		wantWindow image.Rectangle
+		wantInside image.Rectangle
+		wantErr    error
+	}{
+		{
+			testName:   "center",
+			img:        solidImage(200, 100, color.White),
+			width:      100,
+			height:     100,
+			anchor:     conversion.AnchorCenter,
+			wantWindow: image.Rect(50, 0, 150, 100),
+		},
+		{
+			testName:   "bottom right",
+			img:        solidImage(200, 100, color.White),
+			width:      50,
+			height:     40,
+			anchor:     conversion.AnchorBottomRight,
+			wantWindow: image.Rect(150, 60, 200, 100),
+		},
+		{
+			testName:   "window is bigger than image",
+			img:        solidImage(200, 100, color.White),
+			width:      300,
+			height:     300,
+			anchor:     conversion.AnchorTopLeft,
+			wantWindow: image.Rect(0, 0, 200, 100),
+		},
+		{
+			testName:   "smart chooses detailed part",
+			img:        stripedImage(400, 100, image.Rect(300, 20, 380, 80)),
+			width:      100,
+			height:     100,
+			anchor:     conversion.AnchorSmart,
+			wantInside: image.Rect(300, 20, 380, 80),
+		},
+		{
+			testName:   "smart on plain image is centered",
+			img:        solidImage(400, 100, color.White),
+			width:      100,
+			height:     100,
+			anchor:     conversion.AnchorSmart,
+			wantWindow: image.Rect(150, 0, 250, 100),
+		},
+		{
+			testName: "unknown anchor",
+			img:      solidImage(200, 100, color.White),
+			width:    100,
+			height:   100,
+			anchor:   "middle",
+			wantErr:  &conversion.UnknownAnchorError{Anchor: "middle"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			gotImg, gotWindow, gotErr := conversion.Crop(tc.img, tc.width, tc.height, tc.anchor)
+
+			assert.Equal(t, tc.wantErr, gotErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			if tc.wantInside.Empty() {
+				assert.Equal(t, tc.wantWindow, gotWindow)
+			} else {
+				assert.True(t, tc.wantInside.In(gotWindow), "window %v should contain %v", gotWindow, tc.wantInside)
+			}
+
+			assert.Equal(t, gotWindow.Size(), gotImg.Bounds().Size())
+		})
+	}
+}
+
+func TestFill(t *testing.T) {
+	img := stripedImage(800, 200, image.Rect(40, 40, 160, 160))
+
+	gotImg, gotWindow, err := conversion.Fill(img, 100, 100, conversion.AnchorSmart)
+
+	assert.NoError(t, err)
+	assert.Equal(t, image.Pt(100, 100), gotImg.Bounds().Size())
+	assert.Less(t, gotWindow.Min.X, 50)
+	assert.Equal(t, 0, gotWindow.Min.Y)
+}
@@ -0,0 +1,169 @@
+package conversion
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+)
+
+// DiffPolicy defines how images with different sizes are compared.
+type DiffPolicy string
+
+const (
+	// DiffPolicyError doesn't compare images with different sizes.
+	DiffPolicyError DiffPolicy = "error"
+
+	// DiffPolicyResize resizes the second image to the size of the first one.
+	DiffPolicyResize DiffPolicy = "resize"
+
+	// DiffPolicyPad places both images to the top left corner of the transparent canvas,
+	// which covers both of them. Pixels, which are covered by only one image, are changed.
+	DiffPolicyPad DiffPolicy = "pad"
+)
+
+// Share of the white color, which is mixed to the unchanged pixels of the diff image.
+const diffFade = 0.75
+
+// Color of the changed pixels on the diff image.
+var diffHighlight = color.NRGBA{R: 0xff, A: 0xff}
+
+// SizeMismatchError is returned if images have different sizes and the policy doesn't allow to compare them.
+type SizeMismatchError struct {
+	First  image.Point
+	Second image.Point
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("images have different sizes %vx%v and %vx%v",
+		e.First.X, e.First.Y, e.Second.X, e.Second.Y)
+}
+
+// UnknownDiffPolicyError is returned if the policy is not one of the defined.
+type UnknownDiffPolicyError struct {
+	Policy string
+}
+
+func (e *UnknownDiffPolicyError) Error() string {
+	return fmt.Sprintf("unknown diff policy %q", e.Policy)
+}
+
+// DiffResult is the result of the images comparison.
+type DiffResult struct {
+	// Image is the faded first image with the changed pixels highlighted.
+	Image *image.NRGBA
+
+	// ChangedPixels is the amount of pixels, which channels differ more than the threshold.
+	ChangedPixels int
+	TotalPixels   int
+
+	// MaxDelta is the maximum difference of the channel among all pixels.
+	MaxDelta uint8
+}
+
+// ChangedPercent method returns the share of the changed pixels in percents.
+func (r *DiffResult) ChangedPercent() float64 {
+	if r.TotalPixels == 0 {
+		return 0
+	}
+
+	return float64(r.ChangedPixels) * percents / float64(r.TotalPixels)
+}
+
+// Diff function compares images pixel by pixel. Pixel is changed, if any of its channels,
+// including alpha, differs more than the threshold. Images with different sizes
+// are compared according to the policy, empty policy is treated as DiffPolicyError.
+func Diff(first, second image.Image, policy DiffPolicy, threshold uint8) (*DiffResult, error) {
+	if first.Bounds().Empty() || second.Bounds().Empty() {
+		return nil, ErrEmptyImage
+	}
+
+	switch policy {
+	case DiffPolicyError, DiffPolicyResize, DiffPolicyPad, "":
+	default:
+		return nil, &UnknownDiffPolicyError{string(policy)}
+	}
+
+	a, b := imaging.Clone(first), imaging.Clone(second)
+
+	if a.Bounds().Size() != b.Bounds().Size() {
+		switch policy {
+		case DiffPolicyResize:
+			b = imaging.Resize(b, a.Bounds().Dx(), a.Bounds().Dy(), imaging.Lanczos)
+		case DiffPolicyPad:
+			size := image.Pt(maxInt(a.Bounds().Dx(), b.Bounds().Dx()), maxInt(a.Bounds().Dy(), b.Bounds().Dy()))
+			a, b = pad(a, size), pad(b, size)
+		default:
+			return nil, &SizeMismatchError{a.Bounds().Size(), b.Bounds().Size()}
+		}
+	}
+
+	res := &DiffResult{
+		Image:       imaging.New(a.Bounds().Dx(), a.Bounds().Dy(), color.Transparent),
+		TotalPixels: a.Bounds().Dx() * a.Bounds().Dy(),
+	}
+
+	for i := 0; i < len(a.Pix); i += 4 {
+		var delta uint8
+
+		for c := 0; c < 4; c++ {
+			delta = maxUint8(delta, absDiff(a.Pix[i+c], b.Pix[i+c]))
+		}
+
+		res.MaxDelta = maxUint8(res.MaxDelta, delta)
+
+		px := res.Image.Pix[i : i+4 : i+4]
+
+		if delta > threshold {
+			res.ChangedPixels++
+			px[0], px[1], px[2], px[3] = diffHighlight.R, diffHighlight.G, diffHighlight.B, diffHighlight.A
+
+			continue
+		}
+
+		gray := fade(luminance(a.Pix[i], a.Pix[i+1], a.Pix[i+2]))
+		px[0], px[1], px[2], px[3] = gray, gray, gray, 0xff
+	}
+
+	return res, nil
+}
+
+// pad function places the image to the top left corner of the transparent canvas with provided size.
+func pad(im *image.NRGBA, size image.Point) *image.NRGBA {
+	if im.Bounds().Size() == size {
+		return im
+	}
+
+	canvas := imaging.New(size.X, size.Y, color.Transparent)
+	draw.Draw(canvas, im.Bounds(), im, image.Point{}, draw.Src)
+
+	return canvas
+}
+
+// luminance function returns the gray level of the color.
+func luminance(r, g, b uint8) uint8 {
+	return uint8(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b))
+}
+
+// fade function mixes the gray level with the white color.
+func fade(gray uint8) uint8 {
+	return uint8(float64(gray)*(1-diffFade) + 0xff*diffFade)
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
+
+func maxUint8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,108 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	white := imaging.New(4, 4, color.White)
+
+	changed := imaging.Clone(white)
+	changed.Set(1, 1, color.NRGBA{R: 0xff, G: 0xff, B: 0xf0, A: 0xff})
+	changed.Set(2, 2, color.NRGBA{R: 0x80, G: 0xff, B: 0xff, A: 0xff})
+
+	testCases := []struct {
+		testName      string
+		second        image.Image
+		policy        conversion.DiffPolicy
+		threshold     uint8
+		wantSize      image.Point
+		wantChanged   int
+		wantMaxDelta  uint8
+		wantPercent   float64
+		wantErrAs     interface{}
+		wantHighlight []image.Point
+	}{
+		{
+			testName:      "changed pixels",
+			second:        changed,
+			wantSize:      image.Pt(4, 4),
+			wantChanged:   2,
+			wantMaxDelta:  0x7f,
+			wantPercent:   12.5,
+			wantHighlight: []image.Point{{1, 1}, {2, 2}},
+		},
+		{
+			testName:      "threshold",
+			second:        changed,
+			threshold:     0x10,
+			wantSize:      image.Pt(4, 4),
+			wantChanged:   1,
+			wantMaxDelta:  0x7f,
+			wantPercent:   6.25,
+			wantHighlight: []image.Point{{2, 2}},
+		},
+		{
+			testName:  "size mismatch",
+			second:    imaging.New(2, 4, color.White),
+			policy:    conversion.DiffPolicyError,
+			wantErrAs: new(*conversion.SizeMismatchError),
+		},
+		{
+			testName:     "resize",
+			second:       imaging.New(2, 2, color.White),
+			policy:       conversion.DiffPolicyResize,
+			wantSize:     image.Pt(4, 4),
+			wantChanged:  0,
+			wantMaxDelta: 0,
+			wantPercent:  0,
+		},
+		{
+			testName:      "pad",
+			second:        imaging.New(4, 2, color.White),
+			policy:        conversion.DiffPolicyPad,
+			wantSize:      image.Pt(4, 4),
+			wantChanged:   8,
+			wantMaxDelta:  0xff,
+			wantPercent:   50,
+			wantHighlight: []image.Point{{0, 2}, {3, 3}},
+		},
+		{
+			testName:  "unknown policy",
+			second:    white,
+			policy:    "blend",
+			wantErrAs: new(*conversion.UnknownDiffPolicyError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			got, err := conversion.Diff(white, tc.second, tc.policy, tc.threshold)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSize, got.Image.Bounds().Size())
+			assert.Equal(t, tc.wantChanged, got.ChangedPixels)
+			assert.Equal(t, tc.wantMaxDelta, got.MaxDelta)
+			assert.InDelta(t, tc.wantPercent, got.ChangedPercent(), 1e-9)
+
+			for _, p := range tc.wantHighlight {
+				assert.Equal(t, color.NRGBA{R: 0xff, A: 0xff}, got.Image.NRGBAAt(p.X, p.Y))
+			}
+
+			assert.Equal(t, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, got.Image.NRGBAAt(0, 0))
+		})
+	}
+}
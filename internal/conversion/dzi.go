@@ -0,0 +1,107 @@
+package conversion
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// TileLayout is a layout of the deep-zoom tiles.
+type TileLayout struct {
+	// TileSize is the size of the tile side without overlap.
+	TileSize int
+
+	// Overlap is the amount of pixels which tile shares with each neighbour.
+	Overlap int
+}
+
+// TileFunc is called for each tile of the pyramid.
+type TileFunc func(level, col, row int, tile image.Image) error
+
+// DZIMaxLevel function returns the level of the full size image.
+// Level 0 is the image of 1x1 pixel, each next level is twice bigger.
+func DZIMaxLevel(width, height int) int {
+	return int(math.Ceil(math.Log2(float64(maxInt(width, height)))))
+}
+
+// DZILevelSize function returns the size of the image on the level.
+func DZILevelSize(width, height, level int) (int, int) {
+	scale := math.Pow(2, float64(DZIMaxLevel(width, height)-level))
+
+	return int(math.Ceil(float64(width) / scale)), int(math.Ceil(float64(height) / scale))
+}
+
+// Tiles function cuts the image to the deep-zoom tile pyramid and calls fn for each tile.
+// Levels are processed from the full size image to the 1x1 one, each level is made from the previous.
+func Tiles(im image.Image, layout TileLayout, fn TileFunc) error {
+	if im.Bounds().Empty() {
+		return ErrEmptyImage
+	}
+
+	if layout.TileSize <= 0 || layout.Overlap < 0 {
+		return fmt.Errorf("tiles: invalid layout %+v", layout)
+	}
+
+	width, height := im.Bounds().Dx(), im.Bounds().Dy()
+	levelImage := image.Image(imaging.Clone(im))
+
+	for level := DZIMaxLevel(width, height); level >= 0; level-- {
+		levelW, levelH := DZILevelSize(width, height, level)
+		if levelImage.Bounds().Dx() != levelW || levelImage.Bounds().Dy() != levelH {
+			levelImage = imaging.Resize(levelImage, levelW, levelH, imaging.Lanczos)
+		}
+
+		for row := 0; row*layout.TileSize < levelH; row++ {
+			for col := 0; col*layout.TileSize < levelW; col++ {
+				tile := imaging.Crop(levelImage, tileRect(col, row, levelW, levelH, layout))
+
+				if err := fn(level, col, row, tile); err != nil {
+					return fmt.Errorf("tiles: level %v, tile %v_%v: %w", level, col, row, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// tileRect function returns the rectangle of the tile including overlaps with the neighbours.
+func tileRect(col, row, levelW, levelH int, layout TileLayout) image.Rectangle {
+	x, y := col*layout.TileSize, row*layout.TileSize
+
+	return image.Rect(
+		maxInt(0, x-layout.Overlap),
+		maxInt(0, y-layout.Overlap),
+		minInt(levelW, x+layout.TileSize+layout.Overlap),
+		minInt(levelH, y+layout.TileSize+layout.Overlap),
+	)
+}
+
+// dziImage is the xml representation of the deep-zoom descriptor.
+type dziImage struct {
+	XMLName  xml.Name `xml:"http://schemas.microsoft.com/deepzoom/2008 Image"`
+	TileSize int      `xml:"TileSize,attr"`
+	Overlap  int      `xml:"Overlap,attr"`
+	Format   string   `xml:"Format,attr"`
+	Size     struct {
+		Width  int `xml:"Width,attr"`
+		Height int `xml:"Height,attr"`
+	} `xml:"Size"`
+}
+
+// DZIDescriptor function returns the deep-zoom descriptor of the image.
+// Format is the extension of the tile files.
+func DZIDescriptor(width, height int, layout TileLayout, format string) ([]byte, error) {
+	d := dziImage{TileSize: layout.TileSize, Overlap: layout.Overlap, Format: format}
+	d.Size.Width, d.Size.Height = width, height
+
+	bts, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("dzi descriptor: %w", err)
+	}
+
+	return append([]byte(xml.Header), bts...), nil
+}
@@ -0,0 +1,57 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiles(t *testing.T) {
+	img := solidImage(600, 300, color.NRGBA{G: 255, A: 255})
+	layout := conversion.TileLayout{TileSize: 256, Overlap: 1}
+
+	type tileKey struct{ level, col, row int }
+
+	tiles := map[tileKey]image.Point{}
+
+	err := conversion.Tiles(img, layout, func(level, col, row int, tile image.Image) error {
+		tiles[tileKey{level, col, row}] = tile.Bounds().Size()
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, conversion.DZIMaxLevel(600, 300))
+
+	// Full size level has 3x2 tiles, inner tiles overlap neighbours from both sides.
+	assert.Equal(t, image.Pt(257, 257), tiles[tileKey{10, 0, 0}])
+	assert.Equal(t, image.Pt(258, 257), tiles[tileKey{10, 1, 0}])
+	assert.Equal(t, image.Pt(600-511, 257), tiles[tileKey{10, 2, 0}])
+	assert.Equal(t, image.Pt(258, 300-255), tiles[tileKey{10, 1, 1}])
+
+	// Level 9 has size 300x150 and 2x1 tiles.
+	assert.Equal(t, image.Pt(257, 150), tiles[tileKey{9, 0, 0}])
+	assert.Equal(t, image.Pt(300-255, 150), tiles[tileKey{9, 1, 0}])
+	assert.NotContains(t, tiles, tileKey{9, 0, 1})
+
+	// The smallest levels.
+	assert.Equal(t, image.Pt(2, 1), tiles[tileKey{1, 0, 0}])
+	assert.Equal(t, image.Pt(1, 1), tiles[tileKey{0, 0, 0}])
+
+	assert.Len(t, tiles, 6+2+9)
+}
+
+func TestDZIDescriptor(t *testing.T) {
+	d, err := conversion.DZIDescriptor(600, 300, conversion.TileLayout{TileSize: 254, Overlap: 1}, "jpg")
+	require.NoError(t, err)
+
+	s := string(d)
+	assert.True(t, strings.HasPrefix(s, `<?xml version="1.0" encoding="UTF-8"?>`))
+	assert.Contains(t, s, `<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" TileSize="254" Overlap="1" Format="jpg">`)
+	assert.Contains(t, s, `<Size Width="600" Height="300"></Size>`)
+}
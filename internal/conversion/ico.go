@@ -0,0 +1,75 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultICOSizes are sizes of the images packed into ico file.
+var DefaultICOSizes = []int{16, 32, 48, 64, 256}
+
+const (
+	icoHeaderSize = 6
+	icoEntrySize  = 16
+	icoMaxSize    = 256
+	icoBitCount   = 32
+)
+
+// Square function resizes the image to fit into the square with the provided size.
+// Image keeps its aspect ratio, free space is filled with the background.
+func Square(im image.Image, size int, background color.Color) *image.NRGBA {
+	fitted := imaging.Fit(im, size, size, imaging.Lanczos)
+
+	return imaging.PasteCenter(imaging.New(size, size, background), fitted)
+}
+
+// EncodeICO function encodes image as multi-resolution ico file.
+// Each size is stored as the separate png compressed square image.
+func EncodeICO(im image.Image, sizes []int) ([]byte, error) {
+	images := make([][]byte, 0, len(sizes))
+
+	for _, size := range sizes {
+		if size <= 0 || size > icoMaxSize {
+			return nil, fmt.Errorf("encode ico: size %v is out of range", size)
+		}
+
+		bf := new(bytes.Buffer)
+		if err := png.Encode(bf, Square(im, size, color.Transparent)); err != nil {
+			return nil, fmt.Errorf("encode ico: %w", err)
+		}
+
+		images = append(images, bf.Bytes())
+	}
+
+	bf := new(bytes.Buffer)
+
+	// Errors are not checked, because writing to the bytes.Buffer always succeeds.
+	_ = binary.Write(bf, binary.LittleEndian, [3]uint16{0, 1, uint16(len(sizes))})
+
+	offset := icoHeaderSize + icoEntrySize*len(sizes)
+
+	for i, size := range sizes {
+		// Size 256 is written as 0, because it doesn't fit into the byte.
+		dim := uint8(size % icoMaxSize)
+
+		_ = binary.Write(bf, binary.LittleEndian, struct {
+			Width, Height, Colors, Reserved uint8
+			Planes, BitCount                uint16
+			Size, Offset                    uint32
+		}{dim, dim, 0, 0, 1, icoBitCount, uint32(len(images[i])), uint32(offset)})
+
+		offset += len(images[i])
+	}
+
+	for _, img := range images {
+		bf.Write(img)
+	}
+
+	return bf.Bytes(), nil
+}
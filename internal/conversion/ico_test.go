@@ -0,0 +1,48 @@
+package conversion_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeICO(t *testing.T) {
+	img := solidImage(300, 150, color.NRGBA{B: 255, A: 255})
+	sizes := []int{16, 48, 256}
+
+	ico, err := conversion.EncodeICO(img, sizes)
+	require.NoError(t, err)
+
+	var header [3]uint16
+	require.NoError(t, binary.Read(bytes.NewReader(ico), binary.LittleEndian, &header))
+	assert.Equal(t, [3]uint16{0, 1, uint16(len(sizes))}, header)
+
+	for i, size := range sizes {
+		entry := ico[6+16*i : 6+16*(i+1)]
+		dataSize := binary.LittleEndian.Uint32(entry[8:12])
+		offset := binary.LittleEndian.Uint32(entry[12:16])
+
+		assert.Equal(t, uint8(size%256), entry[0])
+
+		decoded, err := png.Decode(bytes.NewReader(ico[offset : offset+dataSize]))
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(size, size), decoded.Bounds().Size())
+
+		// Image is wider than square, so the top left corner is transparent.
+		_, _, _, a := decoded.At(0, 0).RGBA()
+		assert.Zero(t, a)
+	}
+}
+
+func TestEncodeICOWrongSize(t *testing.T) {
+	_, err := conversion.EncodeICO(solidImage(10, 10, color.White), []int{512})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,382 @@
+package conversion
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	// Threshold of the gray level, which separates black and white in bitonal quality.
+	bitonalThreshold = 128
+
+	percents = 100
+
+	fullCircle = 360
+)
+
+// IIIFParamError is returned if the parameter of the IIIF Image API request is invalid.
+type IIIFParamError struct {
+	Param string
+	Value string
+}
+
+func (e *IIIFParamError) Error() string {
+	return fmt.Sprintf("invalid iiif %s: %q", e.Param, e.Value)
+}
+
+// IIIFRequest is parsed request of the IIIF Image API 3.0.
+type IIIFRequest struct {
+	region   string
+	size     string
+	sizeSpec iiifSize
+	rotation float64
+	mirror   bool
+	quality  string
+	format   string
+}
+
+// IIIFFormats are formats which could be requested, mapped to the image types.
+var IIIFFormats = map[string]string{
+	"jpg": "jpeg",
+	"png": "png",
+}
+
+// IIIFQualities are qualities which could be requested.
+var IIIFQualities = []string{"default", "color", "gray", "bitonal"}
+
+// ParseIIIF function parses the parameters of the IIIF Image API request.
+// Region and size are validated against the image only in Apply.
+func ParseIIIF(region, size, rotation, quality, format string) (*IIIFRequest, error) {
+	req := &IIIFRequest{region: region, size: size, quality: quality, format: format}
+
+	if region != "full" && region != "square" {
+		if _, err := parseRect(strings.TrimPrefix(region, "pct:")); err != nil {
+			return nil, &IIIFParamError{"region", region}
+		}
+	}
+
+	sizeSpec, err := parseIIIFSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	req.sizeSpec = sizeSpec
+
+	rot := rotation
+	if strings.HasPrefix(rot, "!") {
+		req.mirror = true
+		rot = rot[1:]
+	}
+
+	r, err := strconv.ParseFloat(rot, 64)
+	if err != nil || !finite(r) || r < 0 || r >= fullCircle || rot == "" || strings.ContainsAny(rot, "+eE") {
+		return nil, &IIIFParamError{"rotation", rotation}
+	}
+
+	req.rotation = r
+
+	if !containsString(IIIFQualities, quality) {
+		return nil, &IIIFParamError{"quality", quality}
+	}
+
+	if _, ok := IIIFFormats[format]; !ok {
+		return nil, &IIIFParamError{"format", format}
+	}
+
+	return req, nil
+}
+
+// Type method returns the image type of the requested format.
+func (r *IIIFRequest) Type() string {
+	return IIIFFormats[r.format]
+}
+
+// Path method returns the path of the request relative to the image, which could be used as cache key.
+func (r *IIIFRequest) Path() string {
+	rotation := strconv.FormatFloat(r.rotation, 'f', -1, 64)
+	if r.mirror {
+		rotation = "!" + rotation
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s.%s", r.region, r.size, rotation, r.quality, r.format)
+}
+
+// Apply method makes the requested image. Operations are applied in the order of the specification:
+// region, size, mirroring, rotation and quality.
+func (r *IIIFRequest) Apply(im image.Image) (image.Image, error) {
+	region, err := r.regionRect(im.Bounds().Size())
+	if err != nil {
+		return nil, err
+	}
+
+	width, height, err := r.sizeSpec.of(region.Dx(), region.Dy())
+	if err != nil {
+		return nil, err
+	}
+
+	res := imaging.Crop(im, region.Add(im.Bounds().Min))
+	if width != region.Dx() || height != region.Dy() {
+		res = imaging.Resize(res, width, height, imaging.Lanczos)
+	}
+
+	if r.mirror {
+		res = imaging.FlipH(res)
+	}
+
+	res = rotate(res, r.rotation, r.background())
+
+	switch r.quality {
+	case "gray":
+		res = imaging.Grayscale(res)
+	case "bitonal":
+		res = bitonal(res)
+	}
+
+	return res, nil
+}
+
+// background method returns the color of the corners uncovered by the rotation.
+// Jpeg has no alpha channel, so the white color is used.
+func (r *IIIFRequest) background() color.Color {
+	if r.format == "jpg" {
+		return color.White
+	}
+
+	return color.Transparent
+}
+
+// regionRect method returns the requested region in the coordinates of the image with provided size.
+func (r *IIIFRequest) regionRect(size image.Point) (image.Rectangle, error) {
+	full := image.Rectangle{Max: size}
+
+	switch {
+	case r.region == "full":
+		return full, nil
+
+	case r.region == "square":
+		side := minInt(size.X, size.Y)
+
+		return image.Rect(0, 0, side, side).Add(image.Pt((size.X-side)/2, (size.Y-side)/2)), nil
+
+	case strings.HasPrefix(r.region, "pct:"):
+		v, err := parseRect(strings.TrimPrefix(r.region, "pct:"))
+		if err != nil {
+			return image.Rectangle{}, &IIIFParamError{"region", r.region}
+		}
+
+		fx, fy := float64(size.X)/percents, float64(size.Y)/percents
+		rect := image.Rect(int(math.Round(v[0]*fx)), int(math.Round(v[1]*fy)),
+			int(math.Round((v[0]+v[2])*fx)), int(math.Round((v[1]+v[3])*fy)))
+
+		return nonEmpty(rect.Intersect(full), r.region)
+
+	default:
+		v, err := parseRect(r.region)
+		if err != nil || v[0] != math.Trunc(v[0]) || v[1] != math.Trunc(v[1]) ||
+			v[2] != math.Trunc(v[2]) || v[3] != math.Trunc(v[3]) {
+			return image.Rectangle{}, &IIIFParamError{"region", r.region}
+		}
+
+		rect := image.Rect(int(v[0]), int(v[1]), int(v[0]+v[2]), int(v[1]+v[3]))
+
+		return nonEmpty(rect.Intersect(full), r.region)
+	}
+}
+
+// iiifSize is parsed size parameter of the IIIF request.
+type iiifSize struct {
+	raw      string
+	upscale  bool
+	max      bool
+	confined bool
+
+	// pct is the scale in percents, zero if size is set with width and height.
+	pct float64
+
+	// width and height, one of them is zero if it should keep aspect ratio.
+	width, height float64
+}
+
+// parseIIIFSize function parses size in one of the forms: max, w,, ,h, pct:n, w,h, !w,h.
+// Each form could be prefixed with ^ to allow upscaling.
+func parseIIIFSize(raw string) (iiifSize, error) {
+	s := iiifSize{raw: raw}
+
+	size := raw
+	s.upscale = strings.HasPrefix(size, "^")
+	size = strings.TrimPrefix(size, "^")
+
+	switch {
+	case size == "max":
+		s.max = true
+
+		return s, nil
+
+	case strings.HasPrefix(size, "pct:"):
+		p, err := strconv.ParseFloat(strings.TrimPrefix(size, "pct:"), 64)
+		if err != nil || !finite(p) || p <= 0 || strings.ContainsAny(size, "+eE") {
+			return s, &IIIFParamError{"size", raw}
+		}
+
+		s.pct = p
+
+		return s, nil
+	}
+
+	s.confined = strings.HasPrefix(size, "!")
+	size = strings.TrimPrefix(size, "!")
+
+	parts := strings.Split(size, ",")
+	if len(parts) != 2 { //nolint:gomnd // width and height
+		return s, &IIIFParamError{"size", raw}
+	}
+
+	var errW, errH error
+
+	s.width, errW = parseSide(parts[0])
+	s.height, errH = parseSide(parts[1])
+
+	if errW != nil || errH != nil || (s.width == 0 && s.height == 0) ||
+		(s.confined && (s.width == 0 || s.height == 0)) {
+		return s, &IIIFParamError{"size", raw}
+	}
+
+	return s, nil
+}
+
+// of method returns the requested size of the region with provided width and height.
+func (s iiifSize) of(regionW, regionH int) (int, int, error) {
+	var w, h float64
+
+	rw, rh := float64(regionW), float64(regionH)
+
+	switch {
+	case s.max:
+		w, h = rw, rh
+	case s.pct != 0:
+		w, h = rw*s.pct/percents, rh*s.pct/percents
+	case s.confined:
+		scale := math.Min(s.width/rw, s.height/rh)
+		w, h = rw*scale, rh*scale
+	case s.height == 0:
+		w, h = s.width, rh*s.width/rw
+	case s.width == 0:
+		w, h = rw*s.height/rh, s.height
+	default:
+		w, h = s.width, s.height
+	}
+
+	width, height := maxInt(1, int(math.Round(w))), maxInt(1, int(math.Round(h)))
+
+	if !s.upscale && (width > regionW || height > regionH) {
+		return 0, 0, &IIIFParamError{"size", s.raw + " (upscaling requires ^)"}
+	}
+
+	if width > maxRasterSize || height > maxRasterSize {
+		return 0, 0, &IIIFParamError{"size", s.raw + " (too big)"}
+	}
+
+	return width, height, nil
+}
+
+// rotate function rotates the image clockwise, uncovered corners are filled with the background.
+func rotate(im *image.NRGBA, angle float64, background color.Color) *image.NRGBA {
+	switch angle {
+	case 0:
+		return im
+	case 90: //nolint:gomnd // right angle
+		return imaging.Rotate270(im)
+	case 180: //nolint:gomnd // straight angle
+		return imaging.Rotate180(im)
+	case 270: //nolint:gomnd // three right angles
+		return imaging.Rotate90(im)
+	default:
+		// imaging rotates counter-clockwise.
+		return imaging.Rotate(im, fullCircle-angle, background)
+	}
+}
+
+// bitonal function converts the image to black and white.
+func bitonal(im *image.NRGBA) *image.NRGBA {
+	gray := imaging.Grayscale(im)
+
+	for i := 0; i < len(gray.Pix); i += 4 {
+		v := uint8(0)
+		if gray.Pix[i] >= bitonalThreshold {
+			v = math.MaxUint8
+		}
+
+		gray.Pix[i], gray.Pix[i+1], gray.Pix[i+2] = v, v, v
+	}
+
+	return gray
+}
+
+// parseRect function parses four comma separated non-negative numbers, width and height should be positive.
+func parseRect(s string) ([4]float64, error) {
+	var v [4]float64
+
+	parts := strings.Split(s, ",")
+	if len(parts) != len(v) {
+		return v, fmt.Errorf("expected %v numbers", len(v))
+	}
+
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil || !finite(f) || f < 0 || strings.ContainsAny(p, "+eE") {
+			return v, fmt.Errorf("invalid number %q", p)
+		}
+
+		v[i] = f
+	}
+
+	if v[2] == 0 || v[3] == 0 {
+		return v, fmt.Errorf("empty rectangle")
+	}
+
+	return v, nil
+}
+
+// parseSide function parses the side of the size, empty side is returned as zero.
+func parseSide(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid side %q", s)
+	}
+
+	return float64(v), nil
+}
+
+// finite function reports whether the parsed number is neither NaN nor infinity,
+// strconv.ParseFloat accepts both of them.
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func nonEmpty(rect image.Rectangle, region string) (image.Rectangle, error) {
+	if rect.Empty() {
+		return image.Rectangle{}, &IIIFParamError{"region", region + " (outside of the image)"}
+	}
+
+	return rect, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
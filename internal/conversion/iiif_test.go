@@ -0,0 +1,182 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIIIFRequest_Apply(t *testing.T) {
+	// Left half of the image is red, right half is blue.
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	red, blue := color.NRGBA{R: 255, A: 255}, color.NRGBA{B: 255, A: 255}
+
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			if x < 200 {
+				img.SetNRGBA(x, y, red)
+			} else {
+				img.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+
+	testCases := []struct {
+		testName  string
+		params    [5]string
+		wantSize  image.Point
+		wantLeft  color.NRGBA
+		wantErrOn string
+	}{
+		{
+			testName: "full image",
+			params:   [5]string{"full", "max", "0", "default", "png"},
+			wantSize: image.Pt(400, 200),
+			wantLeft: red,
+		},
+		{
+			testName: "pixel region",
+			params:   [5]string{"250,0,100,100", "max", "0", "color", "jpg"},
+			wantSize: image.Pt(100, 100),
+			wantLeft: blue,
+		},
+		{
+			testName: "percent region and width",
+			params:   [5]string{"pct:50,0,50,100", "100,", "0", "default", "png"},
+			wantSize: image.Pt(100, 100),
+			wantLeft: blue,
+		},
+		{
+			testName: "square region",
+			params:   [5]string{"square", ",50", "0", "default", "png"},
+			wantSize: image.Pt(50, 50),
+			wantLeft: red,
+		},
+		{
+			testName: "confined size",
+			params:   [5]string{"full", "!100,100", "0", "default", "png"},
+			wantSize: image.Pt(100, 50),
+			wantLeft: red,
+		},
+		{
+			testName: "mirrored",
+			params:   [5]string{"full", "pct:50", "!0", "default", "png"},
+			wantSize: image.Pt(200, 100),
+			wantLeft: blue,
+		},
+		{
+			testName: "rotated clockwise",
+			params:   [5]string{"full", "max", "90", "default", "png"},
+			wantSize: image.Pt(200, 400),
+		},
+		{
+			testName: "gray after rotation",
+			params:   [5]string{"full", "max", "180", "gray", "png"},
+			wantSize: image.Pt(400, 200),
+			wantLeft: color.NRGBA{R: 29, G: 29, B: 29, A: 255},
+		},
+		{
+			testName: "bitonal",
+			params:   [5]string{"full", "max", "0", "bitonal", "png"},
+			wantSize: image.Pt(400, 200),
+			wantLeft: color.NRGBA{A: 255},
+		},
+		{
+			testName: "upscale",
+			params:   [5]string{"full", "^800,", "0", "default", "png"},
+			wantSize: image.Pt(800, 400),
+			wantLeft: red,
+		},
+		{
+			testName:  "upscale without ^",
+			params:    [5]string{"full", "800,", "0", "default", "png"},
+			wantErrOn: "apply",
+		},
+		{
+			testName:  "region outside of the image",
+			params:    [5]string{"500,0,10,10", "max", "0", "default", "png"},
+			wantErrOn: "apply",
+		},
+		{
+			testName:  "invalid region",
+			params:    [5]string{"0,0,10", "max", "0", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "invalid size",
+			params:    [5]string{"full", "!100,", "0", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "invalid rotation",
+			params:    [5]string{"full", "max", "360", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "NaN rotation",
+			params:    [5]string{"full", "max", "NaN", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "NaN percent size",
+			params:    [5]string{"full", "pct:NaN", "0", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "infinite percent size",
+			params:    [5]string{"full", "pct:Inf", "0", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "NaN region",
+			params:    [5]string{"NaN,0,10,10", "max", "0", "default", "png"},
+			wantErrOn: "parse",
+		},
+		{
+			testName:  "unsupported format",
+			params:    [5]string{"full", "max", "0", "default", "webp"},
+			wantErrOn: "parse",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			var paramErr *conversion.IIIFParamError
+
+			req, err := conversion.ParseIIIF(tc.params[0], tc.params[1], tc.params[2], tc.params[3], tc.params[4])
+			if tc.wantErrOn == "parse" {
+				require.ErrorAs(t, err, &paramErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			res, err := req.Apply(img)
+			if tc.wantErrOn == "apply" {
+				require.ErrorAs(t, err, &paramErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSize, res.Bounds().Size())
+
+			if tc.wantLeft != (color.NRGBA{}) {
+				assert.Equal(t, tc.wantLeft, color.NRGBAModel.Convert(res.At(0, res.Bounds().Dy()/2)))
+			}
+		})
+	}
+}
+
+func TestIIIFRequest_Path(t *testing.T) {
+	req, err := conversion.ParseIIIF("pct:10,10,50,50", "^!300,200", "!90.50", "gray", "jpg")
+	require.NoError(t, err)
+
+	assert.Equal(t, "pct:10,10,50,50/^!300,200/!90.5/gray.jpg", req.Path())
+	assert.Equal(t, "jpeg", req.Type())
+}
@@ -0,0 +1,158 @@
+package conversion
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Radius of the Lanczos filter, which is used by the linear light resizing.
+const lanczosSupport = 3
+
+// Function that returns resized picture with the provided ratio. Unlike Resize it converts the picture
+// to the linear light before resampling and back to sRGB after it, so fine high-contrast details
+// don't become darker on downscaling. Each side is at least one pixel, so thin images aren't lost.
+func ResizeLinear(im image.Image, ratio float32) image.Image {
+	newX := maxInt(int(ratio*float32(im.Bounds().Dx())), 1)
+	newY := maxInt(int(ratio*float32(im.Bounds().Dy())), 1)
+
+	return resampleLinear(im, newX, newY)
+}
+
+// resampleLinear function resizes the picture with the Lanczos filter in the linear light.
+// Colours are premultiplied by alpha, so transparent pixels don't bleed to the neighbours.
+func resampleLinear(im image.Image, width, height int) *image.NRGBA {
+	if width <= 0 || height <= 0 || im.Bounds().Empty() {
+		return &image.NRGBA{}
+	}
+
+	src := imaging.Clone(im)
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+
+	var toLinear [256]float64
+	for i := range toLinear {
+		toLinear[i] = srgbToLinear(uint8(i))
+	}
+
+	pix := make([]float64, len(src.Pix))
+
+	for i := 0; i < len(src.Pix); i += 4 {
+		a := float64(src.Pix[i+3]) / math.MaxUint8
+		pix[i] = toLinear[src.Pix[i]] * a
+		pix[i+1] = toLinear[src.Pix[i+1]] * a
+		pix[i+2] = toLinear[src.Pix[i+2]] * a
+		pix[i+3] = a
+	}
+
+	rows := resampleRows(pix, srcW, srcH, width)
+	cols := resampleColumns(rows, width, srcH, height)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		a := math.Max(0, math.Min(1, cols[i+3]))
+		if a == 0 {
+			continue
+		}
+
+		dst.Pix[i] = uint8(linearToSRGB(cols[i] / a))
+		dst.Pix[i+1] = uint8(linearToSRGB(cols[i+1] / a))
+		dst.Pix[i+2] = uint8(linearToSRGB(cols[i+2] / a))
+		dst.Pix[i+3] = uint8(math.Round(a * math.MaxUint8))
+	}
+
+	return dst
+}
+
+// resampleRows function resizes each row of the buffer with the size width x height to the newWidth.
+func resampleRows(pix []float64, width, height, newWidth int) []float64 {
+	weights := resampleWeights(newWidth, width)
+	res := make([]float64, newWidth*height*4)
+
+	for y := 0; y < height; y++ {
+		for x, ws := range weights {
+			for _, w := range ws {
+				accumulate(res[(y*newWidth+x)*4:], pix[(y*width+w.index)*4:], w.weight)
+			}
+		}
+	}
+
+	return res
+}
+
+// resampleColumns function resizes each column of the buffer with the size width x height to the newHeight.
+func resampleColumns(pix []float64, width, height, newHeight int) []float64 {
+	weights := resampleWeights(newHeight, height)
+	res := make([]float64, width*newHeight*4)
+
+	for y, ws := range weights {
+		for x := 0; x < width; x++ {
+			for _, w := range ws {
+				accumulate(res[(y*width+x)*4:], pix[(w.index*width+x)*4:], w.weight)
+			}
+		}
+	}
+
+	return res
+}
+
+// accumulate function adds the weighted channels of the src pixel to the dst pixel.
+func accumulate(dst, src []float64, weight float64) {
+	dst[0] += src[0] * weight
+	dst[1] += src[1] * weight
+	dst[2] += src[2] * weight
+	dst[3] += src[3] * weight
+}
+
+type weight struct {
+	index  int
+	weight float64
+}
+
+// resampleWeights function returns for each destination pixel the weights of the source pixels.
+// On downscaling the filter is stretched to cover all source pixels.
+func resampleWeights(dstLen, srcLen int) [][]weight {
+	scale := float64(srcLen) / float64(dstLen)
+	filterScale := math.Max(scale, 1)
+	radius := lanczosSupport * filterScale
+
+	res := make([][]weight, dstLen)
+
+	for i := range res {
+		center := (float64(i)+0.5)*scale - 0.5
+
+		var sum float64
+
+		for j := int(math.Ceil(center - radius)); j <= int(math.Floor(center+radius)); j++ {
+			w := lanczos((float64(j) - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+
+			res[i] = append(res[i], weight{index: minInt(maxInt(j, 0), srcLen-1), weight: w})
+			sum += w
+		}
+
+		for k := range res[i] {
+			res[i][k].weight /= sum
+		}
+	}
+
+	return res
+}
+
+func lanczos(x float64) float64 {
+	x = math.Abs(x)
+
+	switch {
+	case x == 0:
+		return 1
+	case x < lanczosSupport:
+		px := math.Pi * x
+
+		return lanczosSupport * math.Sin(px) * math.Sin(px/lanczosSupport) / (px * px)
+	default:
+		return 0
+	}
+}
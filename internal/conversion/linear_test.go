@@ -0,0 +1,68 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+// checkerboard function returns the picture of one pixel black and white squares.
+func checkerboard(size int) *image.NRGBA {
+	im := imaging.New(size, size, color.Black)
+
+	for y := 0; y < size; y++ {
+		for x := (y + 1) % 2; x < size; x += 2 {
+			im.Set(x, y, color.White)
+		}
+	}
+
+	return im
+}
+
+// meanGray function returns the mean of the red channel of the picture.
+func meanGray(im image.Image) float64 {
+	n := imaging.Clone(im)
+
+	var sum float64
+	for i := 0; i < len(n.Pix); i += 4 {
+		sum += float64(n.Pix[i])
+	}
+
+	return sum / float64(len(n.Pix)/4)
+}
+
+func TestResizeLinear(t *testing.T) {
+	board := checkerboard(32)
+
+	srgb := conversion.Resize(board, 0.25)
+	linear := conversion.ResizeLinear(board, 0.25)
+
+	assert.Equal(t, image.Pt(8, 8), linear.Bounds().Size())
+
+	// Half of the light is the middle gray in sRGB space (128), but it's 188 in linear light.
+	assert.InDelta(t, 128, meanGray(srgb), 3)
+	assert.InDelta(t, 188, meanGray(linear), 3)
+}
+
+func TestResizeLinear_Thin(t *testing.T) {
+	im := imaging.New(100, 2, color.NRGBA{R: 0xff, A: 0xff})
+
+	got := conversion.ResizeLinear(im, 0.1)
+
+	assert.Equal(t, image.Pt(10, 1), got.Bounds().Size())
+}
+
+func TestResizeLinear_Transparency(t *testing.T) {
+	im := imaging.New(4, 4, color.Transparent)
+	im.Set(0, 0, color.NRGBA{R: 0xff, A: 0xff})
+
+	got := imaging.Clone(conversion.ResizeLinear(im, 0.5))
+
+	// Colour of the transparent pixels doesn't darken the visible one.
+	assert.Equal(t, uint8(0xff), got.NRGBAAt(0, 0).R)
+	assert.Less(t, got.NRGBAAt(0, 0).A, uint8(0xff))
+}
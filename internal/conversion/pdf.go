@@ -0,0 +1,185 @@
+package conversion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// PageFit defines how the image is placed on the pdf page.
+type PageFit string
+
+const (
+	// PageFitContain scales the image to fit into the page keeping aspect ratio.
+	PageFitContain PageFit = "contain"
+
+	// PageFitFill scales the image to cover the page keeping aspect ratio, image is clipped by margins.
+	PageFitFill PageFit = "fill"
+
+	// PageFitStretch stretches the image to the page without keeping aspect ratio.
+	PageFitStretch PageFit = "stretch"
+)
+
+// PageSizes are sizes of the portrait pages in points.
+var PageSizes = map[string][2]float64{
+	"a3":     {842, 1191},
+	"a4":     {595, 842},
+	"a5":     {420, 595},
+	"letter": {612, 792},
+	"legal":  {612, 1008},
+}
+
+// Quality of the jpeg images embedded into pdf.
+const pdfJPEGQuality = 90
+
+var ErrEmptyDocument = errors.New("document has no pages")
+
+// PageLayout is a layout of the pdf page, all sizes are in points.
+type PageLayout struct {
+	Width  float64
+	Height float64
+	Margin float64
+	Fit    PageFit
+}
+
+// PDF function writes images to the pdf document, one image per page.
+// Images are embedded as jpeg, transparent parts are placed on the white background.
+func PDF(images []image.Image, layout PageLayout) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, ErrEmptyDocument
+	}
+
+	if layout.Width <= 2*layout.Margin || layout.Height <= 2*layout.Margin || layout.Margin < 0 {
+		return nil, fmt.Errorf("pdf: margin %v doesn't fit the page %vx%v",
+			layout.Margin, layout.Width, layout.Height)
+	}
+
+	w := &pdfWriter{}
+	w.writeHeader()
+
+	// Objects 1 and 2 are catalog and pages, each page takes three objects:
+	// page itself, its content and the image.
+	pageIDs := make([]int, len(images))
+	for i := range images {
+		pageIDs[i] = 3 + 3*i
+	}
+
+	w.writeObject(1, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := new(bytes.Buffer)
+	for _, id := range pageIDs {
+		fmt.Fprintf(kids, "%d 0 R ", id)
+	}
+
+	w.writeObject(2, fmt.Sprintf("<< /Type /Pages /Kids [ %s] /Count %d >>", kids, len(images)))
+
+	for i, im := range images {
+		pageID, contentID, imageID := pageIDs[i], pageIDs[i]+1, pageIDs[i]+2
+
+		jpg := new(bytes.Buffer)
+		if err := jpeg.Encode(jpg, flatten(im), &jpeg.Options{Quality: pdfJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("pdf: %w", err)
+		}
+
+		w.writeObject(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+				"/Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			pdfNumber(layout.Width), pdfNumber(layout.Height), imageID, contentID))
+
+		w.writeStream(contentID, "", []byte(pageContent(im.Bounds().Size(), layout)))
+
+		b := im.Bounds()
+		w.writeStream(imageID, fmt.Sprintf(
+			"/Type /XObject /Subtype /Image /Width %d /Height %d "+
+				"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode",
+			b.Dx(), b.Dy()), jpg.Bytes())
+	}
+
+	w.writeTrailer(1)
+
+	return w.buf.Bytes(), nil
+}
+
+// pageContent function returns the content stream which draws the image on the page.
+func pageContent(size image.Point, layout PageLayout) string {
+	areaX, areaY := layout.Margin, layout.Margin
+	areaW, areaH := layout.Width-2*layout.Margin, layout.Height-2*layout.Margin
+	imW, imH := float64(size.X), float64(size.Y)
+
+	var w, h float64
+
+	switch layout.Fit {
+	case PageFitStretch:
+		w, h = areaW, areaH
+	case PageFitFill:
+		scale := math.Max(areaW/imW, areaH/imH)
+		w, h = imW*scale, imH*scale
+	case PageFitContain, "":
+		scale := math.Min(areaW/imW, areaH/imH)
+		w, h = imW*scale, imH*scale
+	}
+
+	x, y := areaX+(areaW-w)/2, areaY+(areaH-h)/2
+
+	// The area is used as clipping path, so filled images don't overlap margins.
+	return fmt.Sprintf("q\n%s %s %s %s re W n\n%s 0 0 %s %s %s cm\n/Im0 Do\nQ\n",
+		pdfNumber(areaX), pdfNumber(areaY), pdfNumber(areaW), pdfNumber(areaH),
+		pdfNumber(w), pdfNumber(h), pdfNumber(x), pdfNumber(y))
+}
+
+// flatten function draws the image on the white background.
+func flatten(im image.Image) image.Image {
+	b := im.Bounds()
+
+	return imaging.Overlay(imaging.New(b.Dx(), b.Dy(), color.White), im, image.Point{}, 1)
+}
+
+// pdfNumber function formats the number as pdf real.
+func pdfNumber(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+// pdfWriter writes pdf objects and remembers their offsets for the cross-reference table.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) writeHeader() {
+	// Binary comment marks the file as binary for the transfer programs.
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+// writeObject method writes indirect object, objects should be written in order of their ids.
+func (w *pdfWriter) writeObject(id int, body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// writeStream method writes stream object, dict contains additional entries of the stream dictionary.
+func (w *pdfWriter) writeStream(id int, dict string, data []byte) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// writeTrailer method writes cross-reference table and trailer.
+func (w *pdfWriter) writeTrailer(rootID int) {
+	xref := w.buf.Len()
+
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, rootID, xref)
+}
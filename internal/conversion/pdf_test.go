@@ -0,0 +1,62 @@
+package conversion_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPDF(t *testing.T) {
+	images := []image.Image{
+		solidImage(200, 100, color.NRGBA{R: 255, A: 255}),
+		solidImage(50, 80, color.NRGBA{B: 255, A: 128}),
+	}
+
+	layout := conversion.PageLayout{Width: 595, Height: 842, Margin: 36, Fit: conversion.PageFitContain}
+
+	doc, err := conversion.PDF(images, layout)
+	require.NoError(t, err)
+
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+	assert.Contains(t, string(doc), "/Count 2")
+	assert.Equal(t, 2, bytes.Count(doc, []byte("/MediaBox [0 0 595.00 842.00]")))
+	assert.Equal(t, 2, bytes.Count(doc, []byte("/Filter /DCTDecode")))
+
+	// Wide image is fitted to the width of the page without margins.
+	assert.Contains(t, string(doc), "523.00 0 0 261.50 36.00 290.25 cm")
+
+	// Every entry of the cross-reference table points to the object with the same id.
+	startXref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, startXref)
+
+	xrefOffset, err := strconv.Atoi(string(startXref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(doc[xrefOffset:], []byte("xref\n0 9\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xrefOffset:], -1)
+	require.Len(t, entries, 8)
+
+	for i, e := range entries {
+		offset, err := strconv.Atoi(string(e[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))))
+	}
+}
+
+func TestPDF_Errors(t *testing.T) {
+	_, err := conversion.PDF(nil, conversion.PageLayout{Width: 595, Height: 842})
+	assert.ErrorIs(t, err, conversion.ErrEmptyDocument)
+
+	_, err = conversion.PDF([]image.Image{solidImage(10, 10, color.White)},
+		conversion.PageLayout{Width: 595, Height: 842, Margin: 300})
+	assert.Error(t, err)
+}
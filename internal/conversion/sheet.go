@@ -0,0 +1,128 @@
+package conversion
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Space between the cell and its caption.
+const captionPadding = 4
+
+// MaxSheetPixels is the maximum amount of pixels on the contact sheet.
+const MaxSheetPixels = maxRasterSize * maxRasterSize
+
+// SheetLayout is a layout of the contact sheet.
+type SheetLayout struct {
+	Columns    int
+	CellWidth  int
+	CellHeight int
+	Spacing    int
+	Background color.Color
+}
+
+// SheetSize function returns the size of the contact sheet with the amount of images and the layout.
+func SheetSize(images int, withCaptions bool, layout SheetLayout) image.Point {
+	columns := minInt(layout.Columns, images)
+	if columns <= 0 {
+		return image.Point{}
+	}
+
+	rows := (images + columns - 1) / columns
+	rowHeight := layout.CellHeight + captionHeight(withCaptions)
+
+	return image.Pt(columns*layout.CellWidth+(columns+1)*layout.Spacing, rows*rowHeight+(rows+1)*layout.Spacing)
+}
+
+// captionHeight function returns the height of the caption line under the cell.
+func captionHeight(withCaptions bool) int {
+	if !withCaptions {
+		return 0
+	}
+
+	return basicfont.Face7x13.Metrics().Height.Ceil() + captionPadding
+}
+
+// ContactSheet function places images to the grid with the provided layout.
+// Each image is fitted to the cell keeping the aspect ratio.
+// If captions are provided, each caption is written under the image with the same index.
+func ContactSheet(images []image.Image, captions []string, layout SheetLayout) *image.NRGBA {
+	columns := minInt(layout.Columns, len(images))
+	face := basicfont.Face7x13
+	rowHeight := layout.CellHeight + captionHeight(len(captions) != 0)
+	size := SheetSize(len(images), len(captions) != 0, layout)
+
+	sheet := imaging.New(size.X, size.Y, layout.Background)
+	textColor := contrastColor(layout.Background)
+
+	for i, im := range images {
+		cellX := layout.Spacing + (i%columns)*(layout.CellWidth+layout.Spacing)
+		cellY := layout.Spacing + (i/columns)*(rowHeight+layout.Spacing)
+
+		fitted := imaging.Fit(im, layout.CellWidth, layout.CellHeight, imaging.Lanczos)
+		pos := image.Pt(cellX+(layout.CellWidth-fitted.Bounds().Dx())/2,
+			cellY+(layout.CellHeight-fitted.Bounds().Dy())/2)
+		draw.Draw(sheet, fitted.Bounds().Add(pos), fitted, image.Point{}, draw.Over)
+
+		if i < len(captions) {
+			drawCaption(sheet, face, textColor, captions[i],
+				image.Rect(cellX, cellY+layout.CellHeight, cellX+layout.CellWidth, cellY+rowHeight))
+		}
+	}
+
+	return sheet
+}
+
+// drawCaption function writes the text centered in the rectangle.
+// Text which doesn't fit into the rectangle is cut.
+func drawCaption(dst draw.Image, face font.Face, c color.Color, text string, rect image.Rectangle) {
+	text, textWidth := fitText(face, text, rect.Dx())
+
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	d.Dot = fixed.P(rect.Min.X+(rect.Dx()-textWidth)/2, rect.Max.Y-face.Metrics().Descent.Ceil())
+	d.DrawString(text)
+}
+
+// fitText function returns the longest prefix of the text, which is not wider than the width, and its width.
+// Text is measured in one pass the same way as font.MeasureString does it.
+func fitText(face font.Face, text string, width int) (string, int) {
+	var (
+		advance fixed.Int26_6
+		prev    rune = -1
+	)
+
+	for i, r := range text {
+		next := advance
+		if prev >= 0 {
+			next += face.Kern(prev, r)
+		}
+
+		glyphAdvance, _ := face.GlyphAdvance(r)
+		next += glyphAdvance
+
+		if next.Ceil() > width {
+			return text[:i], advance.Ceil()
+		}
+
+		advance, prev = next, r
+	}
+
+	return text, advance.Ceil()
+}
+
+// contrastColor function returns black for the light colors and white for the dark ones.
+func contrastColor(c color.Color) color.Color {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+
+	// Relative luminance with the Rec. 601 coefficients.
+	if 299*r+587*g+114*b > 1000*0xffff/2 {
+		return color.Black
+	}
+
+	return color.White
+}
@@ -0,0 +1,148 @@
+package conversion_test
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactSheet(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	gray := color.NRGBA{R: 40, G: 40, B: 40, A: 255}
+
+	images := []image.Image{
+		solidImage(200, 100, red),
+		solidImage(50, 50, blue),
+		solidImage(100, 200, red),
+	}
+
+	layout := conversion.SheetLayout{
+		Columns:    2,
+		CellWidth:  100,
+		CellHeight: 100,
+		Spacing:    10,
+		Background: gray,
+	}
+
+	t.Run("without captions", func(t *testing.T) {
+		sheet := conversion.ContactSheet(images, nil, layout)
+
+		assert.Equal(t, image.Pt(2*100+3*10, 2*100+3*10), sheet.Bounds().Size())
+
+		// Wide image is fitted to the cell width and centered vertically.
+		assert.Equal(t, gray, sheet.NRGBAAt(60, 20))
+		assert.Equal(t, red, sheet.NRGBAAt(60, 60))
+
+		// Small image is not enlarged.
+		assert.Equal(t, blue, sheet.NRGBAAt(170, 60))
+		assert.Equal(t, gray, sheet.NRGBAAt(125, 60))
+
+		// The third image is placed to the second row.
+		assert.Equal(t, red, sheet.NRGBAAt(60, 170))
+		assert.Equal(t, gray, sheet.NRGBAAt(170, 170))
+	})
+
+	t.Run("with captions", func(t *testing.T) {
+		sheet := conversion.ContactSheet(images, []string{"a", "b", "c"}, layout)
+		assert.Equal(t, conversion.SheetSize(len(images), true, layout), sheet.Bounds().Size())
+
+		// Rows are higher by the line of the caption text.
+		captionHeight := (sheet.Bounds().Dy() - 3*10 - 2*100) / 2
+		assert.Greater(t, captionHeight, 0)
+		assert.Equal(t, 2*100+3*10, sheet.Bounds().Dx())
+
+		// Caption is written with white color on the dark background.
+		hasText := false
+
+		for y := 110; y < 110+captionHeight; y++ {
+			for x := 10; x < 110; x++ {
+				if sheet.NRGBAAt(x, y) == (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) {
+					hasText = true
+				}
+			}
+		}
+
+		assert.True(t, hasText)
+	})
+
+	t.Run("long caption is cut", func(t *testing.T) {
+		sheet := conversion.ContactSheet(images, []string{strings.Repeat("W", 1000)}, layout)
+		captionHeight := (sheet.Bounds().Dy() - 3*10 - 2*100) / 2
+
+		// Text isn't written to the spacing around the cell.
+		for y := 110; y < 110+captionHeight; y++ {
+			for x := 0; x < 10; x++ {
+				assert.Equal(t, gray, sheet.NRGBAAt(x, y))
+				assert.Equal(t, gray, sheet.NRGBAAt(110+x, y))
+			}
+		}
+	})
+}
+
+func TestSheetSize(t *testing.T) {
+	layout := conversion.SheetLayout{Columns: 4, CellWidth: 100, CellHeight: 50, Spacing: 10}
+
+	testCases := []struct {
+		testName string
+		images   int
+		want     image.Point
+	}{
+		{
+			testName: "fewer images than columns",
+			images:   2,
+			want:     image.Pt(2*100+3*10, 50+2*10),
+		},
+		{
+			testName: "several rows",
+			images:   9,
+			want:     image.Pt(4*100+5*10, 3*50+4*10),
+		},
+		{
+			testName: "no images",
+			images:   0,
+			want:     image.Point{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			assert.Equal(t, tc.want, conversion.SheetSize(tc.images, false, layout))
+		})
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		color     string
+		wantColor color.NRGBA
+		wantErr   bool
+	}{
+		{testName: "rgb", color: "#ff8000", wantColor: color.NRGBA{R: 255, G: 128, A: 255}},
+		{testName: "rgba", color: "#00000080", wantColor: color.NRGBA{A: 128}},
+		{testName: "without hash", color: "ff8000", wantErr: true},
+		{testName: "wrong length", color: "#fff", wantErr: true},
+		{testName: "not hex", color: "#gggggg", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			got, err := conversion.ParseHexColor(tc.color)
+			if tc.wantErr {
+				var colorErr *conversion.InvalidColorError
+				require.ErrorAs(t, err, &colorErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantColor, got)
+		})
+	}
+}
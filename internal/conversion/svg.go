@@ -0,0 +1,210 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+const (
+	// Default resolution of the SVG, user units are CSS pixels.
+	svgDefaultDPI = 96
+
+	// Maximum size of the rasterized side.
+	maxRasterSize = 10000
+)
+
+var ErrSVGWithoutSize = errors.New("svg has no size and no size was requested")
+
+type UnsafeSVGError struct {
+	Reason string
+}
+
+func (e *UnsafeSVGError) Error() string {
+	return fmt.Sprintf("unsafe svg: %s", e.Reason)
+}
+
+// Elements which are refused, because they can execute code or load external content.
+var unsafeSVGElements = map[string]bool{
+	"script":        true,
+	"foreignObject": true,
+	"iframe":        true,
+}
+
+// CheckSVG function returns UnsafeSVGError if the svg uses features, which are not allowed.
+// Scripts, foreign objects, DTD declarations and references to the resources
+// outside the document are refused.
+func CheckSVG(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	hasSVG := false
+	// Depth of the style elements, the text inside them is a style sheet.
+	styleDepth := 0
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("check svg: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.Directive:
+			return &UnsafeSVGError{"document type declarations are not allowed"}
+
+		case xml.ProcInst:
+			if t.Target != "xml" {
+				return &UnsafeSVGError{fmt.Sprintf("processing instruction %q is not allowed", t.Target)}
+			}
+
+		case xml.StartElement:
+			if t.Name.Local == "svg" {
+				hasSVG = true
+			}
+
+			if t.Name.Local == "style" {
+				styleDepth++
+			}
+
+			if unsafeSVGElements[t.Name.Local] {
+				return &UnsafeSVGError{fmt.Sprintf("element %q is not allowed", t.Name.Local)}
+			}
+
+			if err := checkSVGAttrs(t.Attr); err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			if t.Name.Local == "style" && styleDepth > 0 {
+				styleDepth--
+			}
+
+		case xml.CharData:
+			if strings.Contains(strings.ToLower(string(t)), "@import") {
+				return &UnsafeSVGError{"style imports are not allowed"}
+			}
+
+			if styleDepth > 0 && !hasOnlyLocalURLs(string(t)) {
+				return &UnsafeSVGError{"external reference in style is not allowed"}
+			}
+		}
+	}
+
+	if !hasSVG {
+		return &UnsafeSVGError{"document has no svg element"}
+	}
+
+	return nil
+}
+
+// checkSVGAttrs function checks that attributes reference only the document itself.
+func checkSVGAttrs(attrs []xml.Attr) error {
+	for _, attr := range attrs {
+		name := attr.Name.Local
+		value := strings.TrimSpace(attr.Value)
+
+		if strings.HasPrefix(strings.ToLower(name), "on") {
+			return &UnsafeSVGError{fmt.Sprintf("event handler %q is not allowed", name)}
+		}
+
+		if name == "href" && !strings.HasPrefix(value, "#") {
+			return &UnsafeSVGError{fmt.Sprintf("external reference %q is not allowed", value)}
+		}
+
+		if !hasOnlyLocalURLs(value) {
+			return &UnsafeSVGError{fmt.Sprintf("external reference in %q is not allowed", name)}
+		}
+	}
+
+	return nil
+}
+
+// hasOnlyLocalURLs function returns false if value has url(...) which doesn't point to the fragment.
+// CSS functions are case-insensitive, so URL(...) is checked too.
+func hasOnlyLocalURLs(value string) bool {
+	value = strings.ToLower(value)
+
+	for {
+		i := strings.Index(value, "url(")
+		if i == -1 {
+			return true
+		}
+
+		value = strings.TrimLeft(value[i+len("url("):], ` '"`)
+		if !strings.HasPrefix(value, "#") {
+			return false
+		}
+	}
+}
+
+// RasterizeSVG function renders svg to the image.
+// If width or height are provided image is rasterized to this size,
+// if only one of them is provided, the other one keeps aspect ratio.
+// Otherwise the intrinsic size of svg is scaled with the dpi, zero dpi means 96.
+func RasterizeSVG(data []byte, width, height int, dpi float64) (image.Image, error) {
+	if err := CheckSVG(data); err != nil {
+		return nil, err
+	}
+
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("read svg: %w", err)
+	}
+
+	width, height, err = rasterSize(icon.ViewBox.W, icon.ViewBox.H, width, height, dpi)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+
+	return img, nil
+}
+
+// rasterSize function returns the size of the rasterized svg.
+func rasterSize(svgW, svgH float64, width, height int, dpi float64) (int, int, error) {
+	if width <= 0 && height <= 0 {
+		if svgW <= 0 || svgH <= 0 {
+			return 0, 0, ErrSVGWithoutSize
+		}
+
+		if dpi <= 0 {
+			dpi = svgDefaultDPI
+		}
+
+		width = int(math.Round(svgW * dpi / svgDefaultDPI))
+		height = int(math.Round(svgH * dpi / svgDefaultDPI))
+	}
+
+	if width <= 0 || height <= 0 {
+		if svgW <= 0 || svgH <= 0 {
+			return 0, 0, ErrSVGWithoutSize
+		}
+
+		if width <= 0 {
+			width = int(math.Round(svgW * float64(height) / svgH))
+		} else {
+			height = int(math.Round(svgH * float64(width) / svgW))
+		}
+	}
+
+	if width <= 0 || height <= 0 || width > maxRasterSize || height > maxRasterSize {
+		return 0, 0, fmt.Errorf("rasterize svg: size %vx%v is out of range", width, height)
+	}
+
+	return width, height, nil
+}
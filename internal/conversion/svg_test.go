@@ -0,0 +1,148 @@
+package conversion_test
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/stretchr/testify/assert"
+)
+
+const redSquareSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="40" height="20" viewBox="0 0 40 20">
+	<rect x="0" y="0" width="40" height="20" fill="#ff0000"/>
+</svg>`
+
+func TestCheckSVG(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		svg        string
+		wantUnsafe bool
+	}{
+		{
+			testName: "safe svg",
+			svg:      redSquareSVG,
+		},
+		{
+			testName: "local reference",
+			svg: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+				<defs><linearGradient id="g"/></defs>
+				<rect fill="url(#g)" width="1" height="1"/><use xlink:href="#g"/></svg>`,
+		},
+		{
+			testName:   "script",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName: "external image",
+			svg: `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+				<image xlink:href="http://example.com/a.png"/></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "external url in attribute",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><rect fill="url('http://example.com/#g')"/></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "upper case external url in attribute",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><rect fill="URL(http://example.com/#g)"/></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName: "local url in style",
+			svg: `<svg xmlns="http://www.w3.org/2000/svg"><defs><linearGradient id="g"/></defs>
+				<style>rect{fill:url(#g)}</style><rect width="1" height="1"/></svg>`,
+		},
+		{
+			testName:   "external url in style",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><style>rect{fill:url(http://evil/x)}</style></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "upper case external url in style",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><style>rect{fill:URL(http://evil/x)}</style></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "external url in style section",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><style><![CDATA[rect{fill:url(http://evil/x)}]]></style></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "upper case import",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg"><style>@IMPORT "http://evil/x.css";</style></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "event handler",
+			svg:        `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"></svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName: "entity declaration",
+			svg: `<?xml version="1.0"?><!DOCTYPE svg [<!ENTITY x SYSTEM "file:///etc/passwd">]>
+				<svg xmlns="http://www.w3.org/2000/svg">&x;</svg>`,
+			wantUnsafe: true,
+		},
+		{
+			testName:   "not svg",
+			svg:        `<html></html>`,
+			wantUnsafe: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := conversion.CheckSVG([]byte(tc.svg))
+
+			var unsafeErr *conversion.UnsafeSVGError
+			assert.Equal(t, tc.wantUnsafe, errors.As(err, &unsafeErr))
+		})
+	}
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	testCases := []struct {
+		testName string
+		width    int
+		height   int
+		dpi      float64
+		wantSize image.Point
+	}{
+		{
+			testName: "intrinsic size",
+			wantSize: image.Pt(40, 20),
+		},
+		{
+			testName: "double dpi",
+			dpi:      192,
+			wantSize: image.Pt(80, 40),
+		},
+		{
+			testName: "width keeps aspect ratio",
+			width:    100,
+			wantSize: image.Pt(100, 50),
+		},
+		{
+			testName: "width and height",
+			width:    16,
+			height:   16,
+			wantSize: image.Pt(16, 16),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			img, err := conversion.RasterizeSVG([]byte(redSquareSVG), tc.width, tc.height, tc.dpi)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantSize, img.Bounds().Size())
+
+			r, g, b, a := img.At(tc.wantSize.X/2, tc.wantSize.Y/2).RGBA()
+			assert.Equal(t, [4]uint32{0xffff, 0, 0, 0xffff}, [4]uint32{r, g, b, a})
+		})
+	}
+}
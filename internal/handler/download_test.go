@@ -29,6 +29,7 @@ func TestDownload_Download(t *testing.T) {
 		wantStatus   int
 		wantBody     string
 		wantFilename string
+		wantType     string
 	}{
 		{
 			testName: "ok",
@@ -48,6 +49,27 @@ func TestDownload_Download(t *testing.T) {
 			wantStatus:   http.StatusOK,
 			wantBody:     "body",
 			wantFilename: `filename="filename"`,
+			wantType:     "text/plain; charset=utf-8",
+		},
+		{
+			testName: "pdf document",
+			method:   http.MethodGet,
+			path:     "image/download/13",
+			configure: func(r *http.Request, md *mocks.MockDownloader) *http.Request {
+				md.EXPECT().DownloadImage(gomock.Any(), 2, 13).Return([]byte("%PDF-1.4"), "document.pdf", nil).Times(1)
+
+				r = mux.SetURLVars(r, map[string]string{
+					"id": "13",
+				})
+
+				ctx := context.WithValue(r.Context(), jwt.KeyUserID, 2)
+
+				return r.WithContext(ctx)
+			},
+			wantStatus:   http.StatusOK,
+			wantBody:     "%PDF-1.4",
+			wantFilename: `filename="document.pdf"`,
+			wantType:     "application/pdf",
 		},
 		{
 			testName: "no auth",
@@ -144,6 +166,7 @@ func TestDownload_Download(t *testing.T) {
 			assert.Equal(t, rr.Code, tc.wantStatus)
 			if rr.Code == http.StatusOK {
 				assert.Equal(t, rr.Header()["Content-Disposition"][1], tc.wantFilename)
+				assert.Equal(t, tc.wantType, rr.Header().Get("Content-Type"))
 			}
 			assert.Equal(t, rr.Body.String(), tc.wantBody)
 		})
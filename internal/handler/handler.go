@@ -4,15 +4,18 @@ import (
 	"net/http"
 
 	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/signing"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
 // Handler is a struct which has service interfaces.
 type Handler struct {
-	authHandler AuthenticationHandler
-	reqHandler  RequestHandler
-	downHandler DownloadHandler
+	authHandler  AuthenticationHandler
+	reqHandler   RequestHandler
+	downHandler  DownloadHandler
+	transHandler TransformHandler
+	signHandler  SigningHandler
 
 	// logger is used to write all logs in Handler
 	logger *logrus.Logger
@@ -20,9 +23,9 @@ type Handler struct {
 
 // This constructor initialize Handler's fields with provided arguments.
 func New(authHand AuthenticationHandler, reqHandler RequestHandler, downHandler DownloadHandler,
-	logger *logrus.Logger) *Handler {
+	transHandler TransformHandler, signHandler SigningHandler, logger *logrus.Logger) *Handler {
 	return &Handler{authHandler: authHand, reqHandler: reqHandler, downHandler: downHandler,
-		logger: logger}
+		transHandler: transHandler, signHandler: signHandler, logger: logger}
 }
 
 type AuthenticationHandler interface {
@@ -34,15 +37,31 @@ type RequestHandler interface {
 	GetAllRequests(w http.ResponseWriter, r *http.Request)
 	GetRequest(w http.ResponseWriter, r *http.Request)
 	AddRequest(w http.ResponseWriter, r *http.Request)
+	AddContactSheetRequest(w http.ResponseWriter, r *http.Request)
+	AddPDFRequest(w http.ResponseWriter, r *http.Request)
+	AddImageRequest(w http.ResponseWriter, r *http.Request)
+	GetTile(w http.ResponseWriter, r *http.Request)
 	DeleteRequest(w http.ResponseWriter, r *http.Request)
+	CancelRequest(w http.ResponseWriter, r *http.Request)
 }
 
 type DownloadHandler interface {
 	DownloadImage(w http.ResponseWriter, r *http.Request)
 }
 
+type TransformHandler interface {
+	IIIFImage(w http.ResponseWriter, r *http.Request)
+	IIIFInfo(w http.ResponseWriter, r *http.Request)
+	Render(w http.ResponseWriter, r *http.Request)
+	Diff(w http.ResponseWriter, r *http.Request)
+}
+
+type SigningHandler interface {
+	SignURL(w http.ResponseWriter, r *http.Request)
+}
+
 // InitRouters() method is used to initialize all endopoints with the routers.
-func (h *Handler) InitRouters(jwtGen *jwt.Gen) *mux.Router {
+func (h *Handler) InitRouters(jwtGen *jwt.Gen, signer *signing.Signer) *mux.Router {
 	router := mux.NewRouter()
 	router.Use(h.logTime)
 
@@ -50,15 +69,40 @@ func (h *Handler) InitRouters(jwtGen *jwt.Gen) *mux.Router {
 	jwtChecker := JwtChecker{Gen: *jwtGen}
 	authRouter.Use(jwtChecker.CheckJWT)
 
+	publicRouter := router.PathPrefix(PublicPrefix).Subrouter()
+	signatureChecker := SignatureChecker{Signer: signer}
+	publicRouter.Use(signatureChecker.CheckSignature)
+
 	router.HandleFunc("/auth/register", h.authHandler.Register).Methods(http.MethodPost)
 	router.HandleFunc("/auth/login", h.authHandler.Login).Methods(http.MethodPost)
 
 	authRouter.HandleFunc("/requests", h.reqHandler.GetAllRequests).Methods(http.MethodGet)
 	authRouter.HandleFunc("/requests/{reqID}", h.reqHandler.GetRequest).Methods(http.MethodGet)
 	authRouter.HandleFunc("/requests/image", h.reqHandler.AddRequest).Methods(http.MethodPost)
+	authRouter.HandleFunc("/requests/contact-sheet", h.reqHandler.AddContactSheetRequest).Methods(http.MethodPost)
+	authRouter.HandleFunc("/requests/pdf", h.reqHandler.AddPDFRequest).Methods(http.MethodPost)
 	authRouter.HandleFunc("/requests/{reqID}", h.reqHandler.DeleteRequest).Methods(http.MethodDelete)
+	authRouter.HandleFunc("/requests/{reqID}/cancel", h.reqHandler.CancelRequest).Methods(http.MethodPost)
+	authRouter.HandleFunc("/requests/{reqID}/tiles/{level}/{col}/{row}", h.reqHandler.GetTile).
+		Methods(http.MethodGet)
 
 	authRouter.HandleFunc("/download/image/{id}", h.downHandler.DownloadImage).Methods(http.MethodGet)
+	authRouter.HandleFunc("/images/{id}/requests", h.reqHandler.AddImageRequest).Methods(http.MethodPost)
+
+	authRouter.HandleFunc("/iiif/{id}/info.json", h.transHandler.IIIFInfo).Methods(http.MethodGet)
+	authRouter.HandleFunc("/iiif/{id}/{region}/{size}/{rotation}/{quality:[a-z]+}.{format:[a-z]+}",
+		h.transHandler.IIIFImage).Methods(http.MethodGet)
+
+	authRouter.HandleFunc("/render/{id}", h.transHandler.Render).Methods(http.MethodGet)
+	authRouter.HandleFunc("/diff", h.transHandler.Diff).Methods(http.MethodPost)
+
+	authRouter.HandleFunc("/sign", h.signHandler.SignURL).Methods(http.MethodPost)
+
+	publicRouter.HandleFunc("/download/image/{id}", h.downHandler.DownloadImage).Methods(http.MethodGet)
+	publicRouter.HandleFunc("/iiif/{id}/info.json", h.transHandler.IIIFInfo).Methods(http.MethodGet)
+	publicRouter.HandleFunc("/iiif/{id}/{region}/{size}/{rotation}/{quality:[a-z]+}.{format:[a-z]+}",
+		h.transHandler.IIIFImage).Methods(http.MethodGet)
+	publicRouter.HandleFunc("/render/{id}", h.transHandler.Render).Methods(http.MethodGet)
 
 	return router
 }
@@ -10,6 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/signing"
 )
 
 const (
@@ -65,6 +66,31 @@ func (ch *JwtChecker) CheckJWT(handler http.Handler) http.Handler {
 	})
 }
 
+type SignatureChecker struct {
+	Signer *signing.Signer
+}
+
+// CheckSignature allows access to the public endpoints with the signed urls.
+// Signature is verified over the path without PublicPrefix and the query.
+// User on behalf of whom the url was signed is put to the context,
+// so the next handler checks the ownership in the same way as with jwt.
+// Tampered or expired urls are responded with the forbidden status.
+func (sc *SignatureChecker) CheckSignature(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath := strings.TrimPrefix(r.URL.Path, PublicPrefix)
+
+		userID, err := sc.Signer.Verify(urlPath, r.URL.Query(), time.Now())
+		if err != nil {
+			newErrorResponse(w, http.StatusForbidden, fmt.Errorf("middleware: %w", err).Error())
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), jwt.KeyUserID, userID)
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // logTime is used to log all incoming requests.
 // It logs request's url, method and time for answer to this reqeust.
 func (h *Handler) logTime(handler http.Handler) http.Handler {
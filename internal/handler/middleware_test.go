@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/Dyleme/image-coverter/internal/handler"
 	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/signing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,3 +130,72 @@ func TestCheckJwt(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckSignature(t *testing.T) {
+	signer := signing.NewSigner(&signing.Config{Key: "key"})
+
+	signedQuery := func(path string, ttl time.Duration, now time.Time) string {
+		q, _, err := signer.Sign(path, url.Values{"w": {"300"}}, 12, ttl, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return q.Encode()
+	}
+
+	testCases := []struct {
+		testName   string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName:   "ok",
+			target:     "/public/render/3?" + signedQuery("/render/3", time.Hour, time.Now()),
+			wantStatus: http.StatusOK,
+			wantBody:   "12",
+		},
+		{
+			testName:   "other image",
+			target:     "/public/render/4?" + signedQuery("/render/3", time.Hour, time.Now()),
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"message":"middleware: invalid url signature"}`,
+		},
+		{
+			testName: "changed parameter",
+			target: "/public/render/3?" + strings.Replace(signedQuery("/render/3", time.Hour, time.Now()),
+				"w=300", "w=3000", 1),
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"message":"middleware: invalid url signature"}`,
+		},
+		{
+			testName:   "expired",
+			target:     "/public/render/3?" + signedQuery("/render/3", time.Hour, time.Now().Add(-2*time.Hour)),
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"message":"middleware: signed url is expired"}`,
+		},
+		{
+			testName:   "not signed",
+			target:     "/public/render/3?w=300",
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"message":"middleware: url is not signed"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.target, &strings.Reader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checker := handler.SignatureChecker{Signer: signer}
+
+			rr := httptest.NewRecorder()
+
+			checker.CheckSignature(&handMock).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
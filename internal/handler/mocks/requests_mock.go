@@ -0,0 +1,171 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Dyleme/image-coverter/internal/handler (interfaces: Requester)
+
+// Package mock_handler is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	model "github.com/Dyleme/image-coverter/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRequester is a mock of Requester interface.
+type MockRequester struct {
+	ctrl     *gomock.Controller
+	recorder *MockRequesterMockRecorder
+}
+
+// MockRequesterMockRecorder is the mock recorder for MockRequester.
+type MockRequesterMockRecorder struct {
+	mock *MockRequester
+}
+
+// NewMockRequester creates a new mock instance.
+func NewMockRequester(ctrl *gomock.Controller) *MockRequester {
+	mock := &MockRequester{ctrl: ctrl}
+	mock.recorder = &MockRequesterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRequester) EXPECT() *MockRequesterMockRecorder {
+	return m.recorder
+}
+
+// AddContactSheetRequest mocks base method.
+func (m *MockRequester) AddContactSheetRequest(arg0 context.Context, arg1 int, arg2 model.ContactSheetInfo) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddContactSheetRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddContactSheetRequest indicates an expected call of AddContactSheetRequest.
+func (mr *MockRequesterMockRecorder) AddContactSheetRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddContactSheetRequest", reflect.TypeOf((*MockRequester)(nil).AddContactSheetRequest), arg0, arg1, arg2)
+}
+
+// AddImageRequest mocks base method.
+func (m *MockRequester) AddImageRequest(arg0 context.Context, arg1, arg2 int, arg3 model.ConversionInfo) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddImageRequest", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddImageRequest indicates an expected call of AddImageRequest.
+func (mr *MockRequesterMockRecorder) AddImageRequest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImageRequest", reflect.TypeOf((*MockRequester)(nil).AddImageRequest), arg0, arg1, arg2, arg3)
+}
+
+// AddPDFRequest mocks base method.
+func (m *MockRequester) AddPDFRequest(arg0 context.Context, arg1 int, arg2 model.PDFInfo) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPDFRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPDFRequest indicates an expected call of AddPDFRequest.
+func (mr *MockRequesterMockRecorder) AddPDFRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPDFRequest", reflect.TypeOf((*MockRequester)(nil).AddPDFRequest), arg0, arg1, arg2)
+}
+
+// AddRequest mocks base method.
+func (m *MockRequester) AddRequest(arg0 context.Context, arg1 int, arg2 io.Reader, arg3 string, arg4 model.ConversionInfo) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRequest", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddRequest indicates an expected call of AddRequest.
+func (mr *MockRequesterMockRecorder) AddRequest(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRequest", reflect.TypeOf((*MockRequester)(nil).AddRequest), arg0, arg1, arg2, arg3, arg4)
+}
+
+// CancelRequest mocks base method.
+func (m *MockRequester) CancelRequest(arg0 context.Context, arg1, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRequest indicates an expected call of CancelRequest.
+func (mr *MockRequesterMockRecorder) CancelRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRequest", reflect.TypeOf((*MockRequester)(nil).CancelRequest), arg0, arg1, arg2)
+}
+
+// DeleteRequest mocks base method.
+func (m *MockRequester) DeleteRequest(arg0 context.Context, arg1, arg2 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRequest indicates an expected call of DeleteRequest.
+func (mr *MockRequesterMockRecorder) DeleteRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRequest", reflect.TypeOf((*MockRequester)(nil).DeleteRequest), arg0, arg1, arg2)
+}
+
+// GetRequest mocks base method.
+func (m *MockRequester) GetRequest(arg0 context.Context, arg1, arg2 int) (*model.Request, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.Request)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequest indicates an expected call of GetRequest.
+func (mr *MockRequesterMockRecorder) GetRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequest", reflect.TypeOf((*MockRequester)(nil).GetRequest), arg0, arg1, arg2)
+}
+
+// GetRequests mocks base method.
+func (m *MockRequester) GetRequests(arg0 context.Context, arg1 int) ([]model.Request, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequests", arg0, arg1)
+	ret0, _ := ret[0].([]model.Request)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequests indicates an expected call of GetRequests.
+func (mr *MockRequesterMockRecorder) GetRequests(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequests", reflect.TypeOf((*MockRequester)(nil).GetRequests), arg0, arg1)
+}
+
+// GetTile mocks base method.
+func (m *MockRequester) GetTile(arg0 context.Context, arg1, arg2, arg3, arg4, arg5 int) ([]byte, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTile", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetTile indicates an expected call of GetTile.
+func (mr *MockRequesterMockRecorder) GetTile(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTile", reflect.TypeOf((*MockRequester)(nil).GetTile), arg0, arg1, arg2, arg3, arg4, arg5)
+}
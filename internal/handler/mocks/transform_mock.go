@@ -0,0 +1,98 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Dyleme/image-coverter/internal/handler (interfaces: Transformer)
+
+// Package mock_handler is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/Dyleme/image-coverter/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTransformer is a mock of Transformer interface.
+type MockTransformer struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransformerMockRecorder
+}
+
+// MockTransformerMockRecorder is the mock recorder for MockTransformer.
+type MockTransformerMockRecorder struct {
+	mock *MockTransformer
+}
+
+// NewMockTransformer creates a new mock instance.
+func NewMockTransformer(ctrl *gomock.Controller) *MockTransformer {
+	mock := &MockTransformer{ctrl: ctrl}
+	mock.recorder = &MockTransformerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransformer) EXPECT() *MockTransformerMockRecorder {
+	return m.recorder
+}
+
+// Diff mocks base method.
+func (m *MockTransformer) Diff(arg0 context.Context, arg1 int, arg2 model.DiffInfo) (*model.DiffResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Diff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.DiffResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Diff indicates an expected call of Diff.
+func (mr *MockTransformerMockRecorder) Diff(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Diff", reflect.TypeOf((*MockTransformer)(nil).Diff), arg0, arg1, arg2)
+}
+
+// IIIFImage mocks base method.
+func (m *MockTransformer) IIIFImage(arg0 context.Context, arg1, arg2 int, arg3 model.IIIFParams) ([]byte, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IIIFImage", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IIIFImage indicates an expected call of IIIFImage.
+func (mr *MockTransformerMockRecorder) IIIFImage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IIIFImage", reflect.TypeOf((*MockTransformer)(nil).IIIFImage), arg0, arg1, arg2, arg3)
+}
+
+// IIIFInfo mocks base method.
+func (m *MockTransformer) IIIFInfo(arg0 context.Context, arg1, arg2 int, arg3 string) (*model.IIIFInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IIIFInfo", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*model.IIIFInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IIIFInfo indicates an expected call of IIIFInfo.
+func (mr *MockTransformerMockRecorder) IIIFInfo(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IIIFInfo", reflect.TypeOf((*MockTransformer)(nil).IIIFInfo), arg0, arg1, arg2, arg3)
+}
+
+// Render mocks base method.
+func (m *MockTransformer) Render(arg0 context.Context, arg1, arg2 int, arg3 model.RenderParams) ([]byte, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Render", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Render indicates an expected call of Render.
+func (mr *MockTransformerMockRecorder) Render(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Render", reflect.TypeOf((*MockTransformer)(nil).Render), arg0, arg1, arg2, arg3)
+}
@@ -2,13 +2,16 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
 
 	"github.com/Dyleme/image-coverter/internal/jwt"
 	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
@@ -18,7 +21,12 @@ type Requester interface {
 	GetRequests(ctx context.Context, userID int) ([]model.Request, error)
 	GetRequest(ctx context.Context, userID int, reqID int) (*model.Request, error)
 	DeleteRequest(ctx context.Context, userID int, reqID int) error
+	CancelRequest(ctx context.Context, userID int, reqID int) error
 	AddRequest(context.Context, int, io.Reader, string, model.ConversionInfo) (int, error)
+	AddContactSheetRequest(ctx context.Context, userID int, info model.ContactSheetInfo) (int, error)
+	AddPDFRequest(ctx context.Context, userID int, info model.PDFInfo) (int, error)
+	AddImageRequest(ctx context.Context, userID, imageID int, convInfo model.ConversionInfo) (int, error)
+	GetTile(ctx context.Context, userID, reqID, level, col, row int) ([]byte, string, error)
 }
 
 // Struct which provides methods to handle working with requests.
@@ -114,6 +122,170 @@ func (rh *Request) AddRequest(w http.ResponseWriter, r *http.Request) {
 	newJSONResponse(w, m)
 }
 
+// AddContactSheetRequest is handler which adds request to make contact sheet from the user's images.
+// Method response with request id or error, if any occurs.
+// User id is getted from context.
+// Information about the sheet is took from the json body.
+// Handler calls service method AddContactSheetRequest.
+func (rh *Request) AddContactSheetRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := jwt.GetUserFromContext(ctx)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	var info model.ContactSheetInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	reqID, err := rh.requestService.AddContactSheetRequest(ctx, userID, info)
+	if err != nil {
+		rh.logger.Warn(err)
+		newAddRequestErrorResponse(w, err)
+
+		return
+	}
+
+	m := struct {
+		RequestID int `json:"requestID"`
+	}{
+		RequestID: reqID,
+	}
+
+	newJSONResponse(w, m)
+}
+
+// AddPDFRequest is handler which adds request to make pdf document from the user's images.
+// Method response with request id or error, if any occurs.
+// User id is getted from context.
+// Information about the document is took from the json body.
+// Handler calls service method AddPDFRequest.
+func (rh *Request) AddPDFRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := jwt.GetUserFromContext(ctx)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	var info model.PDFInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	reqID, err := rh.requestService.AddPDFRequest(ctx, userID, info)
+	if err != nil {
+		rh.logger.Warn(err)
+		newAddRequestErrorResponse(w, err)
+
+		return
+	}
+
+	m := struct {
+		RequestID int `json:"requestID"`
+	}{
+		RequestID: reqID,
+	}
+
+	newJSONResponse(w, m)
+}
+
+// AddImageRequest is handler which adds request to convert the already stored user's image.
+// Method response with request id or error, if any occurs.
+// User id is getted from context.
+// Image id is getted from query, information about conversion is took from the json body.
+// Handler calls service method AddImageRequest.
+func (rh *Request) AddImageRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := jwt.GetUserFromContext(ctx)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	strImageID, ok := mux.Vars(r)["id"]
+	if !ok {
+		rh.logger.Warn("id parameter is missing")
+		newErrorResponse(w, http.StatusBadRequest, "id parameter is missing")
+
+		return
+	}
+
+	imageID, err := strconv.Atoi(strImageID)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	var info model.ConversionInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	reqID, err := rh.requestService.AddImageRequest(ctx, userID, imageID, info)
+	if err != nil {
+		rh.logger.Warn(err)
+		newAddRequestErrorResponse(w, err)
+
+		return
+	}
+
+	m := struct {
+		RequestID int `json:"requestID"`
+	}{
+		RequestID: reqID,
+	}
+
+	newJSONResponse(w, m)
+}
+
+// newAddRequestErrorResponse function responds with the error of adding the request made from the stored images.
+// Missing or not owned images are responded with the not found status, invalid options with the bad request.
+func newAddRequestErrorResponse(w http.ResponseWriter, err error) {
+	var (
+		ratioErr    *service.RatioNotInRangeError
+		optionErr   *service.InvalidOptionError
+		typeErr     service.UnsupportedTypeError
+		notOwnedErr *service.NotOwnedImagesError
+	)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		newErrorResponse(w, http.StatusNotFound, "image not found")
+	case errors.As(err, &notOwnedErr):
+		newErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &ratioErr), errors.As(err, &optionErr), errors.As(err, &typeErr):
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 // GetRequstHandler is handler which get one reqest.
 // Method response with the json representation of request id or error, if any occurs.
 // User id is getted from context.
@@ -203,3 +375,120 @@ func (rh *Request) DeleteRequest(w http.ResponseWriter, r *http.Request) {
 
 	newJSONResponse(w, reqID)
 }
+
+// CancelRequest is handler which cancels the queued or processing request.
+// Method response with the id of cancelled request or error, if any occurs.
+// User id is getted from context.
+// Request id is getted from query.
+// Handler calls service method CancelRequest.
+func (rh *Request) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := jwt.GetUserFromContext(ctx)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	strReqID, ok := vars["reqID"]
+	if !ok {
+		rh.logger.Warn("id parameter is missing")
+		newErrorResponse(w, http.StatusBadRequest, "id parameter is missing")
+
+		return
+	}
+
+	reqID, err := strconv.Atoi(strReqID)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	err = rh.requestService.CancelRequest(ctx, userID, reqID)
+	if err != nil {
+		rh.logger.Warn(err)
+
+		var finishedErr *service.RequestFinishedError
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			newErrorResponse(w, http.StatusNotFound, "request not found")
+		case errors.As(err, &finishedErr):
+			newErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	newJSONResponse(w, reqID)
+}
+
+// GetTile is handler which response with the deep-zoom tile of the request.
+// User id is getted from context.
+// Request id, level, column and row of the tile are getted from query.
+// Handler calls service method GetTile.
+// Missing request or tile is responded with the not found status, request without tiles with the bad request.
+func (rh *Request) GetTile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, err := jwt.GetUserFromContext(ctx)
+	if err != nil {
+		rh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	vars := mux.Vars(r)
+	params := make(map[string]int, len(tileParams))
+
+	for _, name := range tileParams {
+		str, ok := vars[name]
+		if !ok {
+			rh.logger.Warnf("%s parameter is missing", name)
+			newErrorResponse(w, http.StatusBadRequest, name+" parameter is missing")
+
+			return
+		}
+
+		params[name], err = strconv.Atoi(str)
+		if err != nil || params[name] < 0 {
+			rh.logger.Warnf("%s parameter is invalid: %q", name, str)
+			newErrorResponse(w, http.StatusBadRequest, name+" parameter should be non-negative integer")
+
+			return
+		}
+	}
+
+	tile, filename, err := rh.requestService.GetTile(ctx, userID, params["reqID"],
+		params["level"], params["col"], params["row"])
+	if err != nil {
+		rh.logger.Warn(err)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			newErrorResponse(w, http.StatusNotFound, "request not found")
+		case errors.Is(err, service.ErrTileNotExist):
+			newErrorResponse(w, http.StatusNotFound, "tile not found")
+		case errors.Is(err, service.ErrNotTiled):
+			newErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	newFileResponse(w, tile, filename)
+}
+
+// Path parameters of the tile.
+var tileParams = []string{"reqID", "level", "col", "row"}
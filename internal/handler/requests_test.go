@@ -0,0 +1,240 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/handler"
+	"github.com/Dyleme/image-coverter/internal/handler/mocks"
+	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var errRequest = errors.New("error in request service")
+
+func TestRequest_GetTile(t *testing.T) {
+	vars := map[string]string{"reqID": "7", "level": "10", "col": "2", "row": "3"}
+
+	testCases := []struct {
+		testName   string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName:   "ok",
+			wantStatus: http.StatusOK,
+			wantBody:   "tile",
+		},
+		{
+			testName:   "request of another user",
+			serviceErr: fmt.Errorf("get tile: %w", sql.ErrNoRows),
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"request not found"}`,
+		},
+		{
+			testName:   "request without tiles",
+			serviceErr: fmt.Errorf("get tile: %w", service.ErrNotTiled),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"get tile: request has no tiles"}`,
+		},
+		{
+			testName:   "tile out of the pyramid",
+			serviceErr: fmt.Errorf("get tile: %w", service.ErrTileNotExist),
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"tile not found"}`,
+		},
+		{
+			testName:   "error in service",
+			serviceErr: errRequest,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in request service"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodGet, "/requests/7/tiles/10/2/3", &strings.Reader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reqMock := mocks.NewMockRequester(mockCtr)
+			reqHandler := handler.NewRequest(reqMock, &logrus.Logger{})
+
+			var tile []byte
+			if tc.serviceErr == nil {
+				tile = []byte("tile")
+			}
+
+			reqMock.EXPECT().GetTile(gomock.Any(), 2, 7, 10, 2, 3).Return(tile, "2_3.jpg", tc.serviceErr).Times(1)
+
+			req = mux.SetURLVars(req, vars)
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			reqHandler.GetTile(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestRequest_AddContactSheetRequest(t *testing.T) {
+	body := `{"imageIDs":[3,5],"type":"png","columns":2,"cellWidth":100,"cellHeight":80}`
+
+	testCases := []struct {
+		testName   string
+		body       string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName:   "ok",
+			body:       body,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"requestID":15}`,
+		},
+		{
+			testName:   "invalid option",
+			body:       body,
+			serviceErr: fmt.Errorf("add contact sheet: %w", &service.InvalidOptionError{}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"add contact sheet: invalid option \"\": "}`,
+		},
+		{
+			testName:   "unsupported type",
+			body:       body,
+			serviceErr: fmt.Errorf("add contact sheet: %w", service.UnsupportedTypeError{UnType: "gif"}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"add contact sheet: unsupported type: \"gif\""}`,
+		},
+		{
+			testName:   "image of another user",
+			body:       body,
+			serviceErr: fmt.Errorf("add contact sheet: %w", &service.NotOwnedImagesError{}),
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"add contact sheet: some of the images [] don't exist or belong to another user"}`,
+		},
+		{
+			testName:   "error in service",
+			body:       body,
+			serviceErr: errRequest,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in request service"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodPost, "/requests/contact-sheet", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reqMock := mocks.NewMockRequester(mockCtr)
+			reqHandler := handler.NewRequest(reqMock, &logrus.Logger{})
+
+			reqID := 15
+			if tc.serviceErr != nil {
+				reqID = 0
+			}
+
+			reqMock.EXPECT().AddContactSheetRequest(gomock.Any(), 2, gomock.Any()).Return(reqID, tc.serviceErr).Times(1)
+
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			reqHandler.AddContactSheetRequest(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestRequest_AddPDFRequest(t *testing.T) {
+	body := `{"imageIDs":[3,5],"pageSize":"a4"}`
+
+	testCases := []struct {
+		testName   string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName:   "ok",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"requestID":15}`,
+		},
+		{
+			testName:   "invalid option",
+			serviceErr: fmt.Errorf("add pdf: %w", &service.InvalidOptionError{}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"add pdf: invalid option \"\": "}`,
+		},
+		{
+			testName:   "image of another user",
+			serviceErr: fmt.Errorf("add pdf: %w", &service.NotOwnedImagesError{}),
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"add pdf: some of the images [] don't exist or belong to another user"}`,
+		},
+		{
+			testName:   "error in service",
+			serviceErr: errRequest,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in request service"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodPost, "/requests/pdf", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reqMock := mocks.NewMockRequester(mockCtr)
+			reqHandler := handler.NewRequest(reqMock, &logrus.Logger{})
+
+			reqID := 15
+			if tc.serviceErr != nil {
+				reqID = 0
+			}
+
+			reqMock.EXPECT().AddPDFRequest(gomock.Any(), 2, gomock.Any()).Return(reqID, tc.serviceErr).Times(1)
+
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			reqHandler.AddPDFRequest(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
@@ -3,7 +3,9 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"path"
 	"strconv"
 )
 
@@ -41,9 +43,22 @@ func newJSONResponse(w http.ResponseWriter, v interface{}) {
 }
 
 // newDownloadFileResponse response with bytes of files as attachment to the response.
+// Content type is taken from the file extension, or detected from the bytes if extension is unknown.
 func newDownloadFileResponse(w http.ResponseWriter, b []byte, filename string) {
 	w.Header().Add("Content-Disposition", "Attachment")
 	w.Header().Add("Content-Disposition", `filename="`+filename+`"`)
+	newFileResponse(w, b, filename)
+}
+
+// newFileResponse response with bytes of the file, which could be shown inline.
+// Content type is taken from the file extension, or detected from the bytes if extension is unknown.
+func newFileResponse(w http.ResponseWriter, b []byte, filename string) {
+	contentType := mime.TypeByExtension(path.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(b)
+	}
+
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Add("Content-Length", strconv.Itoa(len(b)))
 	fmt.Fprint(w, string(b))
 }
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+// PublicPrefix is the prefix of the routes, which are accessed with the signed urls.
+const PublicPrefix = "/public"
+
+const defaultSignedTTL = time.Hour
+
+// maxTTLSeconds is the biggest life time in seconds, which doesn't overflow time.Duration.
+const maxTTLSeconds = math.MaxInt64 / int64(time.Second)
+
+// Paths which could be signed, they are served under the PublicPrefix.
+var signablePrefixes = []string{"/download/image/", "/render/", "/iiif/"}
+
+// URLSigner is an interface which has method to sign urls.
+type URLSigner interface {
+	Sign(path string, query url.Values, userID int, ttl time.Duration, now time.Time) (url.Values, time.Time, error)
+}
+
+// Struct which provides method to make signed urls.
+type Signing struct {
+	logger *logrus.Logger
+	signer URLSigner
+}
+
+// Constructor for Signing handler.
+func NewSigning(signer URLSigner, logger *logrus.Logger) *Signing {
+	return &Signing{signer: signer, logger: logger}
+}
+
+// SignURL is handler which response with the url signed on behalf of the user.
+// User id is getted from context.
+// Path and life time of the url are took from the json body.
+// Signed url could be used without authorization until it expires.
+func (sh *Signing) SignURL(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		sh.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	var info model.SignURLInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		sh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	u, err := url.Parse(info.Path)
+	if err != nil || !isSignable(u) {
+		sh.logger.Warnf("path can't be signed: %q", info.Path)
+		newErrorResponse(w, http.StatusBadRequest, "path can't be signed")
+
+		return
+	}
+
+	if int64(info.TTL) > maxTTLSeconds || int64(info.TTL) < -maxTTLSeconds {
+		sh.logger.Warnf("ttl is out of range: %v", info.TTL)
+		newErrorResponse(w, http.StatusBadRequest, "ttl is out of range")
+
+		return
+	}
+
+	ttl := defaultSignedTTL
+	if info.TTL != 0 {
+		ttl = time.Duration(info.TTL) * time.Second
+	}
+
+	query, expires, err := sh.signer.Sign(u.Path, u.Query(), userID, ttl, time.Now())
+	if err != nil {
+		sh.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	newJSONResponse(w, model.SignedURL{
+		URL:     PublicPrefix + u.Path + "?" + query.Encode(),
+		Expires: expires,
+	})
+}
+
+// isSignable function reports whether the url is a relative path to the route, which could be signed.
+func isSignable(u *url.URL) bool {
+	if u.IsAbs() || u.Host != "" || path.Clean(u.Path) != u.Path {
+		return false
+	}
+
+	for _, prefix := range signablePrefixes {
+		if strings.HasPrefix(u.Path, prefix) && len(u.Path) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
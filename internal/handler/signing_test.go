@@ -0,0 +1,132 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/handler"
+	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/signing"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigning_SignURL(t *testing.T) {
+	signer := signing.NewSigner(&signing.Config{Key: "key", MaxTTL: 24 * time.Hour})
+
+	testCases := []struct {
+		testName   string
+		body       string
+		withUser   bool
+		wantStatus int
+		wantPath   string
+		wantTTL    time.Duration
+		wantBody   string
+	}{
+		{
+			testName:   "ok",
+			body:       `{"path":"/render/12?w=300&fit=fill","ttl":600}`,
+			withUser:   true,
+			wantStatus: http.StatusOK,
+			wantPath:   "/public/render/12",
+			wantTTL:    10 * time.Minute,
+		},
+		{
+			testName:   "default ttl",
+			body:       `{"path":"/download/image/12"}`,
+			withUser:   true,
+			wantStatus: http.StatusOK,
+			wantPath:   "/public/download/image/12",
+			wantTTL:    time.Hour,
+		},
+		{
+			testName:   "no auth",
+			body:       `{"path":"/render/12"}`,
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"message":"can't get user from context"}`,
+		},
+		{
+			testName:   "path can't be signed",
+			body:       `{"path":"/requests/12"}`,
+			withUser:   true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"path can't be signed"}`,
+		},
+		{
+			testName:   "absolute url",
+			body:       `{"path":"http://example.com/render/12"}`,
+			withUser:   true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"path can't be signed"}`,
+		},
+		{
+			testName:   "path is not clean",
+			body:       `{"path":"/render/../requests/12"}`,
+			withUser:   true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"path can't be signed"}`,
+		},
+		{
+			testName:   "too long ttl",
+			body:       `{"path":"/render/12","ttl":172800}`,
+			withUser:   true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"ttl 48h0m0s should be positive and not greater than 24h0m0s"}`,
+		},
+		{
+			testName:   "ttl overflows duration",
+			body:       `{"path":"/render/12","ttl":9223372036854775}`,
+			withUser:   true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"ttl is out of range"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/sign", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.withUser {
+				req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+			}
+
+			signHandler := handler.NewSigning(signer, &logrus.Logger{})
+
+			rr := httptest.NewRecorder()
+			begin := time.Now().Truncate(time.Second)
+
+			signHandler.SignURL(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+
+			if rr.Code != http.StatusOK {
+				assert.Equal(t, tc.wantBody, rr.Body.String())
+
+				return
+			}
+
+			var signed model.SignedURL
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &signed))
+
+			u, err := url.Parse(signed.URL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPath, u.Path)
+
+			userID, err := signer.Verify(strings.TrimPrefix(u.Path, handler.PublicPrefix), u.Query(), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, 2, userID)
+			assert.WithinDuration(t, begin.Add(tc.wantTTL), signed.Expires, time.Second)
+		})
+	}
+}
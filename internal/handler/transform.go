@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Transformer is an interface which has methods to make derived images synchronously.
+type Transformer interface {
+	IIIFImage(ctx context.Context, userID, imageID int, params model.IIIFParams) ([]byte, string, error)
+	IIIFInfo(ctx context.Context, userID, imageID int, id string) (*model.IIIFInfo, error)
+	Render(ctx context.Context, userID, imageID int, params model.RenderParams) ([]byte, string, error)
+	Diff(ctx context.Context, userID int, info model.DiffInfo) (*model.DiffResult, error)
+}
+
+// Struct which provides methods to handle synchronous transformations.
+type Transform struct {
+	logger           *logrus.Logger
+	transformService Transformer
+	publicURL        string
+}
+
+// Constructor for Transform handler.
+// The publicURL is the scheme and host the service is reachable with, e.g. https://images.example.com.
+// If it is empty, the scheme and host are taken from the request.
+func NewTransform(tr Transformer, publicURL string, logger *logrus.Logger) *Transform {
+	return &Transform{transformService: tr, publicURL: strings.TrimSuffix(publicURL, "/"), logger: logger}
+}
+
+// imageID function returns the user id from the context and the image id from the query.
+// If any of them is missing, it responses with error and returns false.
+func (th *Transform) imageID(w http.ResponseWriter, r *http.Request) (userID, imageID int, ok bool) {
+	userID, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		th.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return 0, 0, false
+	}
+
+	strImageID, ok := mux.Vars(r)["id"]
+	if !ok {
+		th.logger.Warn("id parameter is missing")
+		newErrorResponse(w, http.StatusBadRequest, `parameter "id" is missing`)
+
+		return 0, 0, false
+	}
+
+	imageID, err = strconv.Atoi(strImageID)
+	if err != nil {
+		th.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return 0, 0, false
+	}
+
+	return userID, imageID, true
+}
+
+// IIIFImage is handler which response with the image made with IIIF Image API parameters.
+// User id is getted from context.
+// Image id and IIIF parameters are getted from query.
+// Handler calls service method IIIFImage.
+// Invalid parameters are responded with the bad request status.
+func (th *Transform) IIIFImage(w http.ResponseWriter, r *http.Request) {
+	userID, imageID, ok := th.imageID(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	params := model.IIIFParams{
+		Region:   vars["region"],
+		Size:     vars["size"],
+		Rotation: vars["rotation"],
+		Quality:  vars["quality"],
+		Format:   vars["format"],
+	}
+
+	b, filename, err := th.transformService.IIIFImage(r.Context(), userID, imageID, params)
+	if err != nil {
+		th.logger.Warn(err)
+
+		var paramErr *conversion.IIIFParamError
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			newErrorResponse(w, http.StatusNotFound, "image not found")
+		case errors.As(err, &paramErr):
+			newErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	newFileResponse(w, b, filename)
+}
+
+// IIIFInfo is handler which response with the IIIF image information.
+// User id is getted from context.
+// Image id is getted from query.
+// Handler calls service method IIIFInfo.
+func (th *Transform) IIIFInfo(w http.ResponseWriter, r *http.Request) {
+	userID, imageID, ok := th.imageID(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := th.transformService.IIIFInfo(r.Context(), userID, imageID, th.iiifBaseURI(r, imageID))
+	if err != nil {
+		th.logger.Warn(err)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			newErrorResponse(w, http.StatusNotFound, "image not found")
+		} else {
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	newJSONResponse(w, info)
+}
+
+// iiifBaseURI function returns the base URI of the image service, which is used as the id of the image.
+// The configured public url is used if it is set, otherwise the url is built from the request.
+// Image requested with the signed url keeps the PublicPrefix.
+func (th *Transform) iiifBaseURI(r *http.Request, imageID int) string {
+	base := th.publicURL
+	if base == "" {
+		base = requestBaseURL(r)
+	}
+
+	prefix := ""
+	if strings.HasPrefix(r.URL.Path, PublicPrefix+"/") {
+		prefix = PublicPrefix
+	}
+
+	return base + prefix + "/iiif/" + strconv.Itoa(imageID)
+}
+
+// requestBaseURL function returns the scheme and host the request was made with.
+// Only http and https are accepted from the X-Forwarded-Proto header.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	switch proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto {
+	case "http", "https":
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host
+}
+
+// Render is handler which response with the image rendered on the fly.
+// User id is getted from context.
+// Image id is getted from path, box, fit, format and quality are getted from the query parameters
+// w, h, fit, fmt and q.
+// Handler calls service method Render.
+// Invalid parameters are responded with the bad request status.
+func (th *Transform) Render(w http.ResponseWriter, r *http.Request) {
+	userID, imageID, ok := th.imageID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	params := model.RenderParams{
+		Fit:    query.Get("fit"),
+		Anchor: query.Get("anchor"),
+		Format: query.Get("fmt"),
+	}
+
+	ints := map[string]*int{"w": &params.Width, "h": &params.Height, "q": &params.Quality}
+
+	for name, value := range ints {
+		str := query.Get(name)
+		if str == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(str)
+		if err != nil {
+			th.logger.Warnf("%s parameter is invalid: %q", name, str)
+			newErrorResponse(w, http.StatusBadRequest, name+" parameter should be integer")
+
+			return
+		}
+
+		*value = v
+	}
+
+	b, filename, err := th.transformService.Render(r.Context(), userID, imageID, params)
+	if err != nil {
+		th.logger.Warn(err)
+
+		var optionErr *service.InvalidOptionError
+		if errors.As(err, &optionErr) {
+			newErrorResponse(w, http.StatusBadRequest, err.Error())
+		} else {
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	newFileResponse(w, b, filename)
+}
+
+// Diff is handler which response with the comparison of two user's images.
+// User id is getted from context.
+// Image ids and the policy of the size mismatch are took from the json body.
+// Handler calls service method Diff.
+// Images with different sizes, which couldn't be compared with the policy, and invalid threshold
+// are responded with the bad request status.
+func (th *Transform) Diff(w http.ResponseWriter, r *http.Request) {
+	userID, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		th.logger.Warn(err)
+		newErrorResponse(w, http.StatusUnauthorized, err.Error())
+
+		return
+	}
+
+	var info model.DiffInfo
+
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		th.logger.Warn(err)
+		newErrorResponse(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	res, err := th.transformService.Diff(r.Context(), userID, info)
+	if err != nil {
+		th.logger.Warn(err)
+
+		var (
+			mismatchErr *conversion.SizeMismatchError
+			policyErr   *conversion.UnknownDiffPolicyError
+			optionErr   *service.InvalidOptionError
+		)
+
+		if errors.As(err, &mismatchErr) || errors.As(err, &policyErr) || errors.As(err, &optionErr) {
+			newErrorResponse(w, http.StatusBadRequest, err.Error())
+		} else {
+			newErrorResponse(w, http.StatusInternalServerError, err.Error())
+		}
+
+		return
+	}
+
+	newJSONResponse(w, res)
+}
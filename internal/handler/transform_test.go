@@ -0,0 +1,414 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/handler"
+	"github.com/Dyleme/image-coverter/internal/handler/mocks"
+	"github.com/Dyleme/image-coverter/internal/jwt"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransform = errors.New("error in transformation")
+
+func TestTransform_IIIFImage(t *testing.T) {
+	vars := map[string]string{
+		"id":       "12",
+		"region":   "full",
+		"size":     "max",
+		"rotation": "0",
+		"quality":  "default",
+		"format":   "png",
+	}
+	params := model.IIIFParams{Region: "full", Size: "max", Rotation: "0", Quality: "default", Format: "png"}
+
+	testCases := []struct {
+		testName   string
+		configure  func(*http.Request, *mocks.MockTransformer) *http.Request
+		wantStatus int
+		wantBody   string
+		wantType   string
+	}{
+		{
+			testName: "ok",
+			configure: func(r *http.Request, mt *mocks.MockTransformer) *http.Request {
+				mt.EXPECT().IIIFImage(gomock.Any(), 2, 12, params).Return([]byte("body"), "default.png", nil).Times(1)
+
+				r = mux.SetURLVars(r, vars)
+				ctx := context.WithValue(r.Context(), jwt.KeyUserID, 2)
+
+				return r.WithContext(ctx)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "body",
+			wantType:   "image/png",
+		},
+		{
+			testName: "no auth",
+			configure: func(r *http.Request, mt *mocks.MockTransformer) *http.Request {
+				return mux.SetURLVars(r, vars)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"message":"can't get user from context"}`,
+		},
+		{
+			testName: "invalid parameter",
+			configure: func(r *http.Request, mt *mocks.MockTransformer) *http.Request {
+				mt.EXPECT().IIIFImage(gomock.Any(), 2, 12, params).
+					Return(nil, "", fmt.Errorf("iiif: %w", &conversion.IIIFParamError{Param: "size", Value: "0,"})).Times(1)
+
+				r = mux.SetURLVars(r, vars)
+				ctx := context.WithValue(r.Context(), jwt.KeyUserID, 2)
+
+				return r.WithContext(ctx)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"iiif: invalid iiif size: \"0,\""}`,
+		},
+		{
+			testName: "image not found",
+			configure: func(r *http.Request, mt *mocks.MockTransformer) *http.Request {
+				mt.EXPECT().IIIFImage(gomock.Any(), 2, 12, params).
+					Return(nil, "", fmt.Errorf("iiif: %w", sql.ErrNoRows)).Times(1)
+
+				r = mux.SetURLVars(r, vars)
+				ctx := context.WithValue(r.Context(), jwt.KeyUserID, 2)
+
+				return r.WithContext(ctx)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"message":"image not found"}`,
+		},
+		{
+			testName: "error in transformation",
+			configure: func(r *http.Request, mt *mocks.MockTransformer) *http.Request {
+				mt.EXPECT().IIIFImage(gomock.Any(), 2, 12, params).Return(nil, "", errTransform).Times(1)
+
+				r = mux.SetURLVars(r, vars)
+				ctx := context.WithValue(r.Context(), jwt.KeyUserID, 2)
+
+				return r.WithContext(ctx)
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in transformation"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodGet, "/iiif/12/full/max/0/default.png", &strings.Reader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transMock := mocks.NewMockTransformer(mockCtr)
+			transHandler := handler.NewTransform(transMock, "", &logrus.Logger{})
+
+			req = tc.configure(req, transMock)
+
+			rr := httptest.NewRecorder()
+
+			transHandler.IIIFImage(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			if rr.Code == http.StatusOK {
+				assert.Equal(t, tc.wantType, rr.Header().Get("Content-Type"))
+			}
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestTransform_IIIFInfo(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		path       string
+		publicURL  string
+		proto      string
+		wantID     string
+		serviceErr error
+		wantStatus int
+	}{
+		{
+			testName:   "authorized",
+			path:       "/iiif/12/info.json",
+			wantID:     "http://host/iiif/12",
+			wantStatus: http.StatusOK,
+		},
+		{
+			testName:   "signed",
+			path:       "/public/iiif/12/info.json",
+			wantID:     "http://host/public/iiif/12",
+			wantStatus: http.StatusOK,
+		},
+		{
+			testName:   "forwarded https",
+			path:       "/iiif/12/info.json",
+			proto:      "https",
+			wantID:     "https://host/iiif/12",
+			wantStatus: http.StatusOK,
+		},
+		{
+			testName:   "forged forwarded proto",
+			path:       "/iiif/12/info.json",
+			proto:      "javascript",
+			wantID:     "http://host/iiif/12",
+			wantStatus: http.StatusOK,
+		},
+		{
+			testName:   "configured public url",
+			path:       "/public/iiif/12/info.json",
+			publicURL:  "https://images.example.com/",
+			proto:      "http",
+			wantID:     "https://images.example.com/public/iiif/12",
+			wantStatus: http.StatusOK,
+		},
+		{
+			testName:   "image not found",
+			path:       "/iiif/12/info.json",
+			wantID:     "http://host/iiif/12",
+			serviceErr: fmt.Errorf("iiif info: %w", sql.ErrNoRows),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			testName:   "error in transformation",
+			path:       "/iiif/12/info.json",
+			wantID:     "http://host/iiif/12",
+			serviceErr: errTransform,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodGet, "http://host"+tc.path, &strings.Reader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+
+			transMock := mocks.NewMockTransformer(mockCtr)
+			transHandler := handler.NewTransform(transMock, tc.publicURL, &logrus.Logger{})
+
+			var info *model.IIIFInfo
+			if tc.serviceErr == nil {
+				info = &model.IIIFInfo{ID: tc.wantID}
+			}
+
+			transMock.EXPECT().IIIFInfo(gomock.Any(), 2, 12, tc.wantID).Return(info, tc.serviceErr).Times(1)
+
+			req = mux.SetURLVars(req, map[string]string{"id": "12"})
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			transHandler.IIIFInfo(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestTransform_Render(t *testing.T) {
+	testCases := []struct {
+		testName   string
+		query      string
+		configure  func(*mocks.MockTransformer)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName: "ok",
+			query:    "w=300&h=200&fit=fill&fmt=jpeg&q=80",
+			configure: func(mt *mocks.MockTransformer) {
+				params := model.RenderParams{Width: 300, Height: 200, Fit: "fill", Format: "jpeg", Quality: 80}
+				mt.EXPECT().Render(gomock.Any(), 2, 12, params).Return([]byte("body"), "300x200_fill_q80.jpg", nil).Times(1)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "body",
+		},
+		{
+			testName: "anchor",
+			query:    "w=300&h=200&fit=crop&anchor=smart",
+			configure: func(mt *mocks.MockTransformer) {
+				params := model.RenderParams{Width: 300, Height: 200, Fit: "crop", Anchor: "smart"}
+				mt.EXPECT().Render(gomock.Any(), 2, 12, params).Return([]byte("body"), "300x200_crop-smart_q0.png", nil).Times(1)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "body",
+		},
+		{
+			testName: "only width",
+			query:    "w=300",
+			configure: func(mt *mocks.MockTransformer) {
+				params := model.RenderParams{Width: 300}
+				mt.EXPECT().Render(gomock.Any(), 2, 12, params).Return([]byte("body"), "300x0_contain_q0.png", nil).Times(1)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "body",
+		},
+		{
+			testName:   "width is not int",
+			query:      "w=big",
+			configure:  func(mt *mocks.MockTransformer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"w parameter should be integer"}`,
+		},
+		{
+			testName: "invalid option",
+			query:    "w=300&fit=fill",
+			configure: func(mt *mocks.MockTransformer) {
+				mt.EXPECT().Render(gomock.Any(), 2, 12, gomock.Any()).
+					Return(nil, "", fmt.Errorf("render: %w", &service.InvalidOptionError{})).Times(1)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"render: invalid option \"\": "}`,
+		},
+		{
+			testName: "error in rendering",
+			query:    "w=300",
+			configure: func(mt *mocks.MockTransformer) {
+				mt.EXPECT().Render(gomock.Any(), 2, 12, gomock.Any()).Return(nil, "", errTransform).Times(1)
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in transformation"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodGet, "/render/12?"+tc.query, &strings.Reader{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transMock := mocks.NewMockTransformer(mockCtr)
+			transHandler := handler.NewTransform(transMock, "", &logrus.Logger{})
+
+			tc.configure(transMock)
+
+			req = mux.SetURLVars(req, map[string]string{"id": "12"})
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			transHandler.Render(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func TestTransform_Diff(t *testing.T) {
+	info := model.DiffInfo{FirstID: 1, SecondID: 2, Policy: "error"}
+
+	testCases := []struct {
+		testName   string
+		body       string
+		configure  func(*mocks.MockTransformer)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			testName: "ok",
+			body:     `{"firstID":1,"secondID":2,"policy":"error"}`,
+			configure: func(mt *mocks.MockTransformer) {
+				mt.EXPECT().Diff(gomock.Any(), 2, info).
+					Return(&model.DiffResult{Width: 4, Height: 2, ChangedPixels: 2, ChangedPercent: 25, MaxDelta: 3,
+						Image: "data:image/png;base64,"}, nil).Times(1)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"width":4,"height":2,"changedPixels":2,"changedPercent":25,"maxDelta":3,` +
+				`"image":"data:image/png;base64,"}`,
+		},
+		{
+			testName: "size mismatch",
+			body:     `{"firstID":1,"secondID":2,"policy":"error"}`,
+			configure: func(mt *mocks.MockTransformer) {
+				err := &conversion.SizeMismatchError{First: image.Pt(4, 2), Second: image.Pt(2, 2)}
+				mt.EXPECT().Diff(gomock.Any(), 2, info).Return(nil, fmt.Errorf("diff: %w", err)).Times(1)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"diff: images have different sizes 4x2 and 2x2"}`,
+		},
+		{
+			testName: "invalid threshold",
+			body:     `{"firstID":1,"secondID":2,"policy":"error"}`,
+			configure: func(mt *mocks.MockTransformer) {
+				mt.EXPECT().Diff(gomock.Any(), 2, info).
+					Return(nil, fmt.Errorf("diff: %w", &service.InvalidOptionError{})).Times(1)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"diff: invalid option \"\": "}`,
+		},
+		{
+			testName:   "invalid body",
+			body:       `{"firstID":"1"}`,
+			configure:  func(mt *mocks.MockTransformer) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"message":"json: cannot unmarshal string into Go struct field DiffInfo.firstID of type int"}`,
+		},
+		{
+			testName: "error in comparison",
+			body:     `{"firstID":1,"secondID":2,"policy":"error"}`,
+			configure: func(mt *mocks.MockTransformer) {
+				mt.EXPECT().Diff(gomock.Any(), 2, info).Return(nil, errTransform).Times(1)
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"message":"error in transformation"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+
+			req, err := http.NewRequest(http.MethodPost, "/diff", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transMock := mocks.NewMockTransformer(mockCtr)
+			transHandler := handler.NewTransform(transMock, "", &logrus.Logger{})
+
+			tc.configure(transMock)
+
+			req = req.WithContext(context.WithValue(req.Context(), jwt.KeyUserID, 2))
+
+			rr := httptest.NewRecorder()
+
+			transHandler.Diff(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantBody, rr.Body.String())
+		})
+	}
+}
@@ -51,3 +51,9 @@ func FromContext(ctx context.Context) *logrus.Logger {
 
 	return DefaultLogger()
 }
+
+// Detach function returns the context with the logger of the provided context,
+// which is not cancelled, when the provided context is done.
+func Detach(ctx context.Context) context.Context {
+	return WithLogger(context.Background(), FromContext(ctx))
+}
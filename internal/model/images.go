@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Information about image conversion.
 type ConversionInfo struct {
 	// Ration with which you will convert image.
@@ -7,25 +9,256 @@ type ConversionInfo struct {
 
 	// Type to which you will convert image.
 	Type string `json:"newType"`
+
+	// Priority is "interactive" or "bulk", interactive requests are processed first.
+	// It's interactive by default.
+	Priority string `json:"priority,omitempty"`
+
+	ConversionOptions
+}
+
+// Optional settings of the image conversion.
+// They are stored with the request and passed to the converter.
+type ConversionOptions struct {
+	// Placeholder is used to generate BlurHash and tiny preview of the processed image.
+	Placeholder bool `json:"placeholder,omitempty"`
+
+	// Fit is the way how image is placed in the box with Width and Height.
+	// It could be "fill" to resize and crop the image or "crop" to crop it without resizing.
+	Fit    string `json:"fit,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+
+	// Anchor is the position of the crop window, "smart" chooses the most interesting part.
+	Anchor string `json:"anchor,omitempty"`
+
+	// LinearLight makes the resizing with the ratio in linear light instead of sRGB,
+	// which keeps the brightness of fine high-contrast details.
+	LinearLight bool `json:"linearLight,omitempty"`
+
+	// DPI is used to rasterize svg images, if Width and Height are not provided.
+	DPI float64 `json:"dpi,omitempty"`
+
+	// ImageIDs are user's images, which are used by the requests made from several images.
+	ImageIDs []int `json:"imageIDs,omitempty"`
+
+	// Sheet is the layout of the contact sheet.
+	Sheet *SheetLayout `json:"sheet,omitempty"`
+
+	// PDF is the layout of the pdf document pages.
+	PDF *PDFLayout `json:"pdf,omitempty"`
+
+	// Tiles is the layout of the deep-zoom tile pyramid.
+	Tiles *TileOptions `json:"tiles,omitempty"`
+
+	// ChromaKey is the colour, which is made transparent. It could be used only with png type.
+	ChromaKey *ChromaKeyOptions `json:"chromaKey,omitempty"`
+}
+
+// Colour which is made transparent.
+type ChromaKeyOptions struct {
+	// Color in #rrggbb format, alpha isn't accepted.
+	Color string `json:"color"`
+
+	// Tolerance is the distance to the colour in percents, closer pixels become fully transparent.
+	Tolerance float64 `json:"tolerance,omitempty"`
+
+	// Feather is the width of the distance band in percents, where transparency fades out.
+	// Band is measured by the colour distance, not by the distance to the keyed pixels.
+	Feather float64 `json:"feather,omitempty"`
+}
+
+// Layout of the deep-zoom tile pyramid.
+type TileOptions struct {
+	// TileSize is the size of the tile side, 254 by default.
+	TileSize int `json:"tileSize,omitempty"`
+
+	// Overlap is the amount of pixels shared by the neighbour tiles.
+	Overlap int `json:"overlap,omitempty"`
+
+	// Format of the tiles, jpeg or png, jpeg by default.
+	Format string `json:"format,omitempty"`
+}
+
+// Information about pdf document, which is made from the user's images.
+type PDFInfo struct {
+	// Images which are placed to the pages in the provided order, one image per page.
+	ImageIDs []int `json:"imageIDs"`
+
+	// Priority is "interactive" or "bulk", interactive by default.
+	Priority string `json:"priority,omitempty"`
+
+	PDFLayout
+}
+
+// Layout of the pdf document pages.
+type PDFLayout struct {
+	// PageSize is one of a3, a4, a5, letter and legal, a4 by default.
+	PageSize  string `json:"pageSize,omitempty"`
+	Landscape bool   `json:"landscape,omitempty"`
+
+	// Margin of the page in points.
+	Margin float64 `json:"margin,omitempty"`
+
+	// Fit is one of contain, fill and stretch, contain by default.
+	Fit string `json:"fit,omitempty"`
+}
+
+// Information about contact sheet, which is made from the user's images.
+type ContactSheetInfo struct {
+	// Images which are placed to the sheet in the provided order.
+	ImageIDs []int `json:"imageIDs"`
+
+	// Type of the result image.
+	Type string `json:"newType"`
+
+	// Priority is "interactive" or "bulk", interactive by default.
+	Priority string `json:"priority,omitempty"`
+
+	SheetLayout
+}
+
+// Layout of the contact sheet.
+type SheetLayout struct {
+	Columns    int `json:"columns"`
+	CellWidth  int `json:"cellWidth"`
+	CellHeight int `json:"cellHeight"`
+	Spacing    int `json:"spacing,omitempty"`
+
+	// Background color in #rrggbb or #rrggbbaa format, white by default.
+	Background string `json:"background,omitempty"`
+
+	// Captions are placed under the images with the same index.
+	Captions []string `json:"captions,omitempty"`
+}
+
+// Information about the crop which was made while conversion.
+type CropResult struct {
+	// Anchor which was used to crop the image.
+	Anchor string `json:"anchor"`
+
+	// Position and size of the cut window.
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
 
 // Information about image.
 type ReuquestImageInfo struct {
-	Type string
-	URL  string
+	Type     string
+	URL      string
+	BlurHash string
+	Preview  string
+	Width    int
+	Height   int
+}
+
+// Additional image which is produced by the request besides the processed one.
+type Artifact struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	URL    string `json:"-"`
 }
 
 type ConvImageInfo struct {
+	Kind    string
 	UserID  int
 	OldImID int
 	OldURL  string
 	OldType string
 	NewType string
 	Ratio   float32
+	Options ConversionOptions
 }
 
-// RequestToProcess is struct, which contains request id and name of converted image.
+// RequestToProcess is struct, which contains request id, name of converted image and priority of the request.
 type RequestToProcess struct {
 	ReqID    int    `json:"reqID"`
 	FileName string `json:"fileName"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// Parameters of the IIIF Image API request.
+type IIIFParams struct {
+	Region   string
+	Size     string
+	Rotation string
+	Quality  string
+	Format   string
+}
+
+// Image information document of the IIIF Image API.
+type IIIFInfo struct {
+	Context        string   `json:"@context"`
+	ID             string   `json:"id"`
+	Type           string   `json:"type"`
+	Protocol       string   `json:"protocol"`
+	Profile        string   `json:"profile"`
+	Width          int      `json:"width"`
+	Height         int      `json:"height"`
+	ExtraQualities []string `json:"extraQualities"`
+	ExtraFormats   []string `json:"extraFormats,omitempty"`
+	ExtraFeatures  []string `json:"extraFeatures"`
+}
+
+// Parameters of the image rendered on the fly.
+type RenderParams struct {
+	// Width and Height of the box, one of them could be zero to keep aspect ratio.
+	Width  int
+	Height int
+
+	// Fit is one of contain, fill, crop and stretch, contain by default.
+	Fit string
+
+	// Anchor is the position of the window for fill and crop, center by default.
+	Anchor string
+
+	// Format is jpeg or png, by default it's the type of the original image.
+	Format string
+
+	// Quality of the jpeg image from 1 to 100.
+	Quality int
+}
+
+// Information about the url which should be signed.
+type SignURLInfo struct {
+	// Path of the image or its transformation with the query, for example "/render/12?w=300".
+	Path string `json:"path"`
+
+	// TTL is the life time of the signed url in seconds, one hour by default.
+	TTL int `json:"ttl,omitempty"`
+}
+
+// Signed url, which could be used without authorization.
+type SignedURL struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// Information about two user's images, which should be compared.
+type DiffInfo struct {
+	FirstID  int `json:"firstID"`
+	SecondID int `json:"secondID"`
+
+	// Policy is used if the images have different sizes, it's one of resize, pad and error, error by default.
+	Policy string `json:"policy,omitempty"`
+
+	// Threshold is the maximum channel difference from 0 to 255, which is not treated as a change.
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// Result of the images comparison.
+type DiffResult struct {
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	ChangedPixels  int     `json:"changedPixels"`
+	ChangedPercent float64 `json:"changedPercent"`
+	MaxDelta       int     `json:"maxDelta"`
+
+	// Image with the changed pixels highlighted, encoded as base64 png data url.
+	Image string `json:"image"`
 }
@@ -6,13 +6,50 @@ import (
 
 // Sruct to put it in requests database.
 type Request struct {
-	ID             int       `json:"id"`
-	OpStatus       string    `json:"status"`
-	RequestTime    time.Time `json:"requestTime"`
-	CompletionTime time.Time `json:"completionTime,omitempty"`
-	OriginalID     int       `json:"originalID"`
-	ProcessedID    int       `json:"processedID"`
-	Ratio          float32   `json:"ratio"`
-	OriginalType   string    `json:"originalType"`
-	ProcessedType  string    `json:"processedType"`
+	ID             int               `json:"id"`
+	Kind           string            `json:"kind"`
+	OpStatus       string            `json:"status"`
+	Priority       string            `json:"priority"`
+	RequestTime    time.Time         `json:"requestTime"`
+	CompletionTime time.Time         `json:"completionTime,omitempty"`
+	StartTime      time.Time         `json:"startTime,omitempty"`
+	WorkerID       string            `json:"workerID,omitempty"`
+	Attempts       int               `json:"attempts"`
+	OriginalID     int               `json:"originalID"`
+	SourceID       int               `json:"sourceID,omitempty"`
+	ProcessedID    int               `json:"processedID"`
+	Ratio          float32           `json:"ratio"`
+	OriginalType   string            `json:"originalType"`
+	ProcessedType  string            `json:"processedType"`
+	Options        ConversionOptions `json:"options"`
+	BlurHash       string            `json:"blurHash,omitempty"`
+	Preview        string            `json:"preview,omitempty"`
+	Crop           *CropResult       `json:"crop,omitempty"`
+	ErrorCode      string            `json:"errorCode,omitempty"`
+	ErrorMessage   string            `json:"errorMessage,omitempty"`
+	Artifacts      []Artifact        `json:"artifacts,omitempty"`
+	Manifest       *WebManifest      `json:"manifest,omitempty"`
+}
+
+// Snippet of the web app manifest, which lists the icons of the favicon bundle.
+type WebManifest struct {
+	Icons []ManifestIcon `json:"icons"`
+}
+
+type ManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// ReapResult is a result of the reaping of the requests, which heartbeats are expired.
+type ReapResult struct {
+	// Leader is false if the other reaper holds the lock, nothing is reaped then.
+	Leader bool
+
+	// Requeued are the ids of the requests queued again.
+	Requeued []int
+
+	// Failed are the ids of the requests, which are out of attempts.
+	Failed []int
 }
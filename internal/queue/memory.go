@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is the in-process queue. It's used to run the API and the converter
+// in the single process without any broker, so messages are lost on exit.
+type Memory struct {
+	mu      sync.Mutex
+	pending []*memoryMessage
+	dead    []*memoryMessage
+	notify  chan struct{}
+}
+
+type memoryMessage struct {
+	body     []byte
+	priority uint8
+	attempt  int
+	err      string
+}
+
+// NewMemory is a constructor to the Memory queue.
+func NewMemory() *Memory {
+	return &Memory{notify: make(chan struct{}, 1)}
+}
+
+// Publish method adds the message to the end of the queue.
+// Messages are consumed in the order of priority, messages with the same priority in the order of publishing.
+func (q *Memory) Publish(ctx context.Context, body []byte, priority uint8) error {
+	q.push(&memoryMessage{body: append([]byte(nil), body...), priority: priority})
+
+	return nil
+}
+
+// Consume method returns channel of the deliveries, which is closed after the context is done.
+// Messages are delivered one by one, so prefetch is not used.
+func (q *Memory) Consume(ctx context.Context, prefetch int) (<-chan Delivery, error) {
+	out := make(chan Delivery)
+
+	go func() {
+		defer close(out)
+
+		for {
+			m := q.pop()
+			if m == nil {
+				select {
+				case <-q.notify:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case out <- &memoryDelivery{q: q, m: m}:
+			case <-ctx.Done():
+				q.pushFront(m)
+
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// DeadLetters method returns bodies of the rejected messages.
+func (q *Memory) DeadLetters() [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	bodies := make([][]byte, 0, len(q.dead))
+	for _, m := range q.dead {
+		bodies = append(bodies, m.body)
+	}
+
+	return bodies
+}
+
+func (q *Memory) push(m *memoryMessage) {
+	q.mu.Lock()
+	q.pending = append(q.pending, m)
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+func (q *Memory) pushFront(m *memoryMessage) {
+	q.mu.Lock()
+	q.pending = append([]*memoryMessage{m}, q.pending...)
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+func (q *Memory) pop() *memoryMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return nil
+	}
+
+	first := 0
+
+	for i, m := range q.pending {
+		if m.priority > q.pending[first].priority {
+			first = i
+		}
+	}
+
+	m := q.pending[first]
+	q.pending = append(q.pending[:first], q.pending[first+1:]...)
+
+	return m
+}
+
+// signal method wakes up the consumer, if it waits for messages.
+func (q *Memory) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+type memoryDelivery struct {
+	q *Memory
+	m *memoryMessage
+}
+
+func (d *memoryDelivery) Body() []byte {
+	return d.m.body
+}
+
+func (d *memoryDelivery) Attempt() int {
+	return d.m.attempt
+}
+
+func (d *memoryDelivery) Ack(ctx context.Context) error {
+	return nil
+}
+
+func (d *memoryDelivery) Nack(ctx context.Context, delay time.Duration, cause error) error {
+	d.m.attempt++
+	d.m.err = cause.Error()
+
+	time.AfterFunc(delay, func() { d.q.push(d.m) })
+
+	return nil
+}
+
+func (d *memoryDelivery) Reject(ctx context.Context, cause error) error {
+	d.m.attempt++
+	d.m.err = cause.Error()
+
+	d.q.mu.Lock()
+	d.q.dead = append(d.q.dead, d.m)
+	d.q.mu.Unlock()
+
+	return nil
+}
+
+func (d *memoryDelivery) Release(ctx context.Context) error {
+	d.q.pushFront(d.m)
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_Priority(t *testing.T) {
+	q := queue.NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	assert.NoError(t, q.Publish(ctx, []byte("bulk 1"), queue.PriorityLow))
+	assert.NoError(t, q.Publish(ctx, []byte("interactive"), queue.PriorityHigh))
+	assert.NoError(t, q.Publish(ctx, []byte("bulk 2"), queue.PriorityLow))
+
+	deliveries, err := q.Consume(ctx, 1)
+	assert.NoError(t, err)
+
+	var got []string
+
+	for i := 0; i < 3; i++ {
+		d := <-deliveries
+		got = append(got, string(d.Body()))
+		assert.NoError(t, d.Ack(ctx))
+	}
+
+	assert.Equal(t, []string{"interactive", "bulk 1", "bulk 2"}, got)
+}
@@ -0,0 +1,231 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/logging"
+)
+
+// JobTable is the table, where messages of the Postgres queue are kept.
+const JobTable = "jobs"
+
+// errJobLost is returned when the job is deleted or claimed by another consumer.
+var errJobLost = errors.New("job is not found or claimed again")
+
+// Postgres is the queue backed by the Postgres table. Messages are claimed with
+// SELECT ... FOR UPDATE SKIP LOCKED, so several converters could consume the same queue.
+// Claim of the message is extended while it's processed. Claimed messages, which are not extended
+// in the claim timeout, are delivered again, so messages of the crashed converter are not lost.
+// Each claim has its own number, so the message claimed again can't be settled by the previous consumer.
+type Postgres struct {
+	db           *sql.DB
+	pollInterval time.Duration
+	claimTimeout time.Duration
+}
+
+// NewPostgres is a constructor to the Postgres queue.
+func NewPostgres(db *sql.DB, conf *Config) *Postgres {
+	pollInterval := conf.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	claimTimeout := conf.ClaimTimeout
+	if claimTimeout <= 0 {
+		claimTimeout = defaultClaimTimeout
+	}
+
+	return &Postgres{db: db, pollInterval: pollInterval, claimTimeout: claimTimeout}
+}
+
+// Publish method inserts the message with the priority to the job table.
+func (q *Postgres) Publish(ctx context.Context, body []byte, priority uint8) error {
+	query := fmt.Sprintf(`INSERT INTO %s (body, priority) VALUES ($1, $2)`, JobTable)
+
+	if _, err := q.db.ExecContext(ctx, query, body, priority); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	return nil
+}
+
+// Consume method returns channel of the deliveries, which is closed after the context is done.
+// The job table is polled, when there are no ready messages. Messages are claimed one by one,
+// so prefetch is not used.
+func (q *Postgres) Consume(ctx context.Context, prefetch int) (<-chan Delivery, error) {
+	logger := logging.FromContext(ctx)
+	out := make(chan Delivery)
+
+	go func() {
+		defer close(out)
+
+		for {
+			d, err := q.claim(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Warnf("consume: %s", err)
+			}
+
+			if d == nil {
+				select {
+				case <-time.After(q.pollInterval):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				if err := d.Release(context.Background()); err != nil {
+					logger.Warnf("consume: return message to the queue: %s", err)
+				}
+
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// claim method locks the oldest ready message with the highest priority.
+// Messages, which claims are expired, are ready too. Returns nil if there are no ready messages.
+// Claim of the returned message is extended till the message is settled.
+func (q *Postgres) claim(ctx context.Context) (*postgresDelivery, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET locked_at = CURRENT_TIMESTAMP, claim = claim + 1
+WHERE id = (
+	SELECT id FROM %[1]s
+	WHERE NOT dead AND run_at <= CURRENT_TIMESTAMP
+		AND (locked_at IS NULL OR locked_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 millisecond')
+	ORDER BY priority DESC, run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+) RETURNING id, body, attempts, claim`, JobTable)
+
+	d := postgresDelivery{db: q.db, stop: make(chan struct{}), stopped: make(chan struct{})}
+
+	err := q.db.QueryRowContext(ctx, query, q.claimTimeout.Milliseconds()).Scan(&d.id, &d.body, &d.attempt, &d.claim)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("claim: %w", err)
+	}
+
+	go d.keepClaim(logging.Detach(ctx), q.claimTimeout/claimExtensions)
+
+	return &d, nil
+}
+
+// claimExtensions is the amount of the claim extensions in the claim timeout,
+// so the claim isn't expired if some of the extensions fail.
+const claimExtensions = 3
+
+type postgresDelivery struct {
+	db      *sql.DB
+	id      int64
+	body    []byte
+	attempt int
+	claim   int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+func (d *postgresDelivery) Body() []byte {
+	return d.body
+}
+
+func (d *postgresDelivery) Attempt() int {
+	return d.attempt
+}
+
+func (d *postgresDelivery) Ack(ctx context.Context) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND claim = $2`, JobTable)
+
+	return d.settle(ctx, "ack", query, d.id, d.claim)
+}
+
+func (d *postgresDelivery) Nack(ctx context.Context, delay time.Duration, cause error) error {
+	query := fmt.Sprintf(`UPDATE %s SET locked_at = NULL, attempts = attempts + 1,
+run_at = CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond', last_error = $4 WHERE id = $1 AND claim = $2`, JobTable)
+
+	return d.settle(ctx, "nack", query, d.id, d.claim, delay.Milliseconds(), cause.Error())
+}
+
+func (d *postgresDelivery) Reject(ctx context.Context, cause error) error {
+	query := fmt.Sprintf(`UPDATE %s SET locked_at = NULL, attempts = attempts + 1, dead = true,
+last_error = $3 WHERE id = $1 AND claim = $2`, JobTable)
+
+	return d.settle(ctx, "reject", query, d.id, d.claim, cause.Error())
+}
+
+func (d *postgresDelivery) Release(ctx context.Context) error {
+	query := fmt.Sprintf(`UPDATE %s SET locked_at = NULL WHERE id = $1 AND claim = $2`, JobTable)
+
+	return d.settle(ctx, "release", query, d.id, d.claim)
+}
+
+// keepClaim method extends the claim of the message after each interval till the message is settled
+// or it's claimed by another consumer.
+func (d *postgresDelivery) keepClaim(ctx context.Context, interval time.Duration) {
+	defer close(d.stopped)
+
+	logger := logging.FromContext(ctx)
+	query := fmt.Sprintf(`UPDATE %s SET locked_at = CURRENT_TIMESTAMP WHERE id = $1 AND claim = $2`, JobTable)
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := d.exec(ctx, "extend claim", query, d.id, d.claim)
+		if errors.Is(err, errJobLost) {
+			logger.Warnf("consume: %s", err)
+
+			return
+		}
+
+		if err != nil {
+			logger.Warnf("consume: %s", err)
+		}
+	}
+}
+
+// settle method stops extending of the claim and executes the query, which settles the message.
+func (d *postgresDelivery) settle(ctx context.Context, op, query string, args ...interface{}) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+	<-d.stopped
+
+	return d.exec(ctx, op, query, args...)
+}
+
+// exec method executes the query, which should affect the single job.
+func (d *postgresDelivery) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	result, err := d.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rows != 1 {
+		return fmt.Errorf("%s: job %v: %w", op, d.id, errJobLost)
+	}
+
+	return nil
+}
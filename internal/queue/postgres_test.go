@@ -0,0 +1,226 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dyleme/image-coverter/internal/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	publishJobQuery = regexp.QuoteMeta(fmt.Sprintf(`INSERT INTO %s (body, priority) VALUES ($1, $2)`, queue.JobTable))
+	claimJobQuery   = fmt.Sprintf(`UPDATE %[1]s SET locked_at = .+, claim = claim \+ 1 .+ SELECT id FROM %[1]s .+
+AND \(locked_at IS NULL OR locked_at < CURRENT_TIMESTAMP - \$1 .+\)
+ORDER BY priority DESC, run_at, id .+ FOR UPDATE SKIP LOCKED`, queue.JobTable)
+	ackJobQuery     = regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND claim = $2`, queue.JobTable))
+	nackJobQuery    = fmt.Sprintf(`UPDATE %s SET locked_at = NULL, attempts = attempts \+ 1, run_at = .+`, queue.JobTable)
+	rejectJobQuery  = fmt.Sprintf(`UPDATE %s SET locked_at = NULL, attempts = attempts \+ 1, dead = true`, queue.JobTable)
+	releaseJobQuery = regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET locked_at = NULL WHERE id = $1 AND claim = $2`,
+		queue.JobTable))
+	extendJobQuery = regexp.QuoteMeta(fmt.Sprintf(
+		`UPDATE %s SET locked_at = CURRENT_TIMESTAMP WHERE id = $1 AND claim = $2`, queue.JobTable))
+)
+
+var errJobs = errors.New("jobs error")
+
+func NewPostgresQueueMock(t *testing.T) (*queue.Postgres, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return queue.NewPostgres(db, &queue.Config{PollInterval: time.Millisecond}), mock
+}
+
+func TestPostgres_Publish(t *testing.T) {
+	testCases := []struct {
+		testName string
+		execErr  error
+		wantErr  error
+	}{
+		{
+			testName: "all is good",
+		},
+		{
+			testName: "error in db",
+			execErr:  errJobs,
+			wantErr:  errJobs,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			q, mock := NewPostgresQueueMock(t)
+
+			mock.ExpectExec(publishJobQuery).WithArgs([]byte("body"), queue.PriorityLow).
+				WillReturnResult(sqlmock.NewResult(1, 1)).WillReturnError(tc.execErr)
+
+			gotErr := q.Publish(context.Background(), []byte("body"), queue.PriorityLow)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgres_Consume(t *testing.T) {
+	testCases := []struct {
+		testName string
+		settle   func(context.Context, queue.Delivery) error
+		initMock func(sqlmock.Sqlmock)
+		wantErr  bool
+	}{
+		{
+			testName: "ack",
+			settle: func(ctx context.Context, d queue.Delivery) error {
+				return d.Ack(ctx)
+			},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(ackJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			testName: "nack",
+			settle: func(ctx context.Context, d queue.Delivery) error {
+				return d.Nack(ctx, time.Second, errJobs)
+			},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(nackJobQuery).WithArgs(7, 4, 1000, errJobs.Error()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			testName: "reject",
+			settle: func(ctx context.Context, d queue.Delivery) error {
+				return d.Reject(ctx, errJobs)
+			},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(rejectJobQuery).WithArgs(7, 4, errJobs.Error()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			testName: "release",
+			settle: func(ctx context.Context, d queue.Delivery) error {
+				return d.Release(ctx)
+			},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(releaseJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			testName: "job is claimed again",
+			settle: func(ctx context.Context, d queue.Delivery) error {
+				return d.Ack(ctx)
+			},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(ackJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			q, mock := NewPostgresQueueMock(t)
+			ctx, cancel := context.WithCancel(context.Background())
+
+			mock.ExpectQuery(claimJobQuery).WillReturnRows(sqlmock.NewRows([]string{}))
+			mock.ExpectQuery(claimJobQuery).WillReturnError(errJobs)
+			mock.ExpectQuery(claimJobQuery).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "body", "attempts", "claim"}).AddRow(7, []byte("body"), 2, 4))
+
+			deliveries, err := q.Consume(ctx, 1)
+			assert.NoError(t, err)
+
+			d := <-deliveries
+			cancel()
+
+			for range deliveries {
+			}
+
+			assert.Equal(t, []byte("body"), d.Body())
+			assert.Equal(t, 2, d.Attempt())
+
+			tc.initMock(mock)
+
+			gotErr := tc.settle(context.Background(), d)
+
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgres_ConsumeExpiredClaim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	q := queue.NewPostgres(db, &queue.Config{PollInterval: time.Millisecond, ClaimTimeout: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Message claimed by the crashed converter is claimed again after the claim timeout.
+	mock.ExpectQuery(claimJobQuery).WithArgs(int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "body", "attempts", "claim"}).AddRow(7, []byte("body"), 0, 1))
+
+	deliveries, err := q.Consume(ctx, 1)
+	assert.NoError(t, err)
+
+	d := <-deliveries
+	cancel()
+
+	for range deliveries {
+	}
+
+	assert.Equal(t, []byte("body"), d.Body())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_ConsumeExtendClaim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	q := queue.NewPostgres(db, &queue.Config{PollInterval: time.Millisecond, ClaimTimeout: 30 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mock.ExpectQuery(claimJobQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "body", "attempts", "claim"}).AddRow(7, []byte("body"), 0, 4))
+	mock.ExpectExec(extendJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Claim isn't extended after the message is claimed by another consumer.
+	mock.ExpectExec(extendJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	deliveries, err := q.Consume(ctx, 1)
+	assert.NoError(t, err)
+
+	d := <-deliveries
+	cancel()
+
+	for range deliveries {
+	}
+
+	assert.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, time.Millisecond)
+
+	mock.ExpectExec(ackJobQuery).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Error(t, d.Ack(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,169 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+)
+
+// Kinds of the queue implementations.
+const (
+	KindRabbitMQ = "rabbitmq"
+	KindPostgres = "postgres"
+	KindMemory   = "memory"
+)
+
+const (
+	defaultWorkers         = 1
+	defaultShutdownTimeout = 30 * time.Second
+	defaultRetryDelay      = time.Second
+	defaultMaxRetryDelay   = 5 * time.Minute
+	defaultPollInterval    = time.Second
+	defaultClaimTimeout    = 15 * time.Minute
+
+	// Time given to settle the messages of the cancelled conversions.
+	settleTimeout = 5 * time.Second
+)
+
+// Config of the queue and of the workers, which process messages from it.
+type Config struct {
+	// Kind is the queue implementation, rabbitmq by default.
+	Kind string
+
+	// Workers is the amount of the concurrent conversions, 1 by default.
+	Workers int
+
+	// ShutdownTimeout is the time given to finish in-flight conversions on shutdown, 30 seconds by default.
+	ShutdownTimeout time.Duration
+
+	// RetryDelay is the delay before the second attempt to process the message, 1 second by default.
+	// It's doubled after each failed attempt up to MaxRetryDelay, which is 5 minutes by default.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+
+	// PollInterval is the interval between polls of the Postgres queue, when it's empty, 1 second by default.
+	PollInterval time.Duration
+
+	// ClaimTimeout is the time after which the message of the Postgres queue, which claim is not extended,
+	// is delivered again, 15 minutes by default. The claim is extended three times in the timeout.
+	ClaimTimeout time.Duration
+}
+
+// Priorities of the messages, messages with the higher priority are consumed first.
+const (
+	PriorityLow  uint8 = 0
+	PriorityHigh uint8 = 1
+
+	// MaxPriority is the highest priority of the messages.
+	MaxPriority = PriorityHigh
+)
+
+// Publisher is an interface which provides method to send messages with the priority to the queue.
+type Publisher interface {
+	Publish(ctx context.Context, body []byte, priority uint8) error
+}
+
+// Queue is an interface of the message queue.
+// Consume returns channel of the deliveries, which is closed after the context is done.
+// Prefetch is the amount of messages which could be delivered but not yet acknowledged.
+type Queue interface {
+	Publisher
+	Consume(ctx context.Context, prefetch int) (<-chan Delivery, error)
+}
+
+// Delivery is a message received from the queue. Every delivery should be settled
+// with exactly one of the Ack, Nack, Reject or Release methods.
+type Delivery interface {
+	// Body returns the content of the message.
+	Body() []byte
+
+	// Attempt returns the amount of the failed attempts to process the message.
+	Attempt() int
+
+	// Ack removes the processed message from the queue.
+	Ack(ctx context.Context) error
+
+	// Nack returns the message to the queue after the delay and counts the failed attempt.
+	Nack(ctx context.Context, delay time.Duration, cause error) error
+
+	// Reject moves the message which couldn't be processed to the dead letters.
+	Reject(ctx context.Context, cause error) error
+
+	// Release returns the unprocessed message to the queue without counting the attempt.
+	Release(ctx context.Context) error
+}
+
+// Sender is a struct, which is used to send conversion requests to the queue.
+type Sender struct {
+	pub Publisher
+}
+
+// NewSender is a constructor to the Sender.
+func NewSender(pub Publisher) *Sender {
+	return &Sender{pub: pub}
+}
+
+// ProcessImage method marshals data in json and sends it to the queue.
+// Bulk requests are sent with the low priority, other requests are sent with the high one.
+func (s *Sender) ProcessImage(ctx context.Context, data *model.RequestToProcess) error {
+	jsn, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("process image: %w", err)
+	}
+
+	priority := PriorityHigh
+	if data.Priority == repository.PriorityBulk {
+		priority = PriorityLow
+	}
+
+	if err := s.pub.Publish(ctx, jsn, priority); err != nil {
+		return fmt.Errorf("process image: %w", err)
+	}
+
+	return nil
+}
+
+// temporary is implemented by the errors, after which the message should be processed again.
+type temporary interface {
+	Temporary() bool
+}
+
+// isTemporary function reports if the message should be processed again after the error.
+func isTemporary(err error) bool {
+	var t temporary
+
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// backoff function returns the delay before the next attempt of processing the message.
+func backoff(conf *Config, attempt int) time.Duration {
+	delay, maxDelay := conf.RetryDelay, conf.MaxRetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxRetryDelay
+	}
+
+	return Backoff(delay, maxDelay, attempt)
+}
+
+// Backoff function returns the delay before the attempt, which is doubled after each failed attempt
+// starting from the provided delay up to the maxDelay.
+func Backoff(delay, maxDelay time.Duration, attempt int) time.Duration {
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
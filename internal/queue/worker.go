@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// Converter is an interface which provide functions to convert images.
+type Converter interface {
+	Convert(ctx context.Context, reqID int, filename string) error
+}
+
+// Serve function gets messages from the queue and converts images by the pool of workers.
+// Message is acknowledged after the conversion. Messages failed with temporary errors are retried
+// with exponential backoff, other failed messages are rejected to the dead letters.
+// When the context is done, consuming is stopped and in-flight conversions are given the shutdown timeout
+// to finish. Then unfinished conversions are cancelled and their messages are returned to the queue,
+// Serve waits for it at most the settle timeout.
+func Serve(ctx context.Context, q Queue, conv Converter, conf *Config) error {
+	logger := logging.FromContext(ctx)
+
+	workers := conf.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	deliveries, err := q.Consume(ctx, workers)
+	if err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	logger.Infof("start conversion server with %v workers", workers)
+
+	// Conversions are not interrupted by the shutdown, they are cancelled only after the timeout.
+	workCtx, cancelWork := context.WithCancel(logging.Detach(ctx))
+	defer cancelWork()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			work(ctx, workCtx, conv, conf, deliveries)
+		}()
+	}
+
+	finished := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		if ctx.Err() == nil {
+			return fmt.Errorf("serve: deliveries channel is closed")
+		}
+
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("stop consuming")
+
+	timeout := conf.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	select {
+	case <-finished:
+		logger.Info("in-flight conversions are finished")
+
+		return nil
+	case <-time.After(timeout):
+		cancelWork()
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(settleTimeout):
+		logger.Warn("cancelled conversions are not settled in time")
+	}
+
+	return fmt.Errorf("serve: in-flight conversions are not finished in %v, they are returned to the queue", timeout)
+}
+
+// work function handles deliveries till the channel is closed.
+// Deliveries received after the shutdown are returned to the queue without processing.
+func work(ctx, workCtx context.Context, conv Converter, conf *Config, deliveries <-chan Delivery) {
+	logger := logging.FromContext(workCtx)
+
+	for d := range deliveries {
+		if ctx.Err() != nil {
+			release(logging.Detach(workCtx), d)
+
+			continue
+		}
+
+		logger.Debug("get conversion reqeust")
+
+		handleDelivery(workCtx, conv, conf, d)
+	}
+}
+
+// handleDelivery function converts the image from the message and acknowledges it.
+// Message is returned to the queue after the delay or rejected if the conversion fails.
+// If the conversion is cancelled or the message can't be settled, it's released to the queue.
+// Message is settled with the detached context, so it's done even after the conversion is cancelled.
+func handleDelivery(ctx context.Context, conv Converter, conf *Config, d Delivery) {
+	logger := logging.FromContext(ctx)
+	attempt := d.Attempt() + 1
+
+	var data model.RequestToProcess
+
+	err := json.Unmarshal(d.Body(), &data)
+	if err == nil {
+		convBegin := time.Now()
+
+		err = conv.Convert(ctx, data.ReqID, data.FileName)
+
+		logger.WithField("time for conversion", time.Since(convBegin)).
+			Debug("conversion ends")
+	}
+
+	settleCtx, cancel := context.WithTimeout(logging.Detach(ctx), settleTimeout)
+	defer cancel()
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		logger.Warnf("serve: conversion is cancelled: %s", err)
+		release(settleCtx, d)
+
+		return
+	case err == nil:
+		err = d.Ack(settleCtx)
+	case isTemporary(err):
+		delay := backoff(conf, attempt)
+		logger.Warnf("serve: attempt %v failed, retry in %v: %s", attempt, delay, err)
+		err = d.Nack(settleCtx, delay, err)
+	default:
+		logger.Warnf("serve: %s", err)
+		err = d.Reject(settleCtx, err)
+	}
+
+	if err != nil {
+		logger.Warnf("serve: %s", err)
+		release(settleCtx, d)
+	}
+}
+
+// release function returns the message to the queue without counting the attempt.
+func release(ctx context.Context, d Delivery) {
+	if err := d.Release(ctx); err != nil {
+		logging.FromContext(ctx).Warnf("serve: return message to the queue: %s", err)
+	}
+}
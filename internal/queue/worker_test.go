@@ -0,0 +1,247 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+type converterFunc func(ctx context.Context, reqID int, filename string) error
+
+func (f converterFunc) Convert(ctx context.Context, reqID int, filename string) error {
+	return f(ctx, reqID, filename)
+}
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary error" }
+func (temporaryError) Temporary() bool { return true }
+
+var errConversion = errors.New("conversion error")
+
+func TestServe(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		errs         []error
+		wantAttempts int
+		wantDead     bool
+	}{
+		{
+			testName:     "converted",
+			wantAttempts: 1,
+		},
+		{
+			testName:     "converted after temporary errors",
+			errs:         []error{temporaryError{}, temporaryError{}},
+			wantAttempts: 3,
+		},
+		{
+			testName:     "permanent error",
+			errs:         []error{errConversion},
+			wantAttempts: 1,
+			wantDead:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			q := queue.NewMemory()
+			conf := &queue.Config{Workers: 2, RetryDelay: time.Millisecond}
+			done := make(chan struct{})
+
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+
+			conv := converterFunc(func(ctx context.Context, reqID int, filename string) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				assert.Equal(t, 12, reqID)
+				assert.Equal(t, "x.png", filename)
+
+				attempts++
+				if attempts == tc.wantAttempts {
+					close(done)
+				}
+
+				if attempts <= len(tc.errs) {
+					return tc.errs[attempts-1]
+				}
+
+				return nil
+			})
+
+			served := make(chan error)
+
+			go func() {
+				served <- queue.Serve(ctx, q, conv, conf)
+			}()
+
+			err := queue.NewSender(q).ProcessImage(ctx, &model.RequestToProcess{ReqID: 12, FileName: "x.png"})
+			assert.NoError(t, err)
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("message is not processed")
+			}
+
+			cancel()
+			assert.NoError(t, <-served)
+
+			assert.Equal(t, tc.wantAttempts, attempts)
+
+			if tc.wantDead {
+				assert.Len(t, q.DeadLetters(), 1)
+			} else {
+				assert.Empty(t, q.DeadLetters())
+			}
+		})
+	}
+}
+
+func TestServe_Workers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const workers = 3
+
+	q := queue.NewMemory()
+	started := make(chan int, workers)
+	unblock := make(chan struct{})
+
+	conv := converterFunc(func(ctx context.Context, reqID int, filename string) error {
+		started <- reqID
+		<-unblock
+
+		return nil
+	})
+
+	served := make(chan error)
+
+	go func() {
+		served <- queue.Serve(ctx, q, conv, &queue.Config{Workers: workers})
+	}()
+
+	for i := 0; i < workers; i++ {
+		err := queue.NewSender(q).ProcessImage(ctx, &model.RequestToProcess{ReqID: i, FileName: "x.png"})
+		assert.NoError(t, err)
+	}
+
+	// All messages are processed concurrently.
+	for i := 0; i < workers; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("only %v conversions are started", i)
+		}
+	}
+
+	close(unblock)
+	cancel()
+	assert.NoError(t, <-served)
+	assert.Empty(t, q.DeadLetters())
+}
+
+func TestServe_Shutdown(t *testing.T) {
+	testCases := []struct {
+		testName        string
+		conversionTime  time.Duration
+		shutdownTimeout time.Duration
+		wantErr         bool
+		wantReleased    bool
+	}{
+		{
+			testName:        "in-flight conversion is finished",
+			conversionTime:  10 * time.Millisecond,
+			shutdownTimeout: time.Second,
+		},
+		{
+			testName:        "in-flight conversion is not finished in time",
+			conversionTime:  time.Second,
+			shutdownTimeout: 10 * time.Millisecond,
+			wantErr:         true,
+			wantReleased:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			q := queue.NewMemory()
+			conf := &queue.Config{ShutdownTimeout: tc.shutdownTimeout}
+			started := make(chan struct{})
+			returned := make(chan struct{})
+
+			conv := converterFunc(func(ctx context.Context, reqID int, filename string) error {
+				close(started)
+				defer close(returned)
+
+				select {
+				case <-time.After(tc.conversionTime):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+
+			served := make(chan error)
+
+			go func() {
+				served <- queue.Serve(ctx, q, conv, conf)
+			}()
+
+			body, err := json.Marshal(&model.RequestToProcess{ReqID: 12, FileName: "x.png"})
+			assert.NoError(t, err)
+			assert.NoError(t, q.Publish(ctx, body, queue.PriorityHigh))
+
+			<-started
+			cancel()
+
+			gotErr := <-served
+			if tc.wantErr {
+				assert.Error(t, gotErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+
+			// Serve waits for the cancelled conversions.
+			select {
+			case <-returned:
+			default:
+				t.Fatal("serve returned before the conversion")
+			}
+
+			// Cancelled conversion is returned to the queue instead of the dead letters.
+			assert.Empty(t, q.DeadLetters())
+
+			consumeCtx, stopConsume := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer stopConsume()
+
+			deliveries, err := q.Consume(consumeCtx, 1)
+			assert.NoError(t, err)
+
+			d, ok := <-deliveries
+			assert.Equal(t, tc.wantReleased, ok)
+
+			if ok {
+				assert.Equal(t, body, d.Body())
+				assert.Equal(t, 0, d.Attempt())
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package rabbitmq
+
+import "context"
+
+type (
+	Connection = connection
+	Channel    = channel
+)
+
+// NewQueueWithDial is NewQueue, which opens connections with the provided function.
+func NewQueueWithDial(ctx context.Context, c *Config, dial func(url string) (Connection, error)) (*Queue, error) {
+	return newQueue(ctx, c, dial)
+}
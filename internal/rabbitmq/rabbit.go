@@ -2,20 +2,32 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Dyleme/image-coverter/internal/logging"
-	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/queue"
 	"github.com/streadway/amqp"
 )
 
-// RabbitSender is a struct, which is used to send data
-// to the  image converter using RabbitMQ.
-type RabbitSender struct {
-	conn *amqp.Connection
-	ch   *amqp.Channel
+// Queue is a struct, which is used to send and receive conversion requests using RabbitMQ.
+// Connection is supervised: when it's lost, Queue reconnects, declares queues again
+// and registers consumers again.
+type Queue struct {
+	conf *Config
+	dial dialer
+
+	mu         sync.Mutex
+	conn       connection
+	pub        *confirmChannel
+	connClosed chan *amqp.Error
+	chClosed   chan *amqp.Error
+	ready      chan struct{} // closed, when the connection is established
+	waiting    int           // publishes waiting for the reconnection
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // Config to connect to the message broker.
@@ -24,180 +36,398 @@ type Config struct {
 	Password string
 	Host     string
 	Port     string
+
+	// ReconnectDelay is the delay before the first reconnection attempt, 1 second by default.
+	// It's doubled after each failed attempt up to MaxReconnectDelay, which is 30 seconds by default.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// PublishBuffer is the amount of publishes, which wait for the reconnection, while the connection is lost.
+	// Publishes fail fast with ErrNotConnected by default.
+	PublishBuffer int
 }
 
-// Name of the queue, which is used to communicate with the RabbitMQ.
-var queueName = "convert"
+// Name of the priority queue, which is used to communicate with the RabbitMQ.
+var queueName = "convert.priority"
 
-// NewRabbitSender returns *RabbitSender, which is ready to send messages.
-// NewRabbitSender at first initialize connection with RabbitMQ server,
-// than it initialize channel with broker.
-func NewRabbitSender(c *Config) (*RabbitSender, error) {
-	connStr := fmt.Sprintf("amqps://%s:%s@%s:%s/", c.User, c.Password, c.Host, c.Port)
-	conn, err := amqp.Dial(connStr)
+// Name of the conversion queue without priority. The existing durable queue can't be redeclared
+// with the priority, so messages are published to the new queue and the legacy one is consumed till it's drained.
+const legacyQueueName = "convert"
 
-	if err != nil {
-		return nil, fmt.Errorf("unable to make connection to rabbitMQ: %w", err)
+// Tags of the converter consumers, which are used to stop consuming.
+const (
+	consumerTag       = "converter"
+	legacyConsumerTag = "converter.legacy"
+)
+
+// consumedQueues are the queues, from which conversion requests are received.
+var consumedQueues = []struct {
+	name string
+	tag  string
+}{
+	{name: queueName, tag: consumerTag},
+	{name: legacyQueueName, tag: legacyConsumerTag},
+}
+
+// NewQueue returns *Queue, which is ready to send and receive messages.
+// NewQueue at first initialize connection with RabbitMQ server,
+// than it initialize channel with broker and declares the conversion and dead-letter queues.
+// Then connection is supervised till the queue is closed.
+func NewQueue(ctx context.Context, c *Config) (*Queue, error) {
+	return newQueue(ctx, c, dialAMQP)
+}
+
+func newQueue(ctx context.Context, c *Config, dial dialer) (*Queue, error) {
+	q := &Queue{
+		conf:  c,
+		dial:  dial,
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
 	}
 
-	ch, err := conn.Channel()
-	if err != nil {
-		return nil, fmt.Errorf("falied in open a channel: %w", err)
+	if err := q.connect(); err != nil {
+		return nil, err
 	}
 
-	err = ch.Qos(
-		1,     // prefetch count
-		0,     // prfectSize
-		false, // global
-	)
+	go q.supervise(ctx)
 
-	if err != nil {
-		return nil, fmt.Errorf("falied in open a channel: %w", err)
+	return q, nil
+}
+
+// Close method stops the supervision and closes the connection.
+// Unacknowledged messages are returned to the queue by the broker.
+func (q *Queue) Close() error {
+	q.closeOnce.Do(func() { close(q.done) })
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conn == nil {
+		return nil
 	}
 
-	return &RabbitSender{conn: conn, ch: ch}, nil
+	return q.conn.Close()
 }
 
-// This function is used to send images and data to convert it, to the message broker.
-func (r *RabbitSender) ProcessImage(ctx context.Context, data *model.RequestToProcess) error {
-	return r.SendJSON(ctx, data)
+// Publish method sends the message with the priority to the conversion queue
+// and returns after the broker confirms it, so the returned nil means that the message is stored by the broker.
+// If the connection is lost, Publish waits for the reconnection,
+// or ErrNotConnected is returned if there are PublishBuffer publishes waiting already.
+func (q *Queue) Publish(ctx context.Context, body []byte, priority uint8) error {
+	msg := amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		Priority:     priority,
+		Body:         body,
+	}
+
+	pub, err := q.publishChannel(ctx)
+	if err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+
+	if err := pub.publish(ctx, queueName, msg); err != nil {
+		return fmt.Errorf("publish: unable to publish message: %w", err)
+	}
+
+	return nil
 }
 
-// This function send data to the message broker.
-// At first this function initialize queue to communicate with message broker,
-// Then it marshals data in json and send this json to the queue.
-// If any error occurs, this function log it to the logger, getted from context.
-func (r *RabbitSender) SendJSON(ctx context.Context, data interface{}) error {
-	q, err := r.ch.QueueDeclare(
-		queueName,
-		true,  // durable
-		false, // delte when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
+// publishChannel method returns the publishing channel.
+// If the connection is lost, it waits for the reconnection, when there is a place in the publish buffer.
+func (q *Queue) publishChannel(ctx context.Context) (*confirmChannel, error) {
+	q.mu.Lock()
 
-	if err != nil {
-		return fmt.Errorf("send json: unable to make a queue: %w", err)
+	if q.pub != nil {
+		defer q.mu.Unlock()
+
+		return q.pub, nil
 	}
 
-	jsn, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("send json: %w", err)
+	if q.waiting >= q.conf.PublishBuffer {
+		q.mu.Unlock()
+
+		return nil, ErrNotConnected
 	}
 
-	err = r.ch.Publish(
-		"",
-		q.Name,
-		false,
-		false,
-		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         jsn,
-		})
+	q.waiting++
+	q.mu.Unlock()
+
+	_, err := q.connection(ctx)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.waiting--
 
 	if err != nil {
-		return fmt.Errorf("send json: uanble to publish message")
+		return nil, err
 	}
 
-	return nil
-}
+	if q.pub == nil {
+		return nil, ErrNotConnected
+	}
 
-// Converter is an interface which provide functions to convert images.
-type Converter interface {
-	Convert(ctx context.Context, reqID int, filename string) error
+	return q.pub, nil
 }
 
-// Receive is method which is used to get messages from RabbitMQ and then convert images.
-// At first this function initialize connection, channel and queue to with RabbitMQ.
-// Then it in infinite loop get messages from queue, convert image and process it.
-func Receive(ctx context.Context, conv Converter, conf *Config) error {
-	logger := logging.FromContext(ctx)
-	connStr := fmt.Sprintf("amqps://%s:%s@%s:%s/", conf.User, conf.Password, conf.Host, conf.Port)
+// confirmChannel is the channel in the confirm mode.
+// Publishes are serialized, so the confirmation is matched with the published message.
+type confirmChannel struct {
+	mu        sync.Mutex
+	ch        channel
+	confirms  <-chan amqp.Confirmation
+	published uint64 // delivery tag of the last published message
+}
 
-	conn, err := amqp.Dial(connStr)
-	if err != nil {
-		return fmt.Errorf("can not connect to broker")
+// newConfirmChannel function puts the channel to the confirm mode.
+func newConfirmChannel(ch channel) (*confirmChannel, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to put the channel to the confirm mode: %w", err)
 	}
 
-	msgs, err := createConnectionAndQueue(conn)
-	if err != nil {
-		return fmt.Errorf("unable to make connection to rabbitMQ: %w", err)
+	return &confirmChannel{ch: ch, confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1))}, nil
+}
+
+// publish method sends the message to the queue and waits for its confirmation till the context is done.
+// The channel is closed, when the connection is lost, so waiting is stopped then.
+// Confirmations of the messages, which weren't waited for, are skipped by their delivery tags.
+func (c *confirmChannel) publish(ctx context.Context, queue string, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ch.Publish("", queue, false, false, msg); err != nil {
+		return err
 	}
 
-	logger.Info("start conversion server")
+	c.published++
 
-loop:
 	for {
 		select {
-		case d := <-msgs:
-			logger.Debug("get conversion reqeust")
+		case confirm, ok := <-c.confirms:
+			if !ok {
+				return amqp.ErrClosed
+			}
 
-			var data model.RequestToProcess
+			if confirm.DeliveryTag < c.published {
+				continue
+			}
 
-			err := json.Unmarshal(d.Body, &data)
-			if err != nil {
-				logger.Warn("Umarshaling error")
+			if !confirm.Ack {
+				return ErrNotConfirmed
 			}
 
-			convBegin := time.Now()
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Consume method returns channel of the deliveries, which is closed after the context is done
+// or the queue is closed. Consumer is registered again after the reconnection.
+func (q *Queue) Consume(ctx context.Context, prefetch int) (<-chan queue.Delivery, error) {
+	logger := logging.FromContext(ctx)
+	out := make(chan queue.Delivery)
+
+	go func() {
+		defer close(out)
 
-			err = conv.Convert(logging.WithLogger(context.TODO(), logger), data.ReqID, data.FileName)
+		for {
+			conn, err := q.connection(ctx)
 			if err != nil {
-				logger.Warnf("receive: %s", err)
+				return
+			}
+
+			err = consume(ctx, conn, prefetch, out)
+			if ctx.Err() != nil {
+				return
 			}
 
-			logger.WithField("time for conversion", time.Since(convBegin)).
-				Debug("conversion ends")
+			logger.Warnf("consume: %s, register consumer again", err)
 
-		case <-ctx.Done():
-			break loop
+			select {
+			case <-time.After(reconnectDelay(q.conf, 1)):
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	return out, nil
+}
+
+// consume function consumes all the conversion queues and sends deliveries to the out channel
+// till consuming of any of them is stopped, then consuming of the rest is cancelled too.
+func consume(ctx context.Context, conn connection, prefetch int, out chan<- queue.Delivery) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(consumedQueues))
+
+	for i, cq := range consumedQueues {
+		go func(name, tag string, prefetch int) {
+			errs <- consumeQueue(ctx, conn, name, tag, prefetch, out)
+		}(cq.name, cq.tag, queuePrefetch(prefetch, i))
 	}
 
-	return nil
+	err := <-errs
+
+	cancel()
+
+	for i := 1; i < len(consumedQueues); i++ {
+		<-errs
+	}
+
+	return err
 }
 
-func createConnectionAndQueue(conn *amqp.Connection) (<-chan amqp.Delivery, error) {
+// queuePrefetch function returns the prefetch count of the i-th consumed queue. Prefetch count is applied
+// to each channel, so it's split between the queues and the amount of the in-flight deliveries doesn't exceed it.
+// The first queues get the remainder, but each queue gets at least one delivery.
+func queuePrefetch(prefetch, i int) int {
+	n := len(consumedQueues)
+
+	count := prefetch / n
+	if i < prefetch%n {
+		count++
+	}
+
+	if count < 1 {
+		count = 1
+	}
+
+	return count
+}
+
+// consumeQueue function opens the channel with prefetch count and sends deliveries of the queue
+// to the out channel till consuming is stopped. Consuming is cancelled after the context is done,
+// but the channel is kept open to settle in-flight deliveries.
+func consumeQueue(ctx context.Context, conn connection, name, tag string, prefetch int,
+	out chan<- queue.Delivery) error {
+	logger := logging.FromContext(ctx)
+
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("falied in open a channel: %w", err)
+		return fmt.Errorf("falied in open a channel: %w", err)
 	}
 
 	err = ch.Qos(
-		1,     // prefetch count
-		0,     // prfectSize
-		false, // global
+		prefetch, // prefetch count
+		0,        // prfectSize
+		false,    // global
 	)
 	if err != nil {
-		return nil, fmt.Errorf("falied in open a channel: %w", err)
+		return fmt.Errorf("falied in open a channel: %w", err)
 	}
 
-	q, err := ch.QueueDeclare(
+	// Failed messages are republished from the consuming channel, they are acknowledged after the confirmation.
+	pub, err := newConfirmChannel(ch)
+	if err != nil {
+		return err
+	}
+
+	msgs, err := ch.Consume(
+		name,  // queue
+		tag,   // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register a consumer of %s: %w", name, err)
+	}
+
+	closed := make(chan struct{})
+	defer close(closed)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := ch.Cancel(tag, false); err != nil {
+				logger.Warnf("consume: stop consuming: %s", err)
+			}
+		case <-closed:
+		}
+	}()
+
+	for d := range msgs {
+		out <- &delivery{pub: pub, d: d}
+	}
+
+	return fmt.Errorf("deliveries channel of %s is closed", name)
+}
+
+// declareQueues function declares the conversion, legacy conversion and dead-letter queues.
+// Conversion queue is the priority queue, messages with the higher priority are delivered first.
+func declareQueues(ch channel) error {
+	_, err := ch.QueueDeclare(
 		queueName,
 		true,  // durable
 		false, // delete when unused
 		false, // exclusive
 		false, // no-wait
+		amqp.Table{"x-max-priority": int32(queue.MaxPriority)},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare a queue: %w", err)
+	}
+
+	_, err = ch.QueueDeclare(
+		legacyQueueName,
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
 		nil,   // arguments
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare a queue: %w", err)
+		return fmt.Errorf("failed to declare a legacy queue: %w", err)
 	}
 
-	msgs, err := ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		true,   // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
+	_, err = ch.QueueDeclare(
+		deadLetterQueue,
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		nil,   // arguments
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register a consumer: %w", err)
+		return fmt.Errorf("failed to declare a dead-letter queue: %w", err)
 	}
 
-	return msgs, nil
+	return nil
+}
+
+// delivery is the message received from the RabbitMQ.
+type delivery struct {
+	pub *confirmChannel
+	d   amqp.Delivery
+}
+
+func (d *delivery) Body() []byte {
+	return d.d.Body
+}
+
+func (d *delivery) Attempt() int {
+	return failedAttempts(d.d.Headers)
+}
+
+func (d *delivery) Ack(ctx context.Context) error {
+	return d.d.Ack(false)
+}
+
+func (d *delivery) Nack(ctx context.Context, delay time.Duration, cause error) error {
+	return retry(ctx, d.pub, &d.d, d.Attempt()+1, delay, cause)
+}
+
+func (d *delivery) Reject(ctx context.Context, cause error) error {
+	return deadLetter(ctx, d.pub, &d.d, d.Attempt()+1, cause)
+}
+
+func (d *delivery) Release(ctx context.Context) error {
+	return d.d.Nack(false, true)
 }
@@ -0,0 +1,660 @@
+package rabbitmq_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/queue"
+	"github.com/Dyleme/image-coverter/internal/rabbitmq"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDial = errors.New("dial error")
+
+// fakeBroker keeps the state of the connections opened by the queue.
+type fakeBroker struct {
+	mu        sync.Mutex
+	down      bool
+	nack      bool
+	hold      bool // confirmations are held till they are released
+	held      []heldConfirmation
+	dials     int
+	conns     []*fakeConn
+	declared  map[string]amqp.Table
+	published []amqp.Publishing
+	consumers []*fakeChannel
+	acked     int
+	requeued  int
+}
+
+// heldConfirmation is the confirmation, which isn't sent to the channel yet.
+type heldConfirmation struct {
+	ch      *fakeChannel
+	confirm amqp.Confirmation
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{declared: make(map[string]amqp.Table)}
+}
+
+func (b *fakeBroker) dial(url string) (rabbitmq.Connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dials++
+
+	if b.down {
+		return nil, errDial
+	}
+
+	conn := &fakeConn{b: b}
+	b.conns = append(b.conns, conn)
+
+	return conn, nil
+}
+
+// setDown method makes the broker unavailable, the last connection is lost.
+func (b *fakeBroker) setDown(down bool) {
+	b.mu.Lock()
+	b.down = down
+
+	var conn *fakeConn
+	if down && len(b.conns) > 0 {
+		conn = b.conns[len(b.conns)-1]
+	}
+	b.mu.Unlock()
+
+	if conn != nil {
+		conn.lose()
+	}
+}
+
+func (b *fakeBroker) state() (dials int, published []amqp.Publishing, consumers []*fakeChannel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dials, append([]amqp.Publishing(nil), b.published...), append([]*fakeChannel(nil), b.consumers...)
+}
+
+// consumersOf method returns the channels, which consume the queue.
+func (b *fakeBroker) consumersOf(queue string) []*fakeChannel {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var consumers []*fakeChannel
+
+	for _, ch := range b.consumers {
+		if ch.queue == queue {
+			consumers = append(consumers, ch)
+		}
+	}
+
+	return consumers
+}
+
+type fakeConn struct {
+	b        *fakeBroker
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (rabbitmq.Channel, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	ch := &fakeChannel{conn: c}
+	c.channels = append(c.channels, ch)
+
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	c.notify = append(c.notify, receiver)
+
+	return receiver
+}
+
+func (c *fakeConn) Close() error {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	c.closeLocked()
+
+	return nil
+}
+
+// lose method closes the connection as if it's lost and notifies the listeners.
+func (c *fakeConn) lose() {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closeLocked()
+
+	for _, n := range c.notify {
+		n <- amqp.ErrClosed
+	}
+}
+
+func (c *fakeConn) closeLocked() {
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+
+	for _, ch := range c.channels {
+		if ch.msgs != nil {
+			close(ch.msgs)
+		}
+
+		if ch.confirms != nil {
+			close(ch.confirms)
+		}
+	}
+}
+
+type fakeChannel struct {
+	conn     *fakeConn
+	msgs     chan amqp.Delivery
+	confirms chan amqp.Confirmation
+	tag      uint64
+	queue    string
+	prefetch int
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	ch.prefetch = prefetchCount
+
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool,
+	args amqp.Table) (<-chan amqp.Delivery, error) {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	if ch.conn.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	ch.msgs = make(chan amqp.Delivery, 1)
+	ch.queue = queue
+	ch.conn.b.consumers = append(ch.conn.b.consumers, ch)
+
+	return ch.msgs, nil
+}
+
+func (ch *fakeChannel) Cancel(consumer string, noWait bool) error {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	if !ch.conn.closed {
+		close(ch.msgs)
+		ch.msgs = nil
+	}
+
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool,
+	args amqp.Table) (amqp.Queue, error) {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	ch.conn.b.declared[name] = args
+
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	if ch.conn.closed {
+		return amqp.ErrClosed
+	}
+
+	ch.conn.b.published = append(ch.conn.b.published, msg)
+
+	if ch.confirms != nil {
+		ch.tag++
+		confirm := amqp.Confirmation{DeliveryTag: ch.tag, Ack: !ch.conn.b.nack}
+
+		if ch.conn.b.hold {
+			ch.conn.b.held = append(ch.conn.b.held, heldConfirmation{ch: ch, confirm: confirm})
+		} else {
+			ch.confirms <- confirm
+		}
+	}
+
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(noWait bool) error {
+	return nil
+}
+
+// NotifyPublish method returns the buffered channel, so late confirmations don't block the publishes.
+func (ch *fakeChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	ch.confirms = make(chan amqp.Confirmation, 16)
+
+	return ch.confirms
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return receiver
+}
+
+func (ch *fakeChannel) Ack(tag uint64, multiple bool) error {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	ch.conn.b.acked++
+
+	return nil
+}
+
+func (ch *fakeChannel) Nack(tag uint64, multiple bool, requeue bool) error {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	if requeue {
+		ch.conn.b.requeued++
+	}
+
+	return nil
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+// deliver method sends the message to the consumer of the channel.
+func (ch *fakeChannel) deliver(body []byte) {
+	ch.conn.b.mu.Lock()
+	defer ch.conn.b.mu.Unlock()
+
+	ch.msgs <- amqp.Delivery{Acknowledger: ch, Body: body}
+}
+
+// release method sends the held confirmations and stops holding them.
+func (b *fakeBroker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, h := range b.held {
+		h.ch.confirms <- h.confirm
+	}
+
+	b.hold, b.held = false, nil
+}
+
+// settled method returns the amount of acknowledged and requeued deliveries.
+func (b *fakeBroker) settled() (acked, requeued int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.acked, b.requeued
+}
+
+// eventually function waits till the condition is true.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	assert.Eventually(t, condition, time.Second, time.Millisecond)
+}
+
+func TestNewQueue(t *testing.T) {
+	b := newFakeBroker()
+	b.down = true
+
+	_, err := rabbitmq.NewQueueWithDial(context.Background(), &rabbitmq.Config{}, b.dial)
+	assert.ErrorIs(t, err, errDial)
+
+	b.down = false
+
+	q, err := rabbitmq.NewQueueWithDial(context.Background(), &rabbitmq.Config{}, b.dial)
+	require.NoError(t, err)
+
+	defer q.Close()
+
+	assert.Equal(t, amqp.Table{"x-max-priority": int32(queue.MaxPriority)}, b.declared["convert.priority"])
+	assert.Contains(t, b.declared, "convert")
+	assert.Nil(t, b.declared["convert"], "legacy queue is declared with the same arguments")
+	assert.Contains(t, b.declared, "convert.dead")
+}
+
+func TestQueue_Publish(t *testing.T) {
+	testCases := []struct {
+		testName string
+		nack     bool
+		wantErr  error
+	}{
+		{
+			testName: "confirmed",
+		},
+		{
+			testName: "not confirmed",
+			nack:     true,
+			wantErr:  rabbitmq.ErrNotConfirmed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			b := newFakeBroker()
+			b.nack = tc.nack
+
+			q, err := rabbitmq.NewQueueWithDial(context.Background(), &rabbitmq.Config{}, b.dial)
+			require.NoError(t, err)
+
+			defer q.Close()
+
+			err = q.Publish(context.Background(), []byte("body"), queue.PriorityHigh)
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			_, published, _ := b.state()
+			require.Len(t, published, 1)
+			assert.Equal(t, []byte("body"), published[0].Body)
+			assert.Equal(t, queue.PriorityHigh, published[0].Priority)
+		})
+	}
+}
+
+func TestQueue_PublishNotConnected(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		publishBuffer int
+		wantErr       error
+	}{
+		{
+			testName:      "fail fast without buffer",
+			publishBuffer: 0,
+			wantErr:       rabbitmq.ErrNotConnected,
+		},
+		{
+			testName:      "wait for reconnection",
+			publishBuffer: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			b := newFakeBroker()
+			conf := &rabbitmq.Config{ReconnectDelay: time.Millisecond, MaxReconnectDelay: time.Millisecond,
+				PublishBuffer: tc.publishBuffer}
+
+			q, err := rabbitmq.NewQueueWithDial(context.Background(), conf, b.dial)
+			require.NoError(t, err)
+
+			defer q.Close()
+
+			b.setDown(true)
+
+			// Queue tries to reconnect after the lost connection is forgotten.
+			eventually(t, func() bool {
+				dials, _, _ := b.state()
+
+				return dials > 1
+			})
+
+			published := make(chan error, 1)
+
+			go func() {
+				published <- q.Publish(context.Background(), []byte("body"), queue.PriorityLow)
+			}()
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, <-published, tc.wantErr)
+
+				return
+			}
+
+			// Message isn't reported as sent, till it's confirmed after the reconnection.
+			select {
+			case err := <-published:
+				t.Fatalf("publish returned while the broker is down: %v", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			b.setDown(false)
+
+			assert.NoError(t, <-published)
+
+			_, msgs, _ := b.state()
+			require.Len(t, msgs, 1)
+			assert.Equal(t, []byte("body"), msgs[0].Body)
+		})
+	}
+}
+
+func TestQueue_PublishCancelled(t *testing.T) {
+	b := newFakeBroker()
+	conf := &rabbitmq.Config{ReconnectDelay: time.Hour, PublishBuffer: 1}
+
+	q, err := rabbitmq.NewQueueWithDial(context.Background(), conf, b.dial)
+	require.NoError(t, err)
+
+	defer q.Close()
+
+	b.setDown(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	eventually(t, func() bool {
+		return errors.Is(q.Publish(ctx, []byte("body"), queue.PriorityLow), context.DeadlineExceeded)
+	})
+}
+
+func TestQueue_PublishNotConfirmedInTime(t *testing.T) {
+	b := newFakeBroker()
+
+	q, err := rabbitmq.NewQueueWithDial(context.Background(), &rabbitmq.Config{}, b.dial)
+	require.NoError(t, err)
+
+	defer q.Close()
+
+	b.mu.Lock()
+	b.hold, b.nack = true, true
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = q.Publish(ctx, []byte("first"), queue.PriorityLow)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Late negative confirmation of the first message isn't taken for the confirmation of the second one.
+	b.release()
+
+	b.mu.Lock()
+	b.nack = false
+	b.mu.Unlock()
+
+	assert.NoError(t, q.Publish(context.Background(), []byte("second"), queue.PriorityLow))
+}
+
+func TestQueue_Consume(t *testing.T) {
+	b := newFakeBroker()
+	conf := &rabbitmq.Config{ReconnectDelay: time.Millisecond, MaxReconnectDelay: time.Millisecond}
+
+	q, err := rabbitmq.NewQueueWithDial(context.Background(), conf, b.dial)
+	require.NoError(t, err)
+
+	defer q.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := q.Consume(ctx, 1)
+	require.NoError(t, err)
+
+	eventually(t, func() bool {
+		return len(b.consumersOf("convert.priority")) == 1 && len(b.consumersOf("convert")) == 1
+	})
+
+	b.consumersOf("convert.priority")[0].deliver([]byte("first"))
+	assert.Equal(t, []byte("first"), (<-deliveries).Body())
+
+	// Legacy queue is drained too.
+	b.consumersOf("convert")[0].deliver([]byte("legacy"))
+	assert.Equal(t, []byte("legacy"), (<-deliveries).Body())
+
+	// Consumers are registered again after the reconnection.
+	b.setDown(true)
+	b.setDown(false)
+
+	eventually(t, func() bool {
+		return len(b.consumersOf("convert.priority")) == 2 && len(b.consumersOf("convert")) == 2
+	})
+
+	b.consumersOf("convert.priority")[1].deliver([]byte("second"))
+	assert.Equal(t, []byte("second"), (<-deliveries).Body())
+
+	cancel()
+
+	for range deliveries {
+	}
+}
+
+func TestQueue_ConsumePrefetch(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		prefetch     int
+		wantPriority int
+		wantLegacy   int
+	}{
+		{
+			testName:     "prefetch is split",
+			prefetch:     5,
+			wantPriority: 3,
+			wantLegacy:   2,
+		},
+		{
+			testName:     "each queue gets one delivery",
+			prefetch:     1,
+			wantPriority: 1,
+			wantLegacy:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			b := newFakeBroker()
+			conf := &rabbitmq.Config{ReconnectDelay: time.Millisecond, MaxReconnectDelay: time.Millisecond}
+
+			q, err := rabbitmq.NewQueueWithDial(context.Background(), conf, b.dial)
+			require.NoError(t, err)
+
+			defer q.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			deliveries, err := q.Consume(ctx, tc.prefetch)
+			require.NoError(t, err)
+
+			eventually(t, func() bool {
+				return len(b.consumersOf("convert.priority")) == 1 && len(b.consumersOf("convert")) == 1
+			})
+
+			assert.Equal(t, tc.wantPriority, b.consumersOf("convert.priority")[0].prefetch)
+			assert.Equal(t, tc.wantLegacy, b.consumersOf("convert")[0].prefetch)
+
+			cancel()
+
+			for range deliveries {
+			}
+		})
+	}
+}
+
+func TestDelivery_Nack(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		nack         bool
+		wantErr      error
+		wantAcked    int
+		wantRequeued int
+	}{
+		{
+			testName:  "retry is confirmed",
+			wantAcked: 1,
+		},
+		{
+			testName:     "retry is not confirmed",
+			nack:         true,
+			wantErr:      rabbitmq.ErrNotConfirmed,
+			wantRequeued: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			b := newFakeBroker()
+
+			q, err := rabbitmq.NewQueueWithDial(context.Background(), &rabbitmq.Config{}, b.dial)
+			require.NoError(t, err)
+
+			defer q.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deliveries, err := q.Consume(ctx, 1)
+			require.NoError(t, err)
+
+			eventually(t, func() bool {
+				return len(b.consumersOf("convert.priority")) == 1
+			})
+
+			b.consumersOf("convert.priority")[0].deliver([]byte("body"))
+			d := <-deliveries
+
+			b.mu.Lock()
+			b.nack = tc.nack
+			b.mu.Unlock()
+
+			err = d.Nack(ctx, time.Second, errors.New("failed"))
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			_, published, _ := b.state()
+			require.Len(t, published, 1)
+			assert.Equal(t, []byte("body"), published[0].Body)
+			assert.Equal(t, int32(1), published[0].Headers["x-attempt"])
+			assert.Contains(t, b.declared, "convert.retry.1000")
+
+			acked, requeued := b.settled()
+			assert.Equal(t, tc.wantAcked, acked, "original is acknowledged only after the confirmation")
+			assert.Equal(t, tc.wantRequeued, requeued)
+
+			cancel()
+
+			for range deliveries {
+			}
+		})
+	}
+}
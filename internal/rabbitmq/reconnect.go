@@ -0,0 +1,215 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/queue"
+	"github.com/streadway/amqp"
+)
+
+const (
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
+)
+
+// ErrNotConnected is returned by Publish, when the connection to the broker is lost
+// and the message can't be buffered till the reconnection.
+var ErrNotConnected = errors.New("rabbitmq: not connected")
+
+// ErrClosed is returned, when the queue is closed.
+var ErrClosed = errors.New("rabbitmq: queue is closed")
+
+// ErrNotConfirmed is returned by Publish, when the broker doesn't confirm the message.
+var ErrNotConfirmed = errors.New("rabbitmq: message is not confirmed")
+
+// connection is the connection to the broker, it's implemented by the *amqp.Connection.
+type connection interface {
+	Channel() (channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// channel is the channel of the connection, it's implemented by the *amqp.Channel.
+type channel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool,
+		args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+}
+
+// dialer is the function, which opens the connection to the broker with the url.
+type dialer func(url string) (connection, error)
+
+// amqpConnection adapts the *amqp.Connection to the connection interface.
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpConnection) Channel() (channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+// dialAMQP function opens the connection to the RabbitMQ server.
+func dialAMQP(url string) (connection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return amqpConnection{conn}, nil
+}
+
+// connect method dials the broker, opens the publishing channel in the confirm mode and declares queues.
+// Publishes waiting for the reconnection are woken up.
+func (q *Queue) connect() error {
+	connStr := fmt.Sprintf("amqps://%s:%s@%s:%s/", q.conf.User, q.conf.Password, q.conf.Host, q.conf.Port)
+
+	conn, err := q.dial(connStr)
+	if err != nil {
+		return fmt.Errorf("unable to make connection to rabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+
+		return fmt.Errorf("falied in open a channel: %w", err)
+	}
+
+	if err := declareQueues(ch); err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	pub, err := newConfirmChannel(ch)
+	if err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		conn.Close()
+
+		return ErrClosed
+	default:
+	}
+
+	q.conn, q.pub = conn, pub
+	q.connClosed = conn.NotifyClose(make(chan *amqp.Error, 1))
+	q.chClosed = ch.NotifyClose(make(chan *amqp.Error, 1))
+	close(q.ready)
+
+	return nil
+}
+
+// disconnect method forgets the lost connection, so publishes and consumers wait for reconnection.
+func (q *Queue) disconnect() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.conn != nil {
+		q.conn.Close()
+	}
+
+	q.conn, q.pub = nil, nil
+	q.ready = make(chan struct{})
+}
+
+// supervise method watches the connection and the publishing channel and reconnects
+// with exponential backoff, when any of them is closed. It returns after the queue is closed.
+func (q *Queue) supervise(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	for {
+		q.mu.Lock()
+		connClosed, chClosed := q.connClosed, q.chClosed
+		q.mu.Unlock()
+
+		var amqpErr *amqp.Error
+
+		select {
+		case amqpErr = <-connClosed:
+		case amqpErr = <-chClosed:
+		case <-q.done:
+			return
+		}
+
+		q.disconnect()
+		logger.Warnf("rabbitmq: connection is lost: %v", amqpErr)
+
+		for attempt := 1; ; attempt++ {
+			delay := reconnectDelay(q.conf, attempt)
+
+			select {
+			case <-time.After(delay):
+			case <-q.done:
+				return
+			}
+
+			err := q.connect()
+			if err == nil {
+				logger.Info("rabbitmq: connection is restored")
+
+				break
+			}
+
+			logger.Warnf("rabbitmq: reconnection attempt %v failed: %s", attempt, err)
+		}
+	}
+}
+
+// connection method returns the current connection. If the connection is lost,
+// it waits for the reconnection till the context is done or the queue is closed.
+func (q *Queue) connection(ctx context.Context) (connection, error) {
+	for {
+		q.mu.Lock()
+		conn, ready := q.conn, q.ready
+		q.mu.Unlock()
+
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.done:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// reconnectDelay function returns the delay before the reconnection attempt, which is doubled after each failure.
+func reconnectDelay(conf *Config, attempt int) time.Duration {
+	delay, maxDelay := conf.ReconnectDelay, conf.MaxReconnectDelay
+	if delay <= 0 {
+		delay = defaultReconnectDelay
+	}
+
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxReconnectDelay
+	}
+
+	return queue.Backoff(delay, maxDelay, attempt)
+}
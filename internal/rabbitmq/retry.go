@@ -0,0 +1,100 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// Prefix of the queues, where messages wait before the next attempt.
+	// There is a queue for each delay, expired messages are returned to the conversion queue.
+	retryQueuePrefix = "convert.retry."
+
+	// Name of the queue, where messages which couldn't be processed are kept.
+	deadLetterQueue = "convert.dead"
+
+	// Headers of the message with the amount of failed attempts and the last error.
+	attemptHeader = "x-attempt"
+	errorHeader   = "x-error"
+
+	// Time after which the unused retry queue is deleted, it's added to the delay.
+	retryQueueExpiration = time.Minute
+)
+
+// failedAttempts function returns the amount of the failed attempts to process the message.
+func failedAttempts(headers amqp.Table) int {
+	switch v := headers[attemptHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// republish function publishes the message to the queue with the attempt and the error headers
+// and acknowledges the original delivery after the broker confirms the published message.
+// If the message isn't published, the delivery is returned to the queue, so the message isn't lost.
+func republish(ctx context.Context, pub *confirmChannel, d *amqp.Delivery, queue string, attempt int, err error) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	headers[attemptHeader] = int32(attempt)
+	headers[errorHeader] = err.Error()
+
+	pubErr := pub.publish(ctx, queue, amqp.Publishing{
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType:  d.ContentType,
+		Priority:     d.Priority,
+		Body:         d.Body,
+	})
+	if pubErr != nil {
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			return fmt.Errorf("publish to %v: %w (return to the queue: %v)", queue, pubErr, nackErr)
+		}
+
+		return fmt.Errorf("publish to %v: %w", queue, pubErr)
+	}
+
+	return d.Ack(false)
+}
+
+// retry function moves the message to the retry queue, where it waits for the delay
+// and then returns to the conversion queue.
+func retry(ctx context.Context, pub *confirmChannel, d *amqp.Delivery, attempt int, delay time.Duration,
+	err error) error {
+	ms := delay.Milliseconds()
+	queue := retryQueuePrefix + strconv.FormatInt(ms, 10)
+
+	_, declErr := pub.ch.QueueDeclare(
+		queue,
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		amqp.Table{
+			"x-message-ttl":             ms,
+			"x-expires":                 (2*delay + retryQueueExpiration).Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		},
+	)
+	if declErr != nil {
+		return fmt.Errorf("failed to declare a retry queue: %w", declErr)
+	}
+
+	return republish(ctx, pub, d, queue, attempt, err)
+}
+
+// deadLetter function moves the message with the error to the dead-letter queue.
+func deadLetter(ctx context.Context, pub *confirmChannel, d *amqp.Delivery, attempt int, err error) error {
+	return republish(ctx, pub, d, deadLetterQueue, attempt, err)
+}
@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/lib/pq"
 )
 
 // ConvPostgres is a struct that provides methods to add image and update it's resolution int the sql.DB.
@@ -20,29 +22,75 @@ func NewConvPostgres(db *sql.DB) *ConvPostgres {
 }
 
 // GetConvInfo method returns all information about request from database.
+// Original image fields are empty for the requests made from several images.
+// For the requests made from the already stored image, the source image is returned as original.
 func (c *ConvPostgres) GetConvInfo(ctx context.Context, reqID int) (*model.ConvImageInfo, error) {
 	query := fmt.Sprintf(`SELECT 
-r.user_id, r.original_id, i.image_url, r.original_type, r.processed_type, r.ratio
+r.kind, r.user_id, COALESCE(r.original_id, r.source_id, 0), COALESCE(i.image_url, ''), COALESCE(r.original_type::text, ''),
+r.processed_type, r.ratio, r.options
 FROM
 %s as r
-INNER JOIN 
+LEFT JOIN 
 %s as i
-	ON r.original_id = i.id
+	ON COALESCE(r.original_id, r.source_id) = i.id
 WHERE 
 	r.id = $1`, RequestTable, ImageTable)
 
 	row := c.db.QueryRowContext(ctx, query, reqID)
 
-	var inf model.ConvImageInfo
+	var (
+		inf     model.ConvImageInfo
+		options []byte
+	)
 
-	err := row.Scan(&inf.UserID, &inf.OldImID, &inf.OldURL, &inf.OldType, &inf.NewType, &inf.Ratio)
+	err := row.Scan(&inf.Kind, &inf.UserID, &inf.OldImID, &inf.OldURL, &inf.OldType, &inf.NewType, &inf.Ratio, &options)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(options) != 0 {
+		if err := json.Unmarshal(options, &inf.Options); err != nil {
+			return nil, fmt.Errorf("unmarshal options: %w", err)
+		}
+	}
+
 	return &inf, nil
 }
 
+// GetImages method returns user's images with provided ids.
+// Returned map is keyed by the image id, images of the other users are not returned.
+func (c *ConvPostgres) GetImages(ctx context.Context, userID int,
+	imageIDs []int) (map[int]model.ReuquestImageInfo, error) {
+	query := fmt.Sprintf(`SELECT id, im_type, image_url FROM %s WHERE user_id = $1 AND id = ANY($2)`, ImageTable)
+
+	rows, err := c.db.QueryContext(ctx, query, userID, pq.Array(imageIDs))
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+	defer rows.Close()
+
+	images := make(map[int]model.ReuquestImageInfo, len(imageIDs))
+
+	for rows.Next() {
+		var (
+			id  int
+			img model.ReuquestImageInfo
+		)
+
+		if err := rows.Scan(&id, &img.Type, &img.URL); err != nil {
+			return nil, fmt.Errorf("repo: %w", err)
+		}
+
+		images[id] = img
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	return images, nil
+}
+
 // SetImageResolution method set image resolution to the image in images table.
 func (c *ConvPostgres) SetImageResolution(ctx context.Context, imID, width, height int) error {
 	query := fmt.Sprintf(`UPDATE %s 
@@ -59,13 +107,185 @@ func (c *ConvPostgres) SetImageResolution(ctx context.Context, imID, width, heig
 	return oneRowInResult(result)
 }
 
+// SetRequestCrop method saves information about the crop made while processing the request.
+func (c *ConvPostgres) SetRequestCrop(ctx context.Context, reqID int, crop *model.CropResult) error {
+	cropJSON, err := json.Marshal(crop)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET crop = $1 WHERE id = $2`, RequestTable)
+
+	result, err := c.db.ExecContext(ctx, query, cropJSON, reqID)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return oneRowInResult(result)
+}
+
+// StartProcessing method moves the queued request to the processing status and returns the number of this attempt.
+// Worker, which processes the request, and the start time are saved with the request,
+// the start time is the first heartbeat of the request. Returns IllegalTransitionError if the request is not queued.
+func (c *ConvPostgres) StartProcessing(ctx context.Context, reqID int, workerID string, t time.Time) (int, error) {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, worker_id = $2, start_time = $3, heartbeat_at = $3,
+		attempts = attempts + 1 WHERE id = $4 RETURNING attempts`, RequestTable)
+
+	var attempt int
+
+	err := c.db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := transitStatus(ctx, tx, reqID, StatusProcessing); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, query, StatusProcessing, workerID, t, reqID).Scan(&attempt)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("repo: %w", err)
+	}
+
+	return attempt, nil
+}
+
+// Heartbeat method saves the time, when the worker reported that it's still processing the request.
+// Returns NotSingleRowAffectedError if the request isn't processed by the worker anymore.
+func (c *ConvPostgres) Heartbeat(ctx context.Context, reqID int, workerID string, t time.Time) error {
+	query := fmt.Sprintf(`UPDATE %s SET heartbeat_at = $1 WHERE id = $2 AND op_status = $3 AND worker_id = $4`,
+		RequestTable)
+
+	result, err := c.db.ExecContext(ctx, query, t, reqID, StatusProcessing, workerID)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	if err := oneRowInResult(result); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseRequest method moves the request processed by the worker back to the queued status,
+// the attempt isn't counted. It's used, when the processing is interrupted by the shutdown of the worker.
+// Returns NotSingleRowAffectedError if the request isn't processed by the worker.
+func (c *ConvPostgres) ReleaseRequest(ctx context.Context, reqID int, workerID string) error {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, attempts = attempts - 1
+		WHERE id = $2 AND op_status = $3 AND worker_id = $4`, RequestTable)
+
+	result, err := c.db.ExecContext(ctx, query, StatusQueued, reqID, StatusProcessing, workerID)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	if err := oneRowInResult(result); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// RetryRequest method moves the request processed by the worker back to the queued status to be processed again.
+// The code and the message of the error of the last attempt are saved with the request.
+// Returns LostOwnershipError if the request isn't processed by the worker.
+func (c *ConvPostgres) RetryRequest(ctx context.Context, reqID int, workerID, code, message string) error {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, error_code = $2, error_message = $3
+		WHERE id = $4 AND op_status = $5 AND worker_id = $6`, RequestTable)
+
+	err := c.db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := transitStatus(ctx, tx, reqID, StatusQueued); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, query, StatusQueued, code, message, reqID, StatusProcessing, workerID)
+		if err != nil {
+			return err
+		}
+
+		return processedByWorker(result, reqID, workerID)
+	})
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// SetRequestFailed method sets failed status to the request processed by the worker with the code
+// and the message of the error. Time of the failure is saved as the completion time.
+// Returns IllegalTransitionError if the request is final
+// and LostOwnershipError if the request isn't processed by the worker.
+func (c *ConvPostgres) SetRequestFailed(ctx context.Context, reqID int, workerID, code, message string,
+	t time.Time) error {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, error_code = $2, error_message = $3, completion_time = $4
+		WHERE id = $5 AND op_status = $6 AND worker_id = $7`, RequestTable)
+
+	err := c.db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := transitStatus(ctx, tx, reqID, StatusFailed); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, query, StatusFailed, code, message, t, reqID, StatusProcessing, workerID)
+		if err != nil {
+			return err
+		}
+
+		return processedByWorker(result, reqID, workerID)
+	})
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// AddArtifacts method adds additional images of the request to the images table in transaction.
+// Artifacts added by the previous attempt to process the request are replaced.
+func (c *ConvPostgres) AddArtifacts(ctx context.Context, userID, reqID int, artifacts []model.Artifact) error {
+	deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE request_id = $1`, ImageTable)
+	query := fmt.Sprintf(`INSERT INTO %s (im_type, image_url, user_id, resoolution_x, resoolution_y,
+		request_id, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, ImageTable)
+
+	err := c.db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, deleteQuery, reqID); err != nil {
+			return err
+		}
+
+		for _, a := range artifacts {
+			_, err := tx.ExecContext(ctx, query, a.Type, a.URL, userID, a.Width, a.Height, reqID, a.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteArtifacts method deletes additional images of the request, which bundle isn't saved.
+func (c *ConvPostgres) DeleteArtifacts(ctx context.Context, reqID int) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE request_id = $1`, ImageTable)
+
+	if _, err := c.db.ExecContext(ctx, query, reqID); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
 // AddProcessedImage is a colmplex method that creates thransaction.
 // And in this transaction at first it add image to the images table.
 // Then it sets resolution of this image. After it add this image, processed time
 // to the requests table and updates request status.
-// Returns any error occurred in transaction or while creatring transaction.
-func (c *ConvPostgres) AddProcessedImage(ctx context.Context, userID, reqID int, imgInfo *model.ReuquestImageInfo,
-	width, height int, status string, t time.Time) error {
+// Returns any error occurred in transaction or while creatring transaction,
+// LostOwnershipError is returned if the request isn't processed by the worker.
+func (c *ConvPostgres) AddProcessedImage(ctx context.Context, userID, reqID int, workerID string,
+	imgInfo *model.ReuquestImageInfo, width, height int, status string, t time.Time) error {
 	err := c.db.inTx(ctx, func(tx *sql.Tx) error {
 		imID, err := addImageWithResolution(ctx, tx, userID, *imgInfo, width, height)
 		if err != nil {
@@ -82,7 +302,7 @@ func (c *ConvPostgres) AddProcessedImage(ctx context.Context, userID, reqID int,
 			return err
 		}
 
-		err = updateRequestStatus(ctx, tx, reqID, status)
+		err = updateRequestStatus(ctx, tx, reqID, workerID, status)
 		if err != nil {
 			return err
 		}
@@ -97,16 +317,23 @@ func (c *ConvPostgres) AddProcessedImage(ctx context.Context, userID, reqID int,
 	return nil
 }
 
-// updateRequestStatus function update status of an existing request in database.
-func updateRequestStatus(ctx context.Context, tx *sql.Tx, reqID int, status string) error {
-	query := fmt.Sprintf(`UPDATE %s SET op_status = $1 WHERE id = $2;`, RequestTable)
+// updateRequestStatus function update status of the request processed by the worker in database.
+// Returns IllegalTransitionError if the request can't be moved to the status
+// and LostOwnershipError if the request isn't processed by the worker.
+func updateRequestStatus(ctx context.Context, tx *sql.Tx, reqID int, workerID, status string) error {
+	if err := transitStatus(ctx, tx, reqID, status); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1 WHERE id = $2 AND op_status = $3 AND worker_id = $4;`,
+		RequestTable)
 
-	result, err := tx.ExecContext(ctx, query, status, reqID)
+	result, err := tx.ExecContext(ctx, query, status, reqID, StatusProcessing, workerID)
 	if err != nil {
 		return err
 	}
 
-	return oneRowInResult(result)
+	return processedByWorker(result, reqID, workerID)
 }
 
 // addProcessedImageIDToRequest function update processed image id column for the reqId.
@@ -125,9 +352,11 @@ func addProcessedImageIDToRequest(ctx context.Context, tx *sql.Tx, reqID, imageI
 // Returns id of this image.
 func addImageWithResolution(ctx context.Context, tx *sql.Tx, userID int,
 	imageInfo model.ReuquestImageInfo, width, height int) (int, error) {
-	query := fmt.Sprintf(`INSERT INTO %s (im_type, image_url, user_id, resoolution_x, resoolution_y)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id`, ImageTable)
-	row := tx.QueryRowContext(ctx, query, imageInfo.Type, imageInfo.URL, userID, width, height)
+	query := fmt.Sprintf(`INSERT INTO %s (im_type, image_url, user_id, resoolution_x, resoolution_y,
+		blurhash, preview)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, '')) RETURNING id`, ImageTable)
+	row := tx.QueryRowContext(ctx, query, imageInfo.Type, imageInfo.URL, userID, width, height,
+		imageInfo.BlurHash, imageInfo.Preview)
 
 	var imageID int
 	if err := row.Scan(&imageID); err != nil {
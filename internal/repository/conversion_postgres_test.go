@@ -28,10 +28,11 @@ func NewConvMock(t *testing.T) (*repository.ConvPostgres, sqlmock.Sqlmock) {
 }
 
 var addImageWithResolutionQuery = regexp.QuoteMeta(fmt.Sprintf(`INSERT INTO %s 
-(im_type, image_url, user_id, resoolution_x, resoolution_y)
-VALUES ($1, $2, $3, $4, $5) RETURNING id`, repository.ImageTable))
+(im_type, image_url, user_id, resoolution_x, resoolution_y, blurhash, preview)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, '')) RETURNING id`, repository.ImageTable))
 var updateRequestStatusQuery = fmt.Sprintf(`UPDATE %s SET op_status = .+ 
 WHERE id = .+`, repository.RequestTable)
+var selectStatusQuery = fmt.Sprintf(`SELECT op_status FROM %s WHERE id = .+ FOR UPDATE`, repository.RequestTable)
 var addProcessedIDQuery = fmt.Sprintf(`UPDATE %s SET processed_id = .+ 
 WHERE id = .+`, repository.RequestTable)
 var addProcessedTimeQuery = fmt.Sprintf(`UPDATE %s SET completion_time = .+ 
@@ -46,16 +47,17 @@ var (
 
 func TestConvPostgres_AddImageDB(t *testing.T) {
 	testCases := []struct {
-		testName string
-		userID   int
-		reqID    int
-		imgInfo  *model.ReuquestImageInfo
-		status   string
-		width    int
-		height   int
-		time     time.Time
-		initMock func(sqlmock.Sqlmock, int, int, *model.ReuquestImageInfo, int, int, string, time.Time) sqlmock.Sqlmock
-		wantErr  error
+		testName  string
+		userID    int
+		reqID     int
+		imgInfo   *model.ReuquestImageInfo
+		status    string
+		width     int
+		height    int
+		time      time.Time
+		initMock  func(sqlmock.Sqlmock, int, int, *model.ReuquestImageInfo, int, int, string, time.Time) sqlmock.Sqlmock
+		wantErr   error
+		wantErrAs interface{}
 	}{
 		{
 			testName: "all is good",
@@ -73,12 +75,15 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 				imageRow := RepoReturnID(imageID)
 				mock.ExpectBegin()
 				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
-					user, width, height).WillReturnRows(imageRow)
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
 				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(addProcessedTimeQuery).WithArgs(t, req).
 					WillReturnResult(sqlmock.NewResult(0, 1))
-				mock.ExpectExec(updateRequestStatusQuery).WithArgs(repository.StatusDone, req).
+				mock.ExpectQuery(selectStatusQuery).WithArgs(req).
+					WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(repository.StatusProcessing))
+				mock.ExpectExec(updateRequestStatusQuery).
+					WithArgs(repository.StatusDone, req, repository.StatusProcessing, "converter-1").
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit()
 				return mock
@@ -101,18 +106,80 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 				imageRow := RepoReturnID(imageID)
 				mock.ExpectBegin()
 				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
-					user, width, height).WillReturnRows(imageRow)
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
 				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(addProcessedTimeQuery).WithArgs(t, req).
 					WillReturnResult(sqlmock.NewResult(0, 1))
-				mock.ExpectExec(updateRequestStatusQuery).WithArgs(repository.StatusDone, req).
+				mock.ExpectQuery(selectStatusQuery).WithArgs(req).
+					WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(repository.StatusProcessing))
+				mock.ExpectExec(updateRequestStatusQuery).
+					WithArgs(repository.StatusDone, req, repository.StatusProcessing, "converter-1").
 					WillReturnResult(sqlmock.NewErrorResult(errUpdateStatus))
 				mock.ExpectRollback()
 				return mock
 			},
 			wantErr: errUpdateStatus,
 		},
+		{
+			testName: "request is processed by another worker",
+			userID:   2,
+			reqID:    3,
+			imgInfo: &model.ReuquestImageInfo{
+				Type: "jpeg",
+				URL:  "image url",
+			},
+			status: repository.StatusDone,
+			time:   time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{}),
+			initMock: func(mock sqlmock.Sqlmock, user, req int, imgInfo *model.ReuquestImageInfo,
+				width, height int, status string, t time.Time) sqlmock.Sqlmock {
+				imageID := 32
+				imageRow := RepoReturnID(imageID)
+				mock.ExpectBegin()
+				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
+				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(addProcessedTimeQuery).WithArgs(t, req).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectStatusQuery).WithArgs(req).
+					WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(repository.StatusProcessing))
+				mock.ExpectExec(updateRequestStatusQuery).
+					WithArgs(repository.StatusDone, req, repository.StatusProcessing, "converter-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+				return mock
+			},
+			wantErrAs: new(*repository.LostOwnershipError),
+		},
+		{
+			testName: "request is already done",
+			userID:   2,
+			reqID:    3,
+			imgInfo: &model.ReuquestImageInfo{
+				Type: "jpeg",
+				URL:  "image url",
+			},
+			status: repository.StatusDone,
+			time:   time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{}),
+			initMock: func(mock sqlmock.Sqlmock, user, req int, imgInfo *model.ReuquestImageInfo,
+				width, height int, status string, t time.Time) sqlmock.Sqlmock {
+				imageID := 32
+				imageRow := RepoReturnID(imageID)
+				mock.ExpectBegin()
+				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
+				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(addProcessedTimeQuery).WithArgs(t, req).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(selectStatusQuery).WithArgs(req).
+					WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(repository.StatusDone))
+				mock.ExpectRollback()
+				return mock
+			},
+			wantErrAs: new(*repository.IllegalTransitionError),
+		},
 		{
 			testName: "error at add time",
 			userID:   2,
@@ -129,7 +196,7 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 				imageRow := RepoReturnID(imageID)
 				mock.ExpectBegin()
 				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
-					user, width, height).WillReturnRows(imageRow)
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
 				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(addProcessedTimeQuery).WithArgs(t, req).
@@ -155,7 +222,7 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 				imageRow := RepoReturnID(imageID)
 				mock.ExpectBegin()
 				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
-					user, width, height).WillReturnRows(imageRow)
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnRows(imageRow)
 				mock.ExpectExec(addProcessedIDQuery).WithArgs(imageID, req).
 					WillReturnResult(sqlmock.NewErrorResult(errAddProcessedID))
 				mock.ExpectRollback()
@@ -177,7 +244,7 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 				width, height int, status string, t time.Time) sqlmock.Sqlmock {
 				mock.ExpectBegin()
 				mock.ExpectQuery(addImageWithResolutionQuery).WithArgs(imgInfo.Type, imgInfo.URL,
-					user, width, height).WillReturnError(errAddImageToDB)
+					user, width, height, imgInfo.BlurHash, imgInfo.Preview).WillReturnError(errAddImageToDB)
 				mock.ExpectRollback()
 				return mock
 			},
@@ -191,9 +258,271 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 			mock = tc.initMock(mock, tc.userID, tc.reqID,
 				tc.imgInfo, tc.width, tc.height, tc.status, tc.time)
 
-			err := repo.AddProcessedImage(context.Background(), tc.userID, tc.reqID, tc.imgInfo,
+			err := repo.AddProcessedImage(context.Background(), tc.userID, tc.reqID, "converter-1", tc.imgInfo,
 				tc.width, tc.height, tc.status, tc.time)
 
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvPostgres_SetRequestFailed(t *testing.T) {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = .+, error_code = .+, error_message = .+, completion_time = .+
+		WHERE id = .+ AND op_status = .+ AND worker_id = .+`, repository.RequestTable)
+	failTime := time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{})
+
+	testCases := []struct {
+		testName   string
+		fromStatus string
+		affected   int64
+		execErr    error
+		wantErr    error
+		wantErrAs  interface{}
+	}{
+		{
+			testName:   "processing request",
+			fromStatus: repository.StatusProcessing,
+			affected:   1,
+		},
+		{
+			testName:   "request is reaped",
+			fromStatus: repository.StatusQueued,
+			wantErrAs:  new(*repository.LostOwnershipError),
+		},
+		{
+			testName:   "request is processed by another worker",
+			fromStatus: repository.StatusProcessing,
+			wantErrAs:  new(*repository.LostOwnershipError),
+		},
+		{
+			testName:   "done request",
+			fromStatus: repository.StatusDone,
+			wantErrAs:  new(*repository.IllegalTransitionError),
+		},
+		{
+			testName:   "error in db",
+			fromStatus: repository.StatusProcessing,
+			execErr:    errUpdateStatus,
+			wantErr:    errUpdateStatus,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectStatusQuery).WithArgs(12).
+				WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(tc.fromStatus))
+
+			exec := func() *sqlmock.ExpectedExec {
+				return mock.ExpectExec(query).WithArgs(repository.StatusFailed, "storage_error", "file is lost", failTime, 12,
+					repository.StatusProcessing, "converter-1")
+			}
+
+			switch {
+			case tc.fromStatus == repository.StatusDone:
+				mock.ExpectRollback()
+			case tc.execErr != nil:
+				exec().WillReturnError(tc.execErr)
+				mock.ExpectRollback()
+			case tc.affected == 0:
+				exec().WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			default:
+				exec().WillReturnResult(sqlmock.NewResult(0, tc.affected))
+				mock.ExpectCommit()
+			}
+
+			err := repo.SetRequestFailed(context.Background(), 12, "converter-1", "storage_error", "file is lost",
+				failTime)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvPostgres_StartProcessing(t *testing.T) {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = .+, worker_id = .+, start_time = .+, heartbeat_at = .+,
+		attempts = attempts \+ 1 WHERE id = .+ RETURNING attempts`, repository.RequestTable)
+	startTime := time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{})
+
+	testCases := []struct {
+		testName    string
+		fromStatus  string
+		wantAttempt int
+		wantErrAs   interface{}
+	}{
+		{
+			testName:    "queued request",
+			fromStatus:  repository.StatusQueued,
+			wantAttempt: 2,
+		},
+		{
+			testName:   "request is already processing",
+			fromStatus: repository.StatusProcessing,
+			wantErrAs:  new(*repository.IllegalTransitionError),
+		},
+		{
+			testName:   "done request",
+			fromStatus: repository.StatusDone,
+			wantErrAs:  new(*repository.IllegalTransitionError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectStatusQuery).WithArgs(12).
+				WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(tc.fromStatus))
+
+			if tc.wantErrAs != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectQuery(query).WithArgs(repository.StatusProcessing, "converter-1", startTime, 12).
+					WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(2))
+				mock.ExpectCommit()
+			}
+
+			attempt, err := repo.StartProcessing(context.Background(), 12, "converter-1", startTime)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantAttempt, attempt)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvPostgres_RetryRequest(t *testing.T) {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = .+, error_code = .+, error_message = .+
+		WHERE id = .+ AND op_status = .+ AND worker_id = .+`, repository.RequestTable)
+
+	testCases := []struct {
+		testName   string
+		fromStatus string
+		affected   int64
+		wantErrAs  interface{}
+	}{
+		{
+			testName:   "processing request",
+			fromStatus: repository.StatusProcessing,
+			affected:   1,
+		},
+		{
+			testName:   "request is processed by another worker",
+			fromStatus: repository.StatusProcessing,
+			wantErrAs:  new(*repository.LostOwnershipError),
+		},
+		{
+			testName:   "failed request",
+			fromStatus: repository.StatusFailed,
+			wantErrAs:  new(*repository.IllegalTransitionError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectStatusQuery).WithArgs(12).
+				WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(tc.fromStatus))
+
+			switch {
+			case tc.fromStatus == repository.StatusFailed:
+				mock.ExpectRollback()
+			case tc.affected == 0:
+				mock.ExpectExec(query).WithArgs(repository.StatusQueued, "storage_error", "file is lost", 12,
+					repository.StatusProcessing, "converter-1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			default:
+				mock.ExpectExec(query).WithArgs(repository.StatusQueued, "storage_error", "file is lost", 12,
+					repository.StatusProcessing, "converter-1").WillReturnResult(sqlmock.NewResult(0, tc.affected))
+				mock.ExpectCommit()
+			}
+
+			err := repo.RetryRequest(context.Background(), 12, "converter-1", "storage_error", "file is lost")
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvPostgres_AddArtifacts(t *testing.T) {
+	deleteQuery := regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE request_id = $1`, repository.ImageTable))
+	insertQuery := fmt.Sprintf(`INSERT INTO %s \(im_type, image_url, user_id, resoolution_x, resoolution_y,
+		request_id, name\)`, repository.ImageTable)
+
+	artifacts := []model.Artifact{
+		{Name: "apple-touch-icon", Type: "png", Width: 180, Height: 180, URL: "processed/12/apple-touch-icon.png"},
+	}
+
+	testCases := []struct {
+		testName  string
+		insertErr error
+		wantErr   error
+	}{
+		{
+			testName: "previous artifacts are replaced",
+		},
+		{
+			testName:  "error in insert",
+			insertErr: errAddImageToDB,
+			wantErr:   errAddImageToDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(deleteQuery).WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(insertQuery).WithArgs("png", "processed/12/apple-touch-icon.png", 1, 180, 180, 12,
+				"apple-touch-icon").WillReturnResult(sqlmock.NewResult(1, 1)).WillReturnError(tc.insertErr)
+
+			if tc.wantErr != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			err := repo.AddArtifacts(context.Background(), 1, 12, artifacts)
+
 			assert.ErrorIs(t, err, tc.wantErr)
 
 			if err := mock.ExpectationsWereMet(); err != nil {
@@ -202,3 +531,119 @@ func TestConvPostgres_AddImageDB(t *testing.T) {
 		})
 	}
 }
+
+func TestConvPostgres_DeleteArtifacts(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM %s WHERE request_id = $1`, repository.ImageTable))
+
+	testCases := []struct {
+		testName  string
+		deleteErr error
+		wantErr   error
+	}{
+		{
+			testName: "all is good",
+		},
+		{
+			testName:  "error in delete",
+			deleteErr: errAddImageToDB,
+			wantErr:   errAddImageToDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectExec(query).WithArgs(12).WillReturnResult(sqlmock.NewResult(0, 3)).WillReturnError(tc.deleteErr)
+
+			err := repo.DeleteArtifacts(context.Background(), 12)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvPostgres_Heartbeat(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(
+		`UPDATE %s SET heartbeat_at = $1 WHERE id = $2 AND op_status = $3 AND worker_id = $4`, repository.RequestTable))
+	heartbeatTime := time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{})
+
+	testCases := []struct {
+		testName     string
+		rowsAffected int64
+		wantErrAs    interface{}
+	}{
+		{
+			testName:     "all is good",
+			rowsAffected: 1,
+		},
+		{
+			testName:     "request is not processed by the worker",
+			rowsAffected: 0,
+			wantErrAs:    new(*repository.NotSingleRowAffectedError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectExec(query).WithArgs(heartbeatTime, 12, repository.StatusProcessing, "converter-1").
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+
+			err := repo.Heartbeat(context.Background(), 12, "converter-1", heartbeatTime)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestConvPostgres_ReleaseRequest(t *testing.T) {
+	query := regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET op_status = $1, attempts = attempts - 1
+		WHERE id = $2 AND op_status = $3 AND worker_id = $4`, repository.RequestTable))
+
+	testCases := []struct {
+		testName     string
+		rowsAffected int64
+		wantErrAs    interface{}
+	}{
+		{
+			testName:     "all is good",
+			rowsAffected: 1,
+		},
+		{
+			testName:     "request is not processed by the worker",
+			rowsAffected: 0,
+			wantErrAs:    new(*repository.NotSingleRowAffectedError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewConvMock(t)
+
+			mock.ExpectExec(query).WithArgs(repository.StatusQueued, 12, repository.StatusProcessing, "converter-1").
+				WillReturnResult(sqlmock.NewResult(0, tc.rowsAffected))
+
+			err := repo.ReleaseRequest(context.Background(), 12, "converter-1")
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/Dyleme/image-coverter/internal/model"
 )
 
 // DownloadPostgres is a struct that provide method to get image url from the sql.DB.
@@ -29,3 +32,55 @@ func (d *DownloadPostgres) GetImageURL(ctx context.Context, userID, imageID int)
 
 	return urlImage, nil
 }
+
+// GetImage function gets type, url and resolution of the user's image from the database.
+// Unknown resolution is returned as zero.
+func (d *DownloadPostgres) GetImage(ctx context.Context, userID, imageID int) (*model.ReuquestImageInfo, error) {
+	query := fmt.Sprintf(`SELECT im_type, image_url, COALESCE(resoolution_x, 0), COALESCE(resoolution_y, 0)
+	FROM %s WHERE user_id = $1 AND id = $2`, ImageTable)
+	row := d.db.QueryRowContext(ctx, query, userID, imageID)
+
+	var img model.ReuquestImageInfo
+
+	if err := row.Scan(&img.Type, &img.URL, &img.Width, &img.Height); err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	return &img, nil
+}
+
+// GetDerivativeURL function gets url of the image derived from the image with provided parameters.
+// Returns empty string if there is no such derived image.
+func (d *DownloadPostgres) GetDerivativeURL(ctx context.Context, imageID int, params string) (string, error) {
+	query := fmt.Sprintf(`SELECT image_url FROM %s WHERE image_id = $1 AND params = $2`, DerivativeTable)
+	row := d.db.QueryRowContext(ctx, query, imageID, params)
+
+	var url string
+
+	if err := row.Scan(&url); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("repo: %w", err)
+	}
+
+	return url, nil
+}
+
+// AddDerivative function adds to the database url of the image derived from the image with provided parameters.
+func (d *DownloadPostgres) AddDerivative(ctx context.Context, imageID int, params, url string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (image_id, params, image_url) VALUES ($1, $2, $3)
+		ON CONFLICT (image_id, params) DO UPDATE SET image_url = EXCLUDED.image_url`, DerivativeTable)
+
+	result, err := d.db.ExecContext(ctx, query, imageID, params, url)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	if err := oneRowInResult(result); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
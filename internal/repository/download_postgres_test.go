@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dyleme/image-coverter/internal/model"
 	"github.com/Dyleme/image-coverter/internal/repository"
 	"github.com/stretchr/testify/assert"
 )
@@ -67,3 +68,152 @@ func TestDownloadPostgres_GetImageUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadPostgres_GetImage(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		userID    int
+		imageID   int
+		repoImage *model.ReuquestImageInfo
+		wantErr   error
+	}{
+		{
+			testName:  "all is good",
+			userID:    12,
+			imageID:   19,
+			repoImage: &model.ReuquestImageInfo{Type: "png", URL: "url to image", Width: 30, Height: 20},
+			wantErr:   nil,
+		},
+		{
+			testName:  "no such row in db",
+			userID:    12,
+			imageID:   19,
+			repoImage: nil,
+			wantErr:   sql.ErrNoRows,
+		},
+	}
+
+	query := fmt.Sprintf(`SELECT im_type, image_url, COALESCE\(resoolution_x, 0\), COALESCE\(resoolution_y, 0\)
+	FROM %s WHERE user_id = .+ AND id = .+`, repository.ImageTable)
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+
+			repo := repository.NewDownloadPostgres(db)
+
+			rows := sqlmock.NewRows([]string{"im_type", "image_url", "resoolution_x", "resoolution_y"})
+			if tc.repoImage != nil {
+				rows = rows.AddRow(tc.repoImage.Type, tc.repoImage.URL, tc.repoImage.Width, tc.repoImage.Height)
+			}
+
+			mock.ExpectQuery(query).WithArgs(tc.userID, tc.imageID).WillReturnRows(rows)
+
+			gotImage, gotErr := repo.GetImage(context.Background(), tc.userID, tc.imageID)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.repoImage, gotImage)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDownloadPostgres_GetDerivativeURL(t *testing.T) {
+	testCases := []struct {
+		testName string
+		imageID  int
+		params   string
+		repoURL  string
+		repoErr  error
+		wantURL  string
+		wantErr  error
+	}{
+		{
+			testName: "all is good",
+			imageID:  19,
+			params:   "300x200_fill_q80.jpg",
+			repoURL:  "url to derivative",
+			wantURL:  "url to derivative",
+			wantErr:  nil,
+		},
+		{
+			testName: "derivative is not cached",
+			imageID:  19,
+			params:   "300x200_fill_q80.jpg",
+			repoURL:  "",
+			wantURL:  "",
+			wantErr:  nil,
+		},
+		{
+			testName: "error in db",
+			imageID:  19,
+			params:   "300x200_fill_q80.jpg",
+			repoErr:  sql.ErrConnDone,
+			wantURL:  "",
+			wantErr:  sql.ErrConnDone,
+		},
+	}
+
+	query := fmt.Sprintf("SELECT image_url FROM %s WHERE image_id = .+ AND params = .+", repository.DerivativeTable)
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+
+			repo := repository.NewDownloadPostgres(db)
+
+			rows := sqlmock.NewRows([]string{"image_url"})
+			if tc.repoURL != "" {
+				rows = rows.AddRow(tc.repoURL)
+			}
+
+			expect := mock.ExpectQuery(query).WithArgs(tc.imageID, tc.params)
+			if tc.repoErr != nil {
+				expect.WillReturnError(tc.repoErr)
+			} else {
+				expect.WillReturnRows(rows)
+			}
+
+			gotURL, gotErr := repo.GetDerivativeURL(context.Background(), tc.imageID, tc.params)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantURL, gotURL)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were fulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestDownloadPostgres_AddDerivative(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	repo := repository.NewDownloadPostgres(db)
+
+	query := fmt.Sprintf(`INSERT INTO %s \(image_id, params, image_url\) VALUES \(.+\)
+		ON CONFLICT \(image_id, params\) DO UPDATE SET image_url = EXCLUDED.image_url`, repository.DerivativeTable)
+
+	mock.ExpectExec(query).WithArgs(19, "300x200_fill_q80.jpg", "url to derivative").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = repo.AddDerivative(context.Background(), 19, "300x200_fill_q80.jpg", "url to derivative")
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were fulfilled expectations: %s", err)
+	}
+}
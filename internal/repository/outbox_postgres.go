@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/lib/pq"
+)
+
+// OutboxPostgres is a struct that provides method to relay messages from the outbox to the queue.
+type OutboxPostgres struct {
+	db *TxDB
+}
+
+// NewOutboxPostgres is a constructor for the OutboxPostgres.
+func NewOutboxPostgres(db *sql.DB) *OutboxPostgres {
+	return &OutboxPostgres{db: &TxDB{db}}
+}
+
+// addOutboxMessage function adds the message to process the user's request to the outbox.
+func addOutboxMessage(ctx context.Context, tx *sql.Tx, userID int, msg *model.RequestToProcess) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (request_id, user_id, priority, payload) VALUES ($1, $2, $3, $4)`,
+		OutboxTable)
+
+	if _, err := tx.ExecContext(ctx, query, msg.ReqID, userID, msg.Priority, payload); err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+
+	return nil
+}
+
+// RelayMessages method sends up to limit pending messages from the outbox and marks them sent.
+// Interactive messages are sent before the bulk ones. To share the workers between users,
+// messages of the different users are interleaved and the user can't have more than userLimit
+// requests sent to the queue, but not processed yet. The rest of the user's messages wait in the outbox.
+// Queued requests are counted only for inFlightTimeout after their messages are sent,
+// so the requests, which messages are lost by the queue, don't block the user forever.
+// Messages are locked till the end of the transaction, so the same message isn't sent by several relays.
+// Sending is stopped at the first error, but messages sent before it are marked.
+// Returns the amount of the sent messages.
+func (o *OutboxPostgres) RelayMessages(ctx context.Context, limit, userLimit int, inFlightTimeout time.Duration,
+	send func(context.Context, *model.RequestToProcess) error) (int, error) {
+	var (
+		sentIDs []int64
+		sendErr error
+	)
+
+	err := o.db.inTx(ctx, func(tx *sql.Tx) error {
+		query := fmt.Sprintf(`WITH in_flight AS (
+	SELECT r.user_id, count(*) AS n FROM %[1]s AS r
+	WHERE (r.op_status = $4 OR r.op_status = $3 AND EXISTS (SELECT 1 FROM %[2]s AS s
+		WHERE s.request_id = r.id AND s.sent_at > CURRENT_TIMESTAMP - $5 * INTERVAL '1 millisecond'))
+	AND NOT EXISTS (SELECT 1 FROM %[2]s AS p WHERE p.request_id = r.id AND p.sent_at IS NULL)
+	GROUP BY r.user_id
+), pending AS (
+	SELECT o.id, o.priority,
+	COALESCE(f.n, 0) + row_number() OVER (PARTITION BY o.user_id ORDER BY o.priority, o.id) AS slot
+	FROM %[2]s AS o LEFT JOIN in_flight AS f ON f.user_id = o.user_id
+	WHERE o.sent_at IS NULL
+)
+SELECT o.id, o.payload FROM %[2]s AS o JOIN pending AS p ON p.id = o.id
+WHERE p.slot <= $2
+ORDER BY p.priority, p.slot, o.id LIMIT $1 FOR UPDATE OF o SKIP LOCKED`, RequestTable, OutboxTable)
+
+		rows, err := tx.QueryContext(ctx, query, limit, userLimit, StatusQueued, StatusProcessing,
+			inFlightTimeout.Milliseconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var (
+			ids      []int64
+			messages []model.RequestToProcess
+		)
+
+		for rows.Next() {
+			var (
+				id      int64
+				payload []byte
+				msg     model.RequestToProcess
+			)
+
+			if err := rows.Scan(&id, &payload); err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				return fmt.Errorf("unmarshal message %v: %w", id, err)
+			}
+
+			ids = append(ids, id)
+			messages = append(messages, msg)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range messages {
+			if sendErr = send(ctx, &messages[i]); sendErr != nil {
+				break
+			}
+
+			sentIDs = append(sentIDs, ids[i])
+		}
+
+		if len(sentIDs) == 0 {
+			return nil
+		}
+
+		query = fmt.Sprintf(`UPDATE %s SET sent_at = CURRENT_TIMESTAMP WHERE id = ANY($1)`, OutboxTable)
+		_, err = tx.ExecContext(ctx, query, pq.Array(sentIDs))
+
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("repo: %w", err)
+	}
+
+	if sendErr != nil {
+		return len(sentIDs), fmt.Errorf("send message: %w", sendErr)
+	}
+
+	return len(sentIDs), nil
+}
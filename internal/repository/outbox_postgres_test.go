@@ -0,0 +1,144 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	selectOutboxQuery = fmt.Sprintf(`WITH in_flight AS .+ FROM %[1]s AS r .+ SELECT o.id, o.payload FROM %[2]s AS o
+JOIN pending AS p ON p.id = o.id WHERE p.slot <= .+
+ORDER BY p.priority, p.slot, o.id LIMIT .+ FOR UPDATE OF o SKIP LOCKED`,
+		repository.RequestTable, repository.OutboxTable)
+	markOutboxSentQuery = regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET sent_at = CURRENT_TIMESTAMP WHERE id = ANY($1)`,
+		repository.OutboxTable))
+)
+
+var (
+	errSend       = errors.New("send error")
+	errOutboxRepo = errors.New("outbox repo error")
+)
+
+func NewOutboxMock(t *testing.T) (*repository.OutboxPostgres, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return repository.NewOutboxPostgres(db), mock
+}
+
+func TestOutboxPostgres_RelayMessages(t *testing.T) {
+	outboxRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "payload"}).
+			AddRow(4, []byte(`{"reqID":12,"fileName":"a.png"}`)).
+			AddRow(5, []byte(`{"reqID":13,"fileName":"b.png"}`))
+	}
+
+	testCases := []struct {
+		testName string
+		sendErrs map[int]error
+		initMock func(sqlmock.Sqlmock)
+		wantSent []model.RequestToProcess
+		wantN    int
+		wantErr  error
+	}{
+		{
+			testName: "all is good",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectOutboxQuery).WithArgs(10, 3, repository.StatusQueued, repository.StatusProcessing, int64(60000)).
+					WillReturnRows(outboxRows())
+				mock.ExpectExec(markOutboxSentQuery).WithArgs(pq.Array([]int64{4, 5})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			wantSent: []model.RequestToProcess{{ReqID: 12, FileName: "a.png"}, {ReqID: 13, FileName: "b.png"}},
+			wantN:    2,
+		},
+		{
+			testName: "outbox is empty",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectOutboxQuery).WithArgs(10, 3, repository.StatusQueued, repository.StatusProcessing, int64(60000)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			testName: "second message is not sent",
+			sendErrs: map[int]error{13: errSend},
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectOutboxQuery).WithArgs(10, 3, repository.StatusQueued, repository.StatusProcessing, int64(60000)).
+					WillReturnRows(outboxRows())
+				mock.ExpectExec(markOutboxSentQuery).WithArgs(pq.Array([]int64{4})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantSent: []model.RequestToProcess{{ReqID: 12, FileName: "a.png"}},
+			wantN:    1,
+			wantErr:  errSend,
+		},
+		{
+			testName: "error in select",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectOutboxQuery).WithArgs(10, 3, repository.StatusQueued, repository.StatusProcessing, int64(60000)).
+					WillReturnError(errOutboxRepo)
+				mock.ExpectRollback()
+			},
+			wantErr: errOutboxRepo,
+		},
+		{
+			testName: "sent messages are not marked",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectOutboxQuery).WithArgs(10, 3, repository.StatusQueued, repository.StatusProcessing, int64(60000)).
+					WillReturnRows(outboxRows())
+				mock.ExpectExec(markOutboxSentQuery).WillReturnError(errOutboxRepo)
+				mock.ExpectRollback()
+			},
+			wantSent: []model.RequestToProcess{{ReqID: 12, FileName: "a.png"}, {ReqID: 13, FileName: "b.png"}},
+			wantErr:  errOutboxRepo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewOutboxMock(t)
+			tc.initMock(mock)
+
+			var gotSent []model.RequestToProcess
+
+			send := func(_ context.Context, msg *model.RequestToProcess) error {
+				if err := tc.sendErrs[msg.ReqID]; err != nil {
+					return err
+				}
+
+				gotSent = append(gotSent, *msg)
+
+				return nil
+			}
+
+			gotN, gotErr := repo.RelayMessages(context.Background(), 10, 3, time.Minute, send)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantN, gotN)
+			assert.Equal(t, tc.wantSent, gotSent)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
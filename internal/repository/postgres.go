@@ -12,12 +12,36 @@ const (
 	UsersTable   = "users"
 	RequestTable = "requests"
 	ImageTable   = "images"
+
+	DerivativeTable = "derivatives"
+	OutboxTable     = "outbox"
 )
 
 const (
 	StatusQueued     = `queued`
 	StatusProcessing = `processing`
 	StatusDone       = `done`
+	StatusFailed     = `failed`
+	StatusCancelled  = `cancelled`
+)
+
+// statusTransitions are the statuses, to which the request could be moved from the key status.
+// Processing request is queued again to be retried. Done, failed and cancelled requests are final.
+var statusTransitions = map[string][]string{
+	StatusQueued:     {StatusProcessing, StatusFailed, StatusCancelled},
+	StatusProcessing: {StatusDone, StatusFailed, StatusQueued, StatusCancelled},
+}
+
+const (
+	KindConversion   = `conversion`
+	KindContactSheet = `contact_sheet`
+	KindPDF          = `pdf`
+)
+
+// Priorities of the requests. Interactive requests are processed before the bulk ones.
+const (
+	PriorityInteractive = `interactive`
+	PriorityBulk        = `bulk`
 )
 
 // Config to connect to the database.
@@ -65,6 +89,56 @@ func (e *NotSingleRowAffectedError) Error() string {
 	return fmt.Sprintf("expected single row affected, got %v rows affected", e.amountAffected)
 }
 
+// LostOwnershipError is returned if the request isn't processed by the worker anymore.
+// It happens, when the request is reaped and queued again or processed by another worker.
+type LostOwnershipError struct {
+	ReqID    int
+	WorkerID string
+}
+
+func (e *LostOwnershipError) Error() string {
+	return fmt.Sprintf("request %v isn't processed by the worker %q anymore", e.ReqID, e.WorkerID)
+}
+
+// IllegalTransitionError is returned if the request can't be moved from its status to the new one.
+type IllegalTransitionError struct {
+	ReqID int
+	From  string
+	To    string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("request %v can't be moved from %q to %q status", e.ReqID, e.From, e.To)
+}
+
+// canTransit function checks if the request could be moved from one status to another.
+func canTransit(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transitStatus function locks the request till the end of the transaction and checks
+// that its status could be changed to the new one. Returns IllegalTransitionError if no.
+func transitStatus(ctx context.Context, tx *sql.Tx, reqID int, to string) error {
+	query := fmt.Sprintf(`SELECT op_status FROM %s WHERE id = $1 FOR UPDATE`, RequestTable)
+
+	var from string
+	if err := tx.QueryRowContext(ctx, query, reqID).Scan(&from); err != nil {
+		return err
+	}
+
+	if !canTransit(from, to) {
+		return &IllegalTransitionError{ReqID: reqID, From: from, To: to}
+	}
+
+	return nil
+}
+
 // oneRowInResult is a function check if result has one row,
 // returns NotSingleRowAffectedError if no.
 func oneRowInResult(result sql.Result) error {
@@ -80,6 +154,25 @@ func oneRowInResult(result sql.Result) error {
 	return nil
 }
 
+// processedByWorker function checks that the request processed by the worker is updated,
+// returns LostOwnershipError if no rows are affected.
+func processedByWorker(result sql.Result, reqID int, workerID string) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return &LostOwnershipError{ReqID: reqID, WorkerID: workerID}
+	}
+
+	if rows != 1 {
+		return &NotSingleRowAffectedError{int(rows)}
+	}
+
+	return nil
+}
+
 // inTx is method which allows you to make queries in transaction.
 func (db *TxDB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	tx, err := db.BeginTx(ctx, nil)
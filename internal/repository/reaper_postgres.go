@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// reaperLockKey is the key of the advisory lock, which is held by the acting reaper.
+const reaperLockKey = 0x52454150
+
+// ReaperPostgres is a struct that provides method to reap the requests abandoned by the workers.
+type ReaperPostgres struct {
+	db *TxDB
+}
+
+// NewReaperPostgres is a constructor for the ReaperPostgres.
+func NewReaperPostgres(db *sql.DB) *ReaperPostgres {
+	return &ReaperPostgres{db: &TxDB{db}}
+}
+
+type stuckRequest struct {
+	id       int
+	attempts int
+	workerID string
+}
+
+// ReapRequests method finds the processing requests with the last heartbeat before expiredBefore.
+// Requests with attempts left are queued again and the message to process them is added to the outbox,
+// other requests get failed status. The code and the message of the error are saved with the requests.
+// Reaping is made under the advisory lock, so only one reaper acts at a time,
+// if the lock is held by the other reaper, result isn't leader and nothing is reaped.
+func (r *ReaperPostgres) ReapRequests(ctx context.Context, expiredBefore time.Time, maxAttempts int,
+	code string, t time.Time) (*model.ReapResult, error) {
+	var result model.ReapResult
+
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`,
+			reaperLockKey).Scan(&result.Leader); err != nil {
+			return err
+		}
+
+		if !result.Leader {
+			return nil
+		}
+
+		stuck, err := selectStuckRequests(ctx, tx, expiredBefore)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range stuck {
+			message := fmt.Sprintf("heartbeat of worker %q expired at attempt %v", s.workerID, s.attempts)
+
+			if s.attempts < maxAttempts {
+				if err := requeueRequest(ctx, tx, s.id, code, message); err != nil {
+					return err
+				}
+
+				result.Requeued = append(result.Requeued, s.id)
+
+				continue
+			}
+
+			if err := failRequest(ctx, tx, s.id, code, message, t); err != nil {
+				return err
+			}
+
+			result.Failed = append(result.Failed, s.id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	return &result, nil
+}
+
+// selectStuckRequests function returns the processing requests with the last heartbeat before expiredBefore.
+// Requests are locked till the end of the transaction.
+func selectStuckRequests(ctx context.Context, tx *sql.Tx, expiredBefore time.Time) ([]stuckRequest, error) {
+	query := fmt.Sprintf(`SELECT id, attempts, COALESCE(worker_id, '') FROM %s
+WHERE op_status = $1 AND COALESCE(heartbeat_at, start_time) < $2 ORDER BY id FOR UPDATE SKIP LOCKED`, RequestTable)
+
+	rows, err := tx.QueryContext(ctx, query, StatusProcessing, expiredBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stuck []stuckRequest
+
+	for rows.Next() {
+		var s stuckRequest
+		if err := rows.Scan(&s.id, &s.attempts, &s.workerID); err != nil {
+			return nil, err
+		}
+
+		stuck = append(stuck, s)
+	}
+
+	return stuck, rows.Err()
+}
+
+// requeueRequest function queues the request again and copies the last message to process it to the outbox.
+func requeueRequest(ctx context.Context, tx *sql.Tx, reqID int, code, message string) error {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, error_code = $2, error_message = $3 WHERE id = $4`,
+		RequestTable)
+
+	result, err := tx.ExecContext(ctx, query, StatusQueued, code, message, reqID)
+	if err != nil {
+		return err
+	}
+
+	if err := oneRowInResult(result); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %[1]s (request_id, user_id, priority, payload)
+SELECT request_id, user_id, priority, payload FROM %[1]s WHERE request_id = $1 ORDER BY id DESC LIMIT 1`,
+		OutboxTable)
+
+	result, err = tx.ExecContext(ctx, query, reqID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowInResult(result)
+}
+
+// failRequest function sets failed status to the request with the code and the message of the error.
+func failRequest(ctx context.Context, tx *sql.Tx, reqID int, code, message string, t time.Time) error {
+	query := fmt.Sprintf(`UPDATE %s SET op_status = $1, error_code = $2, error_message = $3, completion_time = $4
+		WHERE id = $5`, RequestTable)
+
+	result, err := tx.ExecContext(ctx, query, StatusFailed, code, message, t, reqID)
+	if err != nil {
+		return err
+	}
+
+	return oneRowInResult(result)
+}
@@ -0,0 +1,112 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	reaperLockQuery     = regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)
+	selectStuckRequests = fmt.Sprintf(`SELECT id, attempts, COALESCE\(worker_id, ''\) FROM %s
+WHERE op_status = .+ AND COALESCE\(heartbeat_at, start_time\) < .+ ORDER BY id FOR UPDATE SKIP LOCKED`,
+		repository.RequestTable)
+	requeueRequestQuery = regexp.QuoteMeta(fmt.Sprintf(
+		`UPDATE %s SET op_status = $1, error_code = $2, error_message = $3 WHERE id = $4`, repository.RequestTable))
+	copyOutboxMessageQuery = regexp.QuoteMeta(fmt.Sprintf(`INSERT INTO %[1]s (request_id, user_id, priority, payload)
+SELECT request_id, user_id, priority, payload FROM %[1]s WHERE request_id = $1 ORDER BY id DESC LIMIT 1`,
+		repository.OutboxTable))
+	failRequestQuery = fmt.Sprintf(`UPDATE %s SET op_status = .+, error_code = .+, error_message = .+, completion_time = .+
+		WHERE id = .+`, repository.RequestTable)
+)
+
+var errReaperRepo = errors.New("reaper repo error")
+
+func NewReaperMock(t *testing.T) (*repository.ReaperPostgres, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return repository.NewReaperPostgres(db), mock
+}
+
+func TestReaperPostgres_ReapRequests(t *testing.T) {
+	expiredBefore := time.Date(2021, 1, 4, 10, 24, 34, 0, &time.Location{})
+	reapTime := time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{})
+
+	stuckRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "attempts", "worker_id"}).
+			AddRow(12, 2, "converter-1").
+			AddRow(13, 5, "converter-2")
+	}
+
+	testCases := []struct {
+		testName   string
+		initMock   func(sqlmock.Sqlmock)
+		wantResult *model.ReapResult
+		wantErr    error
+	}{
+		{
+			testName: "all is good",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(reaperLockQuery).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(true))
+				mock.ExpectQuery(selectStuckRequests).WithArgs(repository.StatusProcessing, expiredBefore).
+					WillReturnRows(stuckRows())
+				mock.ExpectExec(requeueRequestQuery).WithArgs(repository.StatusQueued, "heartbeat_expired",
+					`heartbeat of worker "converter-1" expired at attempt 2`, 12).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(copyOutboxMessageQuery).WithArgs(12).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(failRequestQuery).WithArgs(repository.StatusFailed, "heartbeat_expired",
+					`heartbeat of worker "converter-2" expired at attempt 5`, reapTime, 13).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantResult: &model.ReapResult{Leader: true, Requeued: []int{12}, Failed: []int{13}},
+		},
+		{
+			testName: "lock is held by the other reaper",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(reaperLockQuery).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(false))
+				mock.ExpectCommit()
+			},
+			wantResult: &model.ReapResult{},
+		},
+		{
+			testName: "message is not copied to the outbox",
+			initMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(reaperLockQuery).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(true))
+				mock.ExpectQuery(selectStuckRequests).WillReturnRows(stuckRows())
+				mock.ExpectExec(requeueRequestQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(copyOutboxMessageQuery).WillReturnError(errReaperRepo)
+				mock.ExpectRollback()
+			},
+			wantErr: errReaperRepo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewReaperMock(t)
+			tc.initMock(mock)
+
+			gotResult, gotErr := repo.ReapRequests(context.Background(), expiredBefore, 5, "heartbeat_expired", reapTime)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantResult, gotResult)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/lib/pq"
 )
 
 // ReqPostgres is a struct that provide methods get, add, delete and update requests.
@@ -18,10 +21,90 @@ func NewReqPostgres(db *sql.DB) *ReqPostgres {
 	return &ReqPostgres{db: &TxDB{db}}
 }
 
+// requestColumns are columns which are selected to get model.Request.
+// Requests table is used with alias r, processed image is used with alias p.
+// Artifacts of the request are aggregated to the json array.
+// Requests made from several images have no original image.
+// Requests made from the already stored image have no original image, but have source image.
+// Failed requests have the code and the message of the error.
+// Start time and worker are set, when the request is picked up for processing.
+var requestColumns = fmt.Sprintf(`r.id, r.kind, r.op_status, r.priority, r.request_time, r.completion_time,
+	 r.start_time, COALESCE(r.worker_id, ''), r.attempts,
+	 COALESCE(r.original_id, 0), COALESCE(r.source_id, 0), r.processed_id, r.ratio, COALESCE(r.original_type::text, ''),
+	 r.processed_type, r.options, r.crop, COALESCE(r.error_code, ''), COALESCE(r.error_message, ''), p.blurhash, p.preview,
+	 (SELECT json_agg(json_build_object('id', a.id, 'name', a.name, 'type', a.im_type,
+		'width', a.resoolution_x, 'height', a.resoolution_y) ORDER BY a.id)
+		FROM %s AS a WHERE a.request_id = r.id) AS artifacts`, ImageTable)
+
+// rowScanner is an interface which is implemented by both sql.Row and sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRequest function scans request selected with requestColumns.
+func scanRequest(row rowScanner) (*model.Request, error) {
+	var (
+		req         model.Request
+		complTime   sql.NullTime
+		startTime   sql.NullTime
+		processedID sql.NullInt64
+		options     []byte
+		crop        []byte
+		blurHash    sql.NullString
+		preview     sql.NullString
+		artifacts   []byte
+	)
+
+	err := row.Scan(&req.ID, &req.Kind, &req.OpStatus, &req.Priority, &req.RequestTime, &complTime,
+		&startTime, &req.WorkerID, &req.Attempts,
+		&req.OriginalID, &req.SourceID, &processedID, &req.Ratio,
+		&req.OriginalType, &req.ProcessedType, &options, &crop,
+		&req.ErrorCode, &req.ErrorMessage, &blurHash, &preview, &artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	if complTime.Valid {
+		req.CompletionTime = complTime.Time
+	}
+
+	if startTime.Valid {
+		req.StartTime = startTime.Time
+	}
+
+	if processedID.Valid {
+		req.ProcessedID = int(processedID.Int64)
+	}
+
+	if len(options) != 0 {
+		if err := json.Unmarshal(options, &req.Options); err != nil {
+			return nil, fmt.Errorf("unmarshal options: %w", err)
+		}
+	}
+
+	if len(crop) != 0 {
+		req.Crop = new(model.CropResult)
+		if err := json.Unmarshal(crop, req.Crop); err != nil {
+			return nil, fmt.Errorf("unmarshal crop: %w", err)
+		}
+	}
+
+	if len(artifacts) != 0 {
+		if err := json.Unmarshal(artifacts, &req.Artifacts); err != nil {
+			return nil, fmt.Errorf("unmarshal artifacts: %w", err)
+		}
+	}
+
+	req.BlurHash = blurHash.String
+	req.Preview = preview.String
+
+	return &req, nil
+}
+
 // GetRequests method gets all user's requests from the postgres database.
 func (r *ReqPostgres) GetRequests(ctx context.Context, userID int) ([]model.Request, error) {
-	query := fmt.Sprintf(`SELECT id, op_status, request_time, completion_time, original_id,
-	 processed_id, ratio, original_type, processed_type FROM %s WHERE user_id = $1`, RequestTable)
+	query := fmt.Sprintf(`SELECT %s FROM %s AS r LEFT JOIN %s AS p ON r.processed_id = p.id
+	WHERE r.user_id = $1`, requestColumns, RequestTable, ImageTable)
 
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -32,29 +115,11 @@ func (r *ReqPostgres) GetRequests(ctx context.Context, userID int) ([]model.Requ
 	var reqs []model.Request
 
 	for rows.Next() {
-		req := new(model.Request)
-
-		var (
-			complTime   sql.NullTime
-			processedID sql.NullInt64
-		)
-
-		err := rows.Scan(&req.ID, &req.OpStatus, &req.RequestTime, &complTime,
-			&req.OriginalID, &processedID, &req.Ratio,
-			&req.OriginalType, &req.ProcessedType)
-
+		req, err := scanRequest(rows)
 		if err != nil {
 			return nil, fmt.Errorf("repo: %w", err)
 		}
 
-		if complTime.Valid {
-			req.CompletionTime = complTime.Time
-		}
-
-		if processedID.Valid {
-			req.ProcessedID = int(processedID.Int64)
-		}
-
 		reqs = append(reqs, *req)
 	}
 
@@ -64,42 +129,32 @@ func (r *ReqPostgres) GetRequests(ctx context.Context, userID int) ([]model.Requ
 // GetRequests method gets one request from the database by its id.
 // If this request belongs to the another user, this function returns error.
 func (r *ReqPostgres) GetRequest(ctx context.Context, userID, reqID int) (*model.Request, error) {
-	query := fmt.Sprintf(`SELECT id, op_status, request_time, completion_time, original_id,
-	 processed_id, ratio, original_type, processed_type FROM %s WHERE id = $1 and user_id = $2`, RequestTable)
+	query := fmt.Sprintf(`SELECT %s FROM %s AS r LEFT JOIN %s AS p ON r.processed_id = p.id
+	WHERE r.id = $1 and r.user_id = $2`, requestColumns, RequestTable, ImageTable)
 	row := r.db.QueryRowContext(ctx, query, reqID, userID)
 
-	var (
-		complTime   sql.NullTime
-		processedID sql.NullInt64
-	)
-
-	var req model.Request
-
-	err := row.Scan(&req.ID, &req.OpStatus, &req.RequestTime, &complTime,
-		&req.OriginalID, &processedID, &req.Ratio,
-		&req.OriginalType, &req.ProcessedType)
+	req, err := scanRequest(row)
 	if err != nil {
 		return nil, fmt.Errorf("repo: %w", err)
 	}
 
-	if complTime.Valid {
-		req.CompletionTime = complTime.Time
-	}
-
-	if processedID.Valid {
-		req.ProcessedID = int(processedID.Int64)
-	}
-
-	return &req, nil
+	return req, nil
 }
 
 // AddRequest method add a request to the database and returns request id.
+// Zero imageID means that request has no original image.
 func addRequest(ctx context.Context, tx *sql.Tx, req *model.Request, imageID, userID int) (int, error) {
+	options, err := json.Marshal(req.Options)
+	if err != nil {
+		return 0, fmt.Errorf("repo: %w", err)
+	}
+
 	query := fmt.Sprintf(`INSERT INTO %s (op_status, request_time, original_id, 
-		user_id, ratio, original_type, processed_type)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`, RequestTable)
+		user_id, ratio, original_type, processed_type, options, kind, source_id, priority)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, NULLIF($6::text, '')::image_type, $7, $8, $9, NULLIF($10, 0), $11)
+		RETURNING id;`, RequestTable)
 	row := tx.QueryRowContext(ctx, query, req.OpStatus, req.RequestTime, imageID,
-		userID, req.Ratio, req.OriginalType, req.ProcessedType)
+		userID, req.Ratio, req.OriginalType, req.ProcessedType, options, req.Kind, req.SourceID, req.Priority)
 
 	var reqID int
 
@@ -126,8 +181,11 @@ func addImage(ctx context.Context, tx *sql.Tx, userID int, imageInfo model.Reuqu
 	return imageID, nil
 }
 
+// AddImageAndRequest method adds the original image and the request to the database.
+// Message to process the request is added to the outbox in the same transaction.
+// Returns id of the added request.
 func (r *ReqPostgres) AddImageAndRequest(ctx context.Context, userID int, imageInfo *model.ReuquestImageInfo,
-	req *model.Request) (int, error) {
+	req *model.Request, fileName string) (int, error) {
 	var reqID int
 
 	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
@@ -137,7 +195,12 @@ func (r *ReqPostgres) AddImageAndRequest(ctx context.Context, userID int, imageI
 		}
 
 		reqID, err = addRequest(ctx, tx, req, imageID, userID)
-		return err
+		if err != nil {
+			return err
+		}
+
+		return addOutboxMessage(ctx, tx, userID,
+			&model.RequestToProcess{ReqID: reqID, FileName: fileName, Priority: req.Priority})
 	})
 
 	if err != nil {
@@ -147,11 +210,62 @@ func (r *ReqPostgres) AddImageAndRequest(ctx context.Context, userID int, imageI
 	return reqID, nil
 }
 
+// AddRequest method adds the request without original image to the database.
+// Request could refer to the already stored image with SourceID, which is kept after request deletion.
+// Message to process the request is added to the outbox in the same transaction.
+// Returns id of the added request.
+func (r *ReqPostgres) AddRequest(ctx context.Context, userID int, req *model.Request, fileName string) (int, error) {
+	var reqID int
+
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		reqID, err = addRequest(ctx, tx, req, 0, userID)
+		if err != nil {
+			return err
+		}
+
+		return addOutboxMessage(ctx, tx, userID,
+			&model.RequestToProcess{ReqID: reqID, FileName: fileName, Priority: req.Priority})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return reqID, nil
+}
+
+// GetImage method gets type and url of the user's image from the database.
+func (r *ReqPostgres) GetImage(ctx context.Context, userID, imageID int) (*model.ReuquestImageInfo, error) {
+	query := fmt.Sprintf(`SELECT im_type, image_url FROM %s WHERE user_id = $1 AND id = $2`, ImageTable)
+	row := r.db.QueryRowContext(ctx, query, userID, imageID)
+
+	var img model.ReuquestImageInfo
+
+	if err := row.Scan(&img.Type, &img.URL); err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	return &img, nil
+}
+
+// CountImages method returns how many images with provided ids belong to the user.
+func (r *ReqPostgres) CountImages(ctx context.Context, userID int, imageIDs []int) (int, error) {
+	query := fmt.Sprintf(`SELECT count(*) FROM %s WHERE user_id = $1 AND id = ANY($2)`, ImageTable)
+	row := r.db.QueryRowContext(ctx, query, userID, pq.Array(imageIDs))
+
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, fmt.Errorf("repo: %w", err)
+	}
+
+	return count, nil
+}
+
 // DeleteRequest method deletes request with reqeust id from database.
-// Returns id of the origianal and converted images.
+// Returns id of the origianal and converted images, zero id means that request has no such image.
 func deleteRequest(ctx context.Context, tx *sql.Tx, userID, reqID int) (im1id, im2id int, err error) {
-	query := fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1 AND id = $2 RETURNING original_id, processed_id`,
-		RequestTable)
+	query := fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1 AND id = $2
+		RETURNING COALESCE(original_id, 0), COALESCE(processed_id, 0)`, RequestTable)
 	row := tx.QueryRowContext(ctx, query, userID, reqID)
 
 	if err := row.Scan(&im1id, &im2id); err != nil {
@@ -175,30 +289,103 @@ func deleteImage(ctx context.Context, tx *sql.Tx, userID, imageID int) (string,
 	return url, nil
 }
 
-func (r *ReqPostgres) DeleteRequestAndImage(ctx context.Context, userID, reqID int) (im1url, im2url string, err error) {
-	err = r.db.inTx(ctx, func(tx *sql.Tx) error {
-		im1id, im2id, err1 := deleteRequest(ctx, tx, userID, reqID)
-		if err1 != nil {
-			return err1
+// deleteArtifacts function deletes all artifacts of the request. Returns url paths to them.
+func deleteArtifacts(ctx context.Context, tx *sql.Tx, userID, reqID int) ([]string, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1 AND request_id = $2 RETURNING image_url`, ImageTable)
+
+	rows, err := tx.QueryContext(ctx, query, userID, reqID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []string
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("repo: %w", err)
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// DeleteRequestAndImage method deletes request with its original, processed images and artifacts.
+// Returns url paths to all deleted images, original image is the first.
+func (r *ReqPostgres) DeleteRequestAndImage(ctx context.Context, userID, reqID int) ([]string, error) {
+	var urls []string
+
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		im1id, im2id, err := deleteRequest(ctx, tx, userID, reqID)
+		if err != nil {
+			return err
 		}
 
-		im1url, err1 = deleteImage(ctx, tx, userID, im1id)
-		if err1 != nil {
-			return err1
+		if im1id != 0 {
+			im1url, err := deleteImage(ctx, tx, userID, im1id)
+			if err != nil {
+				return err
+			}
+
+			urls = append(urls, im1url)
 		}
 
 		if im2id != 0 {
-			im2url, err1 = deleteImage(ctx, tx, userID, im2id)
-			if err1 != nil {
-				return err1
+			im2url, err := deleteImage(ctx, tx, userID, im2id)
+			if err != nil {
+				return err
 			}
+
+			urls = append(urls, im2url)
+		}
+
+		artifactURLs, err := deleteArtifacts(ctx, tx, userID, reqID)
+		if err != nil {
+			return err
 		}
 
+		urls = append(urls, artifactURLs...)
+
 		return nil
 	})
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+// CancelRequest method sets cancelled status to the user's queued or processing request.
+// Time of the cancellation is saved as the completion time.
+// Returns sql.ErrNoRows if the user has no such request and IllegalTransitionError if the request is final.
+func (r *ReqPostgres) CancelRequest(ctx context.Context, userID, reqID int, t time.Time) error {
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		query := fmt.Sprintf(`SELECT op_status FROM %s WHERE id = $1 AND user_id = $2 FOR UPDATE`, RequestTable)
+
+		var from string
+		if err := tx.QueryRowContext(ctx, query, reqID, userID).Scan(&from); err != nil {
+			return err
+		}
+
+		if !canTransit(from, StatusCancelled) {
+			return &IllegalTransitionError{ReqID: reqID, From: from, To: StatusCancelled}
+		}
+
+		query = fmt.Sprintf(`UPDATE %s SET op_status = $1, completion_time = $2 WHERE id = $3`, RequestTable)
+
+		result, err := tx.ExecContext(ctx, query, StatusCancelled, t, reqID)
+		if err != nil {
+			return err
+		}
+
+		return oneRowInResult(result)
+	})
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
 	}
 
-	return im1url, im2url, nil
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
@@ -36,8 +37,13 @@ func RepoReturnID(id int) *sqlmock.Rows {
 	return rows
 }
 
-var getRequestQuery = fmt.Sprintf(`SELECT id, op_status, request_time, completion_time, original_id,
-	 processed_id, ratio, original_type, processed_type FROM %s WHERE id = .+ and user_id = .+`, repository.RequestTable)
+var getRequestQuery = fmt.Sprintf(`SELECT r.id, r.kind, r.op_status, r.priority, r.request_time, r.completion_time,
+	 r.start_time, COALESCE\(r.worker_id, ''\), r.attempts,
+	 COALESCE\(r.original_id, 0\), COALESCE\(r.source_id, 0\), r.processed_id, r.ratio, COALESCE\(r.original_type::text, ''\),
+	 r.processed_type, r.options, r.crop, COALESCE\(r.error_code, ''\), COALESCE\(r.error_message, ''\),
+	 p.blurhash, p.preview,
+	 .+ AS artifacts FROM %s AS r LEFT JOIN %s AS p ON r.processed_id = p.id
+	 WHERE r.id = .+ and r.user_id = .+`, repository.RequestTable, repository.ImageTable)
 
 func TestReqPostgres_GetRequest(t *testing.T) {
 	testCases := []struct {
@@ -53,12 +59,18 @@ func TestReqPostgres_GetRequest(t *testing.T) {
 			userID:   12,
 			reqID:    19,
 			initMock: func(mock sqlmock.Sqlmock, userID, reqID int, req *model.Request) sqlmock.Sqlmock {
-				rows := sqlmock.NewRows([]string{"id", "op_status", "request_time", "completion_time",
-					"original_id", "processed_id", "ratio", "original_type", "processed_type"})
-
-				rows = rows.AddRow(req.ID, req.OpStatus, req.RequestTime, req.CompletionTime,
-					req.OriginalID, req.ProcessedID, req.Ratio,
-					req.OriginalType, req.ProcessedType)
+				rows := sqlmock.NewRows([]string{"id", "kind", "op_status", "priority", "request_time",
+					"completion_time", "start_time", "worker_id", "attempts",
+					"original_id", "source_id", "processed_id", "ratio", "original_type", "processed_type",
+					"options", "crop", "error_code", "error_message", "blurhash", "preview", "artifacts"})
+
+				rows = rows.AddRow(req.ID, req.Kind, req.OpStatus, req.Priority, req.RequestTime, req.CompletionTime,
+					req.StartTime, req.WorkerID, req.Attempts,
+					req.OriginalID, req.SourceID, req.ProcessedID, req.Ratio,
+					req.OriginalType, req.ProcessedType, []byte(`{"placeholder":true,"fit":"fill"}`),
+					[]byte(`{"anchor":"smart","x":10,"y":0,"width":300,"height":200}`),
+					req.ErrorCode, req.ErrorMessage, req.BlurHash, req.Preview,
+					[]byte(`[{"id":31,"name":"apple-touch-icon","type":"png","width":180,"height":180}]`))
 
 				mock.ExpectQuery(getRequestQuery).WithArgs(reqID, userID).
 					WillReturnRows(rows)
@@ -66,14 +78,26 @@ func TestReqPostgres_GetRequest(t *testing.T) {
 			},
 			wantReq: &model.Request{
 				ID:             24,
+				Kind:           "conversion",
 				OpStatus:       "done",
+				Priority:       "bulk",
 				RequestTime:    time.Date(2020, 12, 12, 23, 23, 0, 1, time.Local),
 				CompletionTime: time.Date(2020, 12, 12, 23, 24, 0, 1, time.Local),
+				StartTime:      time.Date(2020, 12, 12, 23, 23, 30, 1, time.Local),
+				WorkerID:       "converter-1",
+				Attempts:       1,
 				OriginalID:     12,
 				ProcessedID:    13,
 				Ratio:          0.5,
 				OriginalType:   "jpeg",
 				ProcessedType:  "png",
+				Options:        model.ConversionOptions{Placeholder: true, Fit: "fill"},
+				Crop:           &model.CropResult{Anchor: "smart", X: 10, Y: 0, Width: 300, Height: 200},
+				Artifacts: []model.Artifact{
+					{ID: 31, Name: "apple-touch-icon", Type: "png", Width: 180, Height: 180},
+				},
+				BlurHash: "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+				Preview:  "data:image/png;base64,iVBORw0KGgo=",
 			},
 			wantErr: nil,
 		},
@@ -114,13 +138,18 @@ var (
 		VALUES (.+, .+, .+) RETURNING id;`, repository.ImageTable)
 
 	addRequestQuery = fmt.Sprintf(`INSERT INTO %s \(op_status, request_time, original_id, 
-		user_id, ratio, original_type, processed_type\)
-		VALUES (.+, .+, .+, .+, .+, .+, .+) RETURNING id;`, repository.RequestTable)
+		user_id, ratio, original_type, processed_type, options, kind, source_id, priority\)
+		VALUES (.+)
+		RETURNING id;`, repository.RequestTable)
+
+	addOutboxMessageQuery = regexp.QuoteMeta(fmt.Sprintf(
+		`INSERT INTO %s (request_id, user_id, priority, payload) VALUES ($1, $2, $3, $4)`, repository.OutboxTable))
 )
 
 var (
 	errAddingImage   = errors.New("error while adding image")
 	errAddingRequest = errors.New("error while adding reqeust")
+	errAddingOutbox  = errors.New("error while adding outbox message")
 )
 
 func TestReqPostgres_AddImageAndRequest(t *testing.T) {
@@ -145,6 +174,7 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 			},
 			reqInfo: &model.Request{
 				OpStatus:      repository.StatusDone,
+				Priority:      repository.PriorityBulk,
 				RequestTime:   time.Date(2022, 1, 3, 14, 36, 2, 32, &time.Location{}),
 				OriginalID:    26,
 				Ratio:         0.5,
@@ -163,8 +193,11 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 					WillReturnRows(imageRow)
 				mock.ExpectQuery(addRequestQuery).WithArgs(req.OpStatus, req.RequestTime,
 					req.OriginalID, userID, req.Ratio,
-					req.OriginalType, req.ProcessedType).
+					req.OriginalType, req.ProcessedType, []byte(`{}`), req.Kind, req.SourceID, req.Priority).
 					WillReturnRows(reqRow)
+				mock.ExpectExec(addOutboxMessageQuery).
+					WithArgs(13, userID, req.Priority, []byte(`{"reqID":13,"fileName":"image.jpeg","priority":"bulk"}`)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				mock.ExpectCommit()
 
@@ -182,6 +215,7 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 			},
 			reqInfo: &model.Request{
 				OpStatus:      repository.StatusDone,
+				Priority:      repository.PriorityBulk,
 				RequestTime:   time.Date(2022, 1, 3, 14, 36, 2, 32, &time.Location{}),
 				OriginalID:    26,
 				Ratio:         0.5,
@@ -213,6 +247,7 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 			},
 			reqInfo: &model.Request{
 				OpStatus:      repository.StatusDone,
+				Priority:      repository.PriorityBulk,
 				RequestTime:   time.Date(2022, 1, 3, 14, 36, 2, 32, &time.Location{}),
 				OriginalID:    26,
 				Ratio:         0.5,
@@ -230,7 +265,7 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 					WillReturnRows(imageRow)
 				mock.ExpectQuery(addRequestQuery).WithArgs(req.OpStatus, req.RequestTime,
 					req.OriginalID, userID, req.Ratio,
-					req.OriginalType, req.ProcessedType).
+					req.OriginalType, req.ProcessedType, []byte(`{}`), req.Kind, req.SourceID, req.Priority).
 					WillReturnError(errAddingRequest)
 
 				mock.ExpectRollback()
@@ -240,6 +275,40 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 			wantID:  0,
 			wantErr: errAddingRequest,
 		},
+		{
+			testName: "transaction rollback outbox error",
+			userID:   12,
+			imageInfo: &model.ReuquestImageInfo{
+				Type: "jpeg",
+				URL:  "image url",
+			},
+			reqInfo: &model.Request{
+				OpStatus:      repository.StatusDone,
+				Priority:      repository.PriorityBulk,
+				RequestTime:   time.Date(2022, 1, 3, 14, 36, 2, 32, &time.Location{}),
+				OriginalID:    26,
+				Ratio:         0.5,
+				OriginalType:  "jpeg",
+				ProcessedType: "type",
+			},
+			initMock: func(userID int, im *model.ReuquestImageInfo,
+				req *model.Request) (*repository.ReqPostgres, sqlmock.Sqlmock) {
+				repo, mock := NewReqMock(t)
+
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(addImageQuery).WithArgs(im.Type, im.URL, userID).
+					WillReturnRows(RepoReturnID(req.OriginalID))
+				mock.ExpectQuery(addRequestQuery).WillReturnRows(RepoReturnID(13))
+				mock.ExpectExec(addOutboxMessageQuery).WillReturnError(errAddingOutbox)
+
+				mock.ExpectRollback()
+
+				return repo, mock
+			},
+			wantID:  0,
+			wantErr: errAddingOutbox,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -247,7 +316,7 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 			repo, mock := tc.initMock(tc.userID, tc.imageInfo, tc.reqInfo)
 
 			reqID, gotErr := repo.AddImageAndRequest(context.Background(), tc.userID,
-				tc.imageInfo, tc.reqInfo)
+				tc.imageInfo, tc.reqInfo, "image.jpeg")
 
 			assert.ErrorIs(t, gotErr, tc.wantErr)
 			assert.Equal(t, reqID, tc.wantID)
@@ -260,20 +329,22 @@ func TestReqPostgres_AddImageAndRequest(t *testing.T) {
 }
 
 var delteRequestQuery = fmt.Sprintf(`DELETE FROM %s WHERE user_id = .+ AND id = .+ 
-		RETURNING original_id, processed_id`, repository.RequestTable)
+		RETURNING COALESCE\(original_id, 0\), COALESCE\(processed_id, 0\)`, repository.RequestTable)
 
 var deleteImageQuery = fmt.Sprintf(`DELETE FROM %s WHERE user_id = .+ AND id = .+
 		RETURNING image_url`, repository.ImageTable)
 
+var deleteArtifactsQuery = fmt.Sprintf(`DELETE FROM %s WHERE user_id = .+ AND request_id = .+
+		RETURNING image_url`, repository.ImageTable)
+
 func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 	testCases := []struct {
-		testName   string
-		userID     int
-		reqID      int
-		initMock   func(sqlmock.Sqlmock, int, int) sqlmock.Sqlmock
-		wantIm1URL string
-		wantIm2URL string
-		wantErr    error
+		testName string
+		userID   int
+		reqID    int
+		initMock func(sqlmock.Sqlmock, int, int) sqlmock.Sqlmock
+		wantURLs []string
+		wantErr  error
 	}{
 		{
 			testName: "all is good",
@@ -292,12 +363,13 @@ func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 					WillReturnRows(url1Row)
 				mock.ExpectQuery(deleteImageQuery).WithArgs(userID, 24).
 					WillReturnRows(url2Row)
+				mock.ExpectQuery(deleteArtifactsQuery).WithArgs(userID, reqID).
+					WillReturnRows(sqlmock.NewRows([]string{"image_url"}).AddRow("artifact url"))
 				mock.ExpectCommit()
 				return mock
 			},
-			wantIm1URL: "im 1 url",
-			wantIm2URL: "im 2 url",
-			wantErr:    nil,
+			wantURLs: []string{"im 1 url", "im 2 url", "artifact url"},
+			wantErr:  nil,
 		},
 		{
 			testName: "such rown not exist",
@@ -310,9 +382,8 @@ func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 				mock.ExpectRollback()
 				return mock
 			},
-			wantIm1URL: "",
-			wantIm2URL: "",
-			wantErr:    sql.ErrNoRows,
+			wantURLs: nil,
+			wantErr:  sql.ErrNoRows,
 		},
 		{
 			testName: "only one id is exist in row",
@@ -328,12 +399,35 @@ func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 					WillReturnRows(idRows)
 				mock.ExpectQuery(deleteImageQuery).WithArgs(userID, 23).
 					WillReturnRows(url1Row)
+				mock.ExpectQuery(deleteArtifactsQuery).WithArgs(userID, reqID).
+					WillReturnRows(sqlmock.NewRows([]string{"image_url"}))
 				mock.ExpectCommit()
 				return mock
 			},
-			wantIm1URL: "im 1 url",
-			wantIm2URL: "",
-			wantErr:    nil,
+			wantURLs: []string{"im 1 url"},
+			wantErr:  nil,
+		},
+		{
+			testName: "request without original image",
+			userID:   12,
+			reqID:    13,
+			initMock: func(mock sqlmock.Sqlmock, userID, reqID int) sqlmock.Sqlmock {
+				idRows := sqlmock.NewRows([]string{"original_id", "processed_id"})
+				idRows.AddRow(0, 24)
+
+				url2Row := sqlmock.NewRows([]string{"image_url"}).AddRow("im 2 url")
+				mock.ExpectBegin()
+				mock.ExpectQuery(delteRequestQuery).WithArgs(userID, reqID).
+					WillReturnRows(idRows)
+				mock.ExpectQuery(deleteImageQuery).WithArgs(userID, 24).
+					WillReturnRows(url2Row)
+				mock.ExpectQuery(deleteArtifactsQuery).WithArgs(userID, reqID).
+					WillReturnRows(sqlmock.NewRows([]string{"image_url"}))
+				mock.ExpectCommit()
+				return mock
+			},
+			wantURLs: []string{"im 2 url"},
+			wantErr:  nil,
 		},
 	}
 
@@ -343,11 +437,10 @@ func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 
 			mock = tc.initMock(mock, tc.userID, tc.reqID)
 
-			gotIm1URL, gotIm2URL, gotErr := repo.DeleteRequestAndImage(context.Background(), tc.userID, tc.reqID)
+			gotURLs, gotErr := repo.DeleteRequestAndImage(context.Background(), tc.userID, tc.reqID)
 
 			assert.ErrorIs(t, gotErr, tc.wantErr)
-			assert.Equal(t, gotIm2URL, tc.wantIm2URL)
-			assert.Equal(t, gotIm1URL, tc.wantIm1URL)
+			assert.Equal(t, tc.wantURLs, gotURLs)
 
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("there were fulfilled expectations: %s", err)
@@ -355,3 +448,74 @@ func TestReqPostgres_DeleteRequestAndImage(t *testing.T) {
 		})
 	}
 }
+
+func TestReqPostgres_CancelRequest(t *testing.T) {
+	selectQuery := regexp.QuoteMeta(fmt.Sprintf(`SELECT op_status FROM %s WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		repository.RequestTable))
+	cancelQuery := regexp.QuoteMeta(fmt.Sprintf(`UPDATE %s SET op_status = $1, completion_time = $2 WHERE id = $3`,
+		repository.RequestTable))
+	cancelTime := time.Date(2021, 1, 4, 10, 25, 34, 0, &time.Location{})
+
+	testCases := []struct {
+		testName   string
+		fromStatus string
+		selectErr  error
+		wantErr    error
+		wantErrAs  interface{}
+	}{
+		{
+			testName:   "queued request",
+			fromStatus: repository.StatusQueued,
+		},
+		{
+			testName:   "processing request",
+			fromStatus: repository.StatusProcessing,
+		},
+		{
+			testName:   "done request",
+			fromStatus: repository.StatusDone,
+			wantErrAs:  new(*repository.IllegalTransitionError),
+		},
+		{
+			testName:  "request of the other user",
+			selectErr: sql.ErrNoRows,
+			wantErr:   sql.ErrNoRows,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			repo, mock := NewReqMock(t)
+
+			mock.ExpectBegin()
+
+			if tc.selectErr != nil {
+				mock.ExpectQuery(selectQuery).WithArgs(12, 1).WillReturnError(tc.selectErr)
+			} else {
+				mock.ExpectQuery(selectQuery).WithArgs(12, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"op_status"}).AddRow(tc.fromStatus))
+			}
+
+			if tc.wantErr != nil || tc.wantErrAs != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(cancelQuery).WithArgs(repository.StatusCancelled, cancelTime, 12).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			err := repo.CancelRequest(context.Background(), 1, 12, cancelTime)
+
+			switch {
+			case tc.wantErrAs != nil:
+				assert.ErrorAs(t, err, tc.wantErrAs)
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			default:
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// Maximum of the tolerance and feather in percents.
+const maxChromaKeyDistance = 100
+
+// Format of the key colour, alpha isn't accepted, because only the colour channels are compared.
+const chromaKeyColorFormat = "#rrggbb"
+
+// chromaKey function returns the validated chroma key from the options.
+func chromaKey(opts *model.ChromaKeyOptions) (conversion.ChromaKey, error) {
+	c, err := conversion.ParseHexColor(opts.Color)
+	if err != nil || len(opts.Color) != len(chromaKeyColorFormat) {
+		return conversion.ChromaKey{}, &InvalidOptionError{"chromaKey",
+			fmt.Sprintf("invalid color %q, expected %s", opts.Color, chromaKeyColorFormat)}
+	}
+
+	switch {
+	case opts.Tolerance < 0 || opts.Tolerance > maxChromaKeyDistance:
+		return conversion.ChromaKey{}, &InvalidOptionError{"tolerance",
+			fmt.Sprintf("should be between 0 and %v", maxChromaKeyDistance)}
+	case opts.Feather < 0 || opts.Feather > maxChromaKeyDistance:
+		return conversion.ChromaKey{}, &InvalidOptionError{"feather",
+			fmt.Sprintf("should be between 0 and %v", maxChromaKeyDistance)}
+	}
+
+	return conversion.ChromaKey{Color: c, Tolerance: opts.Tolerance, Feather: opts.Feather}, nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+)
+
+const (
+	// Maximum amount of the images on the one contact sheet.
+	maxSheetImages = 100
+
+	// Maximum size of the cell side and of the spacing between cells.
+	maxSheetCellSize = 2000
+	maxSheetSpacing  = 200
+
+	// Maximum length of the caption in characters, longer captions never fit into the cell.
+	maxCaptionLength = 300
+
+	// Name of the file, which is used for the contact sheet.
+	contactSheetName = "contact-sheet"
+)
+
+// validateContactSheet function checks that the contact sheet could be made.
+func validateContactSheet(info *model.ContactSheetInfo) error {
+	switch {
+	case len(info.ImageIDs) == 0 || len(info.ImageIDs) > maxSheetImages:
+		return &InvalidOptionError{"imageIDs", fmt.Sprintf("amount of images should be between 1 and %v",
+			maxSheetImages)}
+	case info.Columns <= 0:
+		return &InvalidOptionError{"columns", "should be positive"}
+	case info.CellWidth <= 0 || info.CellWidth > maxSheetCellSize:
+		return &InvalidOptionError{"cellWidth", fmt.Sprintf("should be between 1 and %v", maxSheetCellSize)}
+	case info.CellHeight <= 0 || info.CellHeight > maxSheetCellSize:
+		return &InvalidOptionError{"cellHeight", fmt.Sprintf("should be between 1 and %v", maxSheetCellSize)}
+	case info.Spacing < 0 || info.Spacing > maxSheetSpacing:
+		return &InvalidOptionError{"spacing", fmt.Sprintf("should be between 0 and %v", maxSheetSpacing)}
+	case len(info.Captions) > len(info.ImageIDs):
+		return &InvalidOptionError{"captions", "there are more captions than images"}
+	}
+
+	for _, caption := range info.Captions {
+		if utf8.RuneCountInString(caption) > maxCaptionLength {
+			return &InvalidOptionError{"captions", fmt.Sprintf("caption is longer than %v characters", maxCaptionLength)}
+		}
+	}
+
+	layout, err := sheetLayout(&info.SheetLayout)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSheetSize(len(info.ImageIDs), len(info.Captions), layout); err != nil {
+		return err
+	}
+
+	if info.Type != jpegType && info.Type != pngType {
+		return UnsupportedTypeError{info.Type}
+	}
+
+	return nil
+}
+
+// checkSheetSize function checks that the contact sheet with the layout isn't too large to be drawn.
+func checkSheetSize(images, captions int, layout conversion.SheetLayout) error {
+	size := conversion.SheetSize(images, captions != 0, layout)
+	if size.X*size.Y > conversion.MaxSheetPixels {
+		return &InvalidOptionError{"sheet", fmt.Sprintf("size %vx%v is more than %v pixels",
+			size.X, size.Y, conversion.MaxSheetPixels)}
+	}
+
+	return nil
+}
+
+// sheetLayout function converts layout from the request to the layout used to draw the sheet.
+func sheetLayout(layout *model.SheetLayout) (conversion.SheetLayout, error) {
+	var background color.Color = color.White
+
+	if layout.Background != "" {
+		c, err := conversion.ParseHexColor(layout.Background)
+		if err != nil {
+			return conversion.SheetLayout{}, &InvalidOptionError{"background", err.Error()}
+		}
+
+		background = c
+	}
+
+	return conversion.SheetLayout{
+		Columns:    layout.Columns,
+		CellWidth:  layout.CellWidth,
+		CellHeight: layout.CellHeight,
+		Spacing:    layout.Spacing,
+		Background: background,
+	}, nil
+}
+
+// AddContactSheetRequest returns the id of the added request or error if any occurs.
+// Request is made from the user's images, which were uploaded before.
+// Contact sheet is drawn by the converter like the other requests,
+// so the message to the converter is added with the request.
+func (s *Request) AddContactSheetRequest(ctx context.Context, userID int, info model.ContactSheetInfo) (int, error) {
+	if err := validateContactSheet(&info); err != nil {
+		return 0, fmt.Errorf("add contact sheet: %w", err)
+	}
+
+	priority, err := requestPriority(info.Priority)
+	if err != nil {
+		return 0, fmt.Errorf("add contact sheet: %w", err)
+	}
+
+	if err := s.checkImagesOwner(ctx, userID, info.ImageIDs); err != nil {
+		return 0, fmt.Errorf("add contact sheet: %w", err)
+	}
+
+	layout := info.SheetLayout
+
+	req := model.Request{
+		Kind:          repository.KindContactSheet,
+		OpStatus:      repository.StatusQueued,
+		Priority:      priority,
+		RequestTime:   time.Now(),
+		Ratio:         1,
+		ProcessedType: info.Type,
+		Options: model.ConversionOptions{
+			ImageIDs: info.ImageIDs,
+			Sheet:    &layout,
+		},
+	}
+
+	reqID, err := s.repo.AddRequest(ctx, userID, &req, contactSheetName+"."+info.Type)
+	if err != nil {
+		return 0, fmt.Errorf("repo add request: %w", err)
+	}
+
+	return reqID, nil
+}
+
+// contactSheet method draws the contact sheet from the images of the request.
+func (c *ConvertRequest) contactSheet(ctx context.Context, info *model.ConvImageInfo) (image.Image, error) {
+	if info.Options.Sheet == nil {
+		return nil, &InvalidOptionError{"sheet", "layout is missing"}
+	}
+
+	layout, err := sheetLayout(info.Options.Sheet)
+	if err != nil {
+		return nil, fmt.Errorf("contact sheet: %w", err)
+	}
+
+	if err := checkSheetSize(len(info.Options.ImageIDs), len(info.Options.Sheet.Captions), layout); err != nil {
+		return nil, fmt.Errorf("contact sheet: %w", err)
+	}
+
+	images, err := c.getUserImages(ctx, info.UserID, info.Options.ImageIDs,
+		image.Pt(layout.CellWidth, layout.CellHeight))
+	if err != nil {
+		return nil, fmt.Errorf("contact sheet: %w", err)
+	}
+
+	return conversion.ContactSheet(images, info.Options.Sheet.Captions, layout), nil
+}
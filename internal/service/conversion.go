@@ -3,38 +3,332 @@ package service
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"image"
 	"time"
 
 	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/logging"
 	"github.com/Dyleme/image-coverter/internal/model"
 	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/disintegration/imaging"
 )
 
 type ConvertRepo interface {
 	GetConvInfo(ctx context.Context, reqID int) (*model.ConvImageInfo, error)
+	StartProcessing(ctx context.Context, reqID int, workerID string, t time.Time) (int, error)
+	Heartbeat(ctx context.Context, reqID int, workerID string, t time.Time) error
+	ReleaseRequest(ctx context.Context, reqID int, workerID string) error
+	RetryRequest(ctx context.Context, reqID int, workerID, code, message string) error
+	GetImages(ctx context.Context, userID int, imageIDs []int) (map[int]model.ReuquestImageInfo, error)
 	SetImageResolution(ctx context.Context, imID int, width int, height int) error
-	AddProcessedImage(ctx context.Context, userID, reqID int, imgInfo *model.ReuquestImageInfo,
+	SetRequestCrop(ctx context.Context, reqID int, crop *model.CropResult) error
+	SetRequestFailed(ctx context.Context, reqID int, workerID, code, message string, t time.Time) error
+	AddArtifacts(ctx context.Context, userID, reqID int, artifacts []model.Artifact) error
+	DeleteArtifacts(ctx context.Context, reqID int) error
+	AddProcessedImage(ctx context.Context, userID, reqID int, workerID string, imgInfo *model.ReuquestImageInfo,
 		width, height int, status string, t time.Time) error
 }
 
+const (
+	// Default amount of attempts to process the request.
+	defaultMaxAttempts = 5
+
+	// Default time between heartbeats of the processed request.
+	defaultHeartbeatInterval = 10 * time.Second
+
+	// Default time given to process the request.
+	defaultTimeout = 5 * time.Minute
+
+	// Time given to save the state of the request, when the context of the processing is done.
+	settleTimeout = 5 * time.Second
+
+	// Maximum amount of pixels of all images decoded for the one contact sheet or pdf document.
+	maxSourcePixels = 250000000
+)
+
+// ConvertConfig is a configuration of the requests processing.
+type ConvertConfig struct {
+	// WorkerID is saved with the processed requests to find out where they are processed.
+	WorkerID string
+
+	// MaxAttempts is the amount of attempts to process the request, which fails with transient errors.
+	// It's 5 by default.
+	MaxAttempts int
+
+	// HeartbeatInterval is the time between heartbeats of the processed request, 10 seconds by default.
+	// It should be several times less than the heartbeat timeout of the reaper.
+	HeartbeatInterval time.Duration
+
+	// Timeout is the time given to one attempt to process the request, 5 minutes by default.
+	// Requests, which aren't processed in time, are failed without retries.
+	Timeout time.Duration
+
+	// Concurrency is the amount of the conversions, which could run at once, 1 by default.
+	// Conversions left in background after the timeout are counted till they exit.
+	Concurrency int
+}
+
 type ConvertRequest struct {
-	repo    ConvertRepo
-	storage Storager
+	repo              ConvertRepo
+	storage           Storager
+	workerID          string
+	maxAttempts       int
+	heartbeatInterval time.Duration
+	timeout           time.Duration
+
+	// slots limits the amount of the running conversions, slot is taken till the conversion exits.
+	slots chan struct{}
 }
 
-func NewConvertRequest(repo ConvertRepo, stor Storager) *ConvertRequest {
-	return &ConvertRequest{repo: repo, storage: stor}
+func NewConvertRequest(repo ConvertRepo, stor Storager, conf *ConvertConfig) *ConvertRequest {
+	maxAttempts := conf.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	heartbeatInterval := conf.HeartbeatInterval
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = defaultHeartbeatInterval
+	}
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	concurrency := conf.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return &ConvertRequest{repo: repo, storage: stor, workerID: conf.WorkerID, maxAttempts: maxAttempts,
+		heartbeatInterval: heartbeatInterval, timeout: timeout, slots: make(chan struct{}, concurrency)}
 }
 
+// Convert method processes the request and saves the result.
+// At first request is moved to the processing status, requests which are not queued are not processed.
+// Requests which are already done, failed or cancelled are skipped without error,
+// so redelivered messages are dropped. If the request is still processing, RetryError is returned.
+// Outputs are saved with the paths made from the request id, so the same files are overwritten on retry.
+// If the processing fails with the transient error and there are attempts left, request is queued again
+// and RetryError is returned. Otherwise request gets failed status with the code and the message of the error.
+// Processing is limited by the timeout, request is failed if it's not processed in time.
+// While the request is processed its heartbeat is saved, if the request is reaped or cancelled in the meantime,
+// processing is stopped and the request is left as it is.
+// If the context is cancelled, request is returned to the queued status without counting the attempt.
+// Processing waits for the free slot, if the concurrency is reached by the conversions left after the timeout.
 func (c *ConvertRequest) Convert(ctx context.Context, reqID int, filename string) error {
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("conversion: wait for the free slot: %w", ctx.Err())
+	}
+
+	// Slot is passed to the conversion, when it's started.
+	holdSlot := true
+
+	defer func() {
+		if holdSlot {
+			<-c.slots
+		}
+	}()
+
+	attempt, err := c.repo.StartProcessing(ctx, reqID, c.workerID, time.Now())
+	if err != nil {
+		var transitionErr *repository.IllegalTransitionError
+		if errors.As(err, &transitionErr) {
+			if finished(transitionErr.From) {
+				return nil
+			}
+
+			// Message is redelivered, while the request is still processing. The worker processing it
+			// could have crashed, so the message is retried later, when the request is finished
+			// or queued again by the reaper.
+			if transitionErr.From == repository.StatusProcessing {
+				return &RetryError{Err: fmt.Errorf("conversion: request is still processing: %w", err)}
+			}
+
+			return fmt.Errorf("conversion: %w", err)
+		}
+
+		return &RetryError{Err: fmt.Errorf("conversion: start processing: %w", err)}
+	}
+
+	workCtx, stop := c.keepAlive(ctx, reqID)
+	jobCtx, cancel := context.WithTimeout(workCtx, c.timeout)
+	holdSlot = false
+	err = c.convertWithin(jobCtx, reqID, filename)
+
+	cancel()
+
+	var lostErr *repository.LostOwnershipError
+	if stop() || errors.As(err, &lostErr) {
+		logging.FromContext(ctx).Warnf("conversion: request %v isn't processed by the worker anymore", reqID)
+
+		return nil
+	}
+
+	var transitionErr *repository.IllegalTransitionError
+	if errors.As(err, &transitionErr) && finished(transitionErr.From) {
+		return nil
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return c.release(ctx, reqID, err)
+	}
+
+	if err != nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		err = failure(FailureTimeout, fmt.Errorf("conversion: not finished in %v: %w", c.timeout, err))
+	}
+
+	if err != nil {
+		if transient(err) && attempt < c.maxAttempts {
+			return c.retry(ctx, reqID, attempt, err)
+		}
+
+		return c.fail(ctx, reqID, err)
+	}
+
+	return nil
+}
+
+// keepAlive method saves the heartbeat of the request every interval till the returned function is called.
+// Returned context is cancelled if the request isn't processed by the worker anymore,
+// returned function reports if it happened.
+func (c *ConvertRequest) keepAlive(ctx context.Context, reqID int) (context.Context, func() bool) {
+	workCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	lost := make(chan bool, 1)
+
+	go func() {
+		ticker := time.NewTicker(c.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				lost <- false
+
+				return
+			case <-ticker.C:
+			}
+
+			err := c.repo.Heartbeat(workCtx, reqID, c.workerID, time.Now())
+
+			var rowsErr *repository.NotSingleRowAffectedError
+			if errors.As(err, &rowsErr) {
+				cancel()
+				<-done
+				lost <- true
+
+				return
+			}
+
+			if err != nil && workCtx.Err() == nil {
+				logging.FromContext(ctx).Warnf("conversion: heartbeat of request %v: %s", reqID, err)
+			}
+		}
+	}()
+
+	return workCtx, func() bool {
+		close(done)
+		defer cancel()
+
+		return <-lost
+	}
+}
+
+// finished function reports whether the request with the status is already processed.
+func finished(status string) bool {
+	return status == repository.StatusDone || status == repository.StatusFailed ||
+		status == repository.StatusCancelled
+}
+
+// convertWithin method processes the request till it's processed or the context is done.
+// Steps, which don't take the context, like decoding, are left in background then.
+// They can't save anything, because storage and repo calls fail with the done context.
+// The slot taken by the caller is freed, when the conversion exits.
+func (c *ConvertRequest) convertWithin(ctx context.Context, reqID int, filename string) error {
+	errs := make(chan error, 1)
+
+	go func() {
+		defer func() { <-c.slots }()
+
+		errs <- c.convert(ctx, reqID, filename)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("conversion: %w", ctx.Err())
+	}
+}
+
+// settleContext function returns the context to save the state of the request,
+// which is not cancelled with the context of the processing.
+func settleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(logging.Detach(ctx), settleTimeout)
+}
+
+// processedPath function returns the storage path of the request output.
+// It's the same for all attempts to process the request.
+func processedPath(reqID int, name string) string {
+	return fmt.Sprintf("processed/%d/%s", reqID, name)
+}
+
+// cleanup method deletes the outputs of the request, which are not saved to the repo.
+// Outputs are deleted with the settle context, because the context of the processing could be done already.
+// Deletion errors are added to the original error.
+func (c *ConvertRequest) cleanup(ctx context.Context, err error, paths ...string) error {
+	settleCtx, cancel := settleContext(ctx)
+	defer cancel()
+
+	for _, p := range paths {
+		if delErr := c.storage.DeleteFile(settleCtx, p); delErr != nil {
+			err = fmt.Errorf("%w (cleanup %v: %v)", err, p, delErr) //nolint:errorlint // making combined error
+		}
+	}
+
+	return err
+}
+
+// cleanupPrefix method deletes all the outputs of the request under the prefix with the settle context.
+// Deletion error is added to the original error.
+func (c *ConvertRequest) cleanupPrefix(ctx context.Context, err error, prefix string) error {
+	settleCtx, cancel := settleContext(ctx)
+	defer cancel()
+
+	if delErr := c.storage.DeleteFiles(settleCtx, prefix); delErr != nil {
+		return fmt.Errorf("%w (cleanup %v: %v)", err, prefix, delErr) //nolint:errorlint // making combined error
+	}
+
+	return err
+}
+
+func (c *ConvertRequest) convert(ctx context.Context, reqID int, filename string) error {
 	info, err := c.repo.GetConvInfo(ctx, reqID)
 	if err != nil {
-		return fmt.Errorf("conversion: %w", err)
+		return fmt.Errorf("conversion: %w", failure(FailureDatabase, err))
 	}
 
-	img, err := c.getImage(ctx, info.OldURL, info.OldType)
+	if info.Kind == repository.KindPDF {
+		return c.savePDF(ctx, reqID, filename, info)
+	}
+
+	if info.Kind == repository.KindContactSheet {
+		img, err := c.contactSheet(ctx, info)
+		if err != nil {
+			return fmt.Errorf("conversion: %w", err)
+		}
+
+		return c.saveProcessedImage(ctx, reqID, filename, info, img, info.NewType)
+	}
+
+	img, err := c.getImage(ctx, info)
 	if err != nil {
 		return fmt.Errorf("conversion: %w", err)
 	}
@@ -43,44 +337,252 @@ func (c *ConvertRequest) Convert(ctx context.Context, reqID int, filename string
 	err = c.repo.SetImageResolution(ctx, info.OldImID, width, height)
 
 	if err != nil {
-		return fmt.Errorf("conversion: %w", err)
+		return fmt.Errorf("conversion: %w", failure(FailureDatabase, err))
+	}
+
+	if info.Options.Fit != "" {
+		var crop *model.CropResult
+
+		img, crop, err = fitImage(img, &info.Options)
+		if err != nil {
+			return fmt.Errorf("conversion: %w", err)
+		}
+
+		err = c.repo.SetRequestCrop(ctx, reqID, crop)
+		if err != nil {
+			return fmt.Errorf("conversion: %w", failure(FailureDatabase, err))
+		}
 	}
 
 	if info.Ratio != 1 {
-		img = conversion.Resize(img, info.Ratio)
+		if info.Options.LinearLight {
+			img = conversion.ResizeLinear(img, info.Ratio)
+		} else {
+			img = conversion.Resize(img, info.Ratio)
+		}
 	}
 
-	bts, err := encodeImage(img, info.NewType)
-	if err != nil {
-		return fmt.Errorf("conversion: %w", err)
+	if info.Options.ChromaKey != nil {
+		key, err := chromaKey(info.Options.ChromaKey)
+		if err != nil {
+			return fmt.Errorf("conversion: %w", err)
+		}
+
+		img = conversion.RemoveColor(img, key)
+	}
+
+	if info.NewType == dziType {
+		return c.saveTilePyramid(ctx, reqID, info, img)
+	}
+
+	if info.NewType == faviconType {
+		return c.saveFaviconBundle(ctx, reqID, filename, info, img)
 	}
 
-	newURL, err := c.storage.UploadFile(ctx, info.UserID, filename, bts)
+	return c.saveProcessedImage(ctx, reqID, filename, info, img, info.NewType)
+}
+
+// saveProcessedImage method encodes the image with imgType, puts it to the storage
+// and adds it to the repo as processed image of the request. Image is deleted, if it isn't added to the repo.
+func (c *ConvertRequest) saveProcessedImage(ctx context.Context, reqID int, filename string,
+	info *model.ConvImageInfo, img image.Image, imgType string) error {
+	bts, err := encodeImage(img, imgType)
 	if err != nil {
-		return fmt.Errorf("conversion: %w", err)
+		return fmt.Errorf("conversion: %w", failure(FailureEncode, err))
 	}
 
+	newURL := processedPath(reqID, replaceExtension(filename, imgType))
+
 	newImgInfo := model.ReuquestImageInfo{
 		URL:  newURL,
-		Type: info.NewType,
+		Type: imgType,
+	}
+
+	// Placeholders are made before the upload, so their errors don't leave the processed image in the storage.
+	if info.Options.Placeholder {
+		newImgInfo.BlurHash, newImgInfo.Preview, err = placeholders(img)
+		if err != nil {
+			return fmt.Errorf("conversion: %w", failure(FailureEncode, err))
+		}
+	}
+
+	if err := c.storage.PutFile(ctx, newURL, bts); err != nil {
+		return fmt.Errorf("conversion: %w", failure(FailureStorage, err))
 	}
 
 	newWidth, newHeight := getResolution(img)
 
-	err = c.repo.AddProcessedImage(ctx, info.UserID, reqID, &newImgInfo,
+	err = c.repo.AddProcessedImage(ctx, info.UserID, reqID, c.workerID, &newImgInfo,
 		newWidth, newHeight, repository.StatusDone, time.Now())
 	if err != nil {
-		return fmt.Errorf("update repo with image: %w", err)
+		return c.cleanup(ctx, fmt.Errorf("update repo with image: %w", failure(FailureDatabase, err)), newURL)
+	}
+
+	return nil
+}
+
+// ErrSourceDeleted is returned when the source image of the request is deleted before the conversion.
+var ErrSourceDeleted = errors.New("source image is deleted")
+
+// getImage method gets the original image from the storage and decodes it.
+// Svg images are rasterized with the size from the options. The request, which source image is deleted,
+// is invalid and isn't retried.
+func (c *ConvertRequest) getImage(ctx context.Context, info *model.ConvImageInfo) (image.Image, error) {
+	if info.OldURL == "" {
+		return nil, fmt.Errorf("get image: %w", failure(FailureInvalid, ErrSourceDeleted))
+	}
+
+	bts, err := c.storage.GetFile(ctx, info.OldURL)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", failure(FailureStorage, err))
+	}
+
+	if info.OldType == svgType {
+		width, height := info.Options.Width, info.Options.Height
+
+		// The box of the fit is applied after rasterization.
+		if info.Options.Fit != "" {
+			width, height = 0, 0
+		}
+
+		img, err := conversion.RasterizeSVG(bts, width, height, info.Options.DPI)
+
+		return img, failure(FailureDecode, err)
+	}
+
+	img, err := decodeImage(bytes.NewBuffer(bts), info.OldType)
+
+	return img, failure(FailureDecode, err)
+}
+
+// getUserImages method gets user's images with provided ids from the storage and decodes them.
+// Images are returned in order of ids, each image is downscaled to fit into the box right after decoding.
+// Svg images are rasterized to the width of the box. Requests, which images have more than
+// maxSourcePixels pixels in total, are refused.
+func (c *ConvertRequest) getUserImages(ctx context.Context, userID int, imageIDs []int,
+	box image.Point) ([]image.Image, error) {
+	infos, err := c.repo.GetImages(ctx, userID, uniqueIDs(imageIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get images: %w", failure(FailureDatabase, err))
+	}
+
+	images := make([]image.Image, 0, len(imageIDs))
+	pixels := 0
+
+	for _, id := range imageIDs {
+		imInfo, ok := infos[id]
+		if !ok {
+			return nil, fmt.Errorf("get images: %w", &NotOwnedImagesError{[]int{id}})
+		}
+
+		bts, err := c.storage.GetFile(ctx, imInfo.URL)
+		if err != nil {
+			return nil, fmt.Errorf("get images: %w", failure(FailureStorage, err))
+		}
+
+		img, err := decodeSourceImage(bts, imInfo.Type, box.X, &pixels)
+		if err != nil {
+			return nil, fmt.Errorf("get images: image %v: %w", id, err)
+		}
+
+		if img.Bounds().Dx() > box.X || img.Bounds().Dy() > box.Y {
+			img = imaging.Fit(img, box.X, box.Y, imaging.Lanczos)
+		}
+
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+// decodeSourceImage function decodes the image and adds its pixels to the counted ones.
+// Size of png and jpeg images is checked before decoding, svg images are rasterized to the width.
+func decodeSourceImage(bts []byte, imgType string, svgWidth int, pixels *int) (image.Image, error) {
+	if imgType == svgType {
+		img, err := conversion.RasterizeSVG(bts, svgWidth, 0, 0)
+		if err != nil {
+			return nil, failure(FailureDecode, err)
+		}
+
+		return img, countSourcePixels(pixels, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	cfg, err := decodeImageConfig(bytes.NewReader(bts), imgType)
+	if err != nil {
+		return nil, failure(FailureDecode, err)
+	}
+
+	if err := countSourcePixels(pixels, cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	img, err := decodeImage(bytes.NewReader(bts), imgType)
+
+	return img, failure(FailureDecode, err)
+}
+
+// countSourcePixels function adds pixels of the image to the counted ones.
+// Returns InvalidOptionError if there are more than maxSourcePixels pixels.
+func countSourcePixels(pixels *int, width, height int) error {
+	*pixels += width * height
+	if *pixels > maxSourcePixels {
+		return &InvalidOptionError{"imageIDs", fmt.Sprintf("images have more than %v pixels", maxSourcePixels)}
 	}
 
 	return nil
 }
 
-func (c *ConvertRequest) getImage(ctx context.Context, url, fileType string) (image.Image, error) {
-	bts, err := c.storage.GetFile(ctx, url)
+// fitImage function places the image in the box from the options.
+// Returns processed image and information about the made crop.
+func fitImage(img image.Image, opts *model.ConversionOptions) (image.Image, *model.CropResult, error) {
+	anchor, err := conversion.ParseAnchor(opts.Anchor)
 	if err != nil {
-		return nil, fmt.Errorf("get image: %w", err)
+		return nil, nil, err
+	}
+
+	var window image.Rectangle
+
+	switch opts.Fit {
+	case fitFill:
+		img, window, err = conversion.Fill(img, opts.Width, opts.Height, anchor)
+	case fitCrop:
+		img, window, err = conversion.Crop(img, opts.Width, opts.Height, anchor)
+	default:
+		err = &InvalidOptionError{"fit", fmt.Sprintf("unknown fit %q", opts.Fit)}
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("fit image: %w", err)
+	}
+
+	crop := &model.CropResult{
+		Anchor: string(anchor),
+		X:      window.Min.X,
+		Y:      window.Min.Y,
+		Width:  window.Dx(),
+		Height: window.Dy(),
 	}
 
-	return decodeImage(bytes.NewBuffer(bts), fileType)
+	return img, crop, nil
+}
+
+// Width of the tiny preview, which is used as placeholder.
+const previewWidth = 16
+
+// placeholders function returns BlurHash of the image and
+// tiny preview of the image encoded as base64 png data url.
+func placeholders(img image.Image) (blurHash, preview string, err error) {
+	blurHash, err = conversion.BlurHash(img)
+	if err != nil {
+		return "", "", fmt.Errorf("blurhash: %w", err)
+	}
+
+	bts, err := encodeImage(conversion.ResizeToWidth(img, previewWidth), pngType)
+	if err != nil {
+		return "", "", fmt.Errorf("preview: %w", err)
+	}
+
+	preview = "data:image/png;base64," + base64.StdEncoding.EncodeToString(bts)
+
+	return blurHash, preview, nil
 }
@@ -0,0 +1,566 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertRequest_ConvertFailure(t *testing.T) {
+	pngTestImage := loadImage(t, "test_data/x.png")
+	info := &model.ConvImageInfo{
+		Kind:    "conversion",
+		UserID:  1,
+		OldImID: 2,
+		OldURL:  "x.png",
+		OldType: "png",
+		NewType: "jpeg",
+		Ratio:   1,
+	}
+
+	testCases := []struct {
+		testName  string
+		attempt   int
+		initMock  func(*mocks.MockConvertRepo, *mocks.MockStorager)
+		wantCode  string
+		wantRetry bool
+		failErr   error
+		wantErr   error
+	}{
+		{
+			testName: "request is not found",
+			attempt:  3,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(nil, errRepository)
+			},
+			wantCode:  service.FailureDatabase,
+			wantRetry: true,
+			wantErr:   errRepository,
+		},
+		{
+			testName: "original is lost",
+			attempt:  1,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(nil, errStorage)
+			},
+			wantCode:  service.FailureStorage,
+			wantRetry: true,
+			wantErr:   errStorage,
+		},
+		{
+			testName: "original is lost at the last attempt",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(nil, errStorage)
+			},
+			wantCode: service.FailureStorage,
+			wantErr:  errStorage,
+		},
+		{
+			testName: "source image is deleted",
+			attempt:  1,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&model.ConvImageInfo{
+					Kind: "conversion", UserID: 1, OldImID: 2, NewType: "jpeg", Ratio: 1,
+				}, nil)
+			},
+			wantCode: service.FailureInvalid,
+			wantErr:  service.ErrSourceDeleted,
+		},
+		{
+			testName: "original is broken",
+			attempt:  1,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return([]byte("not png"), nil)
+			},
+			wantCode: service.FailureDecode,
+		},
+		{
+			testName: "invalid options",
+			attempt:  1,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				badInfo := *info
+				badInfo.Options.Fit = "unknown"
+
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&badInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantCode: service.FailureInvalid,
+		},
+		{
+			testName: "processed image is not saved",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), "processed/12/x.jpeg", gomock.Any()).Return(errStorage)
+			},
+			wantCode: service.FailureStorage,
+			wantErr:  errStorage,
+		},
+		{
+			testName: "processed image is not added to repo",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), "processed/12/x.jpeg", gomock.Any()).Return(nil)
+				mRep.EXPECT().AddProcessedImage(gomock.Any(), 1, 12, "converter-1", &model.ReuquestImageInfo{
+					URL:  "processed/12/x.jpeg",
+					Type: "jpeg",
+				}, gomock.Any(), gomock.Any(), "done", gomock.Any()).Return(errRepository)
+				mStor.EXPECT().DeleteFile(gomock.Any(), "processed/12/x.jpeg").Return(nil)
+			},
+			wantCode: service.FailureDatabase,
+			wantErr:  errRepository,
+		},
+		{
+			testName: "favicon icon is not saved",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				faviconInfo := *info
+				faviconInfo.NewType = "favicon"
+
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&faviconInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), "processed/12/apple-touch-icon.png", gomock.Any()).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), "processed/12/android-chrome-192x192.png", gomock.Any()).
+					Return(errStorage)
+				mStor.EXPECT().DeleteFile(gomock.Any(), "processed/12/apple-touch-icon.png").Return(nil)
+			},
+			wantCode: service.FailureStorage,
+			wantErr:  errStorage,
+		},
+		{
+			testName: "favicon ico is not saved",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				faviconInfo := *info
+				faviconInfo.NewType = "favicon"
+				icons := []string{"processed/12/apple-touch-icon.png", "processed/12/android-chrome-192x192.png",
+					"processed/12/android-chrome-512x512.png"}
+
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&faviconInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+
+				for _, icon := range icons {
+					mStor.EXPECT().PutFile(gomock.Any(), icon, gomock.Any()).Return(nil)
+				}
+
+				mRep.EXPECT().AddArtifacts(gomock.Any(), 1, 12, gomock.Len(len(icons))).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), "processed/12/x.ico", gomock.Any()).Return(errStorage)
+				mRep.EXPECT().DeleteArtifacts(gomock.Any(), 12).Return(nil)
+
+				for _, icon := range icons {
+					mStor.EXPECT().DeleteFile(gomock.Any(), icon).Return(nil)
+				}
+			},
+			wantCode: service.FailureStorage,
+			wantErr:  errStorage,
+		},
+		{
+			testName: "tile is not saved",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				dziInfo := *info
+				dziInfo.NewType = "dzi"
+
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&dziInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mRep.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+				mStor.EXPECT().PutFile(gomock.Any(), gomock.Any(), gomock.Any()).Return(errStorage)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "dzi/12/").Return(nil)
+			},
+			wantCode: service.FailureStorage,
+			wantErr:  errStorage,
+		},
+		{
+			testName: "contact sheet images have too many pixels",
+			attempt:  1,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				sheetInfo := *info
+				sheetInfo.Kind = repository.KindContactSheet
+				sheetInfo.NewType = "png"
+				sheetInfo.Options.ImageIDs = []int{2, 3}
+				sheetInfo.Options.Sheet = &model.SheetLayout{Columns: 2, CellWidth: 100, CellHeight: 100}
+				images := map[int]model.ReuquestImageInfo{2: {URL: "x.png", Type: "png"}, 3: {URL: "big.png", Type: "png"}}
+
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(&sheetInfo, nil)
+				mRep.EXPECT().GetImages(gomock.Any(), 1, []int{2, 3}).Return(images, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "big.png").Return(pngHeader(t, 16000, 16000), nil)
+			},
+			wantCode: service.FailureInvalid,
+		},
+		{
+			testName: "failed status is not saved",
+			attempt:  5,
+			initMock: func(mRep *mocks.MockConvertRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(nil, errStorage)
+			},
+			wantCode: service.FailureStorage,
+			failErr:  errRepository,
+			wantErr:  errStorage,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockConvertRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(tc.attempt, nil)
+			tc.initMock(mockRepo, mockStorage)
+
+			var message string
+
+			if tc.wantRetry {
+				mockRepo.EXPECT().RetryRequest(gomock.Any(), 12, "converter-1", tc.wantCode, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _, _, msg string) error {
+						message = msg
+
+						return nil
+					})
+			} else {
+				mockRepo.EXPECT().SetRequestFailed(gomock.Any(), 12, "converter-1", tc.wantCode, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int, _, _, msg string, _ time.Time) error {
+						message = msg
+
+						return tc.failErr
+					})
+			}
+
+			conv := service.NewConvertRequest(mockRepo, mockStorage,
+				&service.ConvertConfig{WorkerID: "converter-1", MaxAttempts: 5})
+			gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+			assert.Error(t, gotErr)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+
+			var retryErr *service.RetryError
+			assert.Equal(t, tc.wantRetry, errors.As(gotErr, &retryErr))
+
+			if tc.wantRetry {
+				assert.Equal(t, tc.attempt, retryErr.Attempt)
+			}
+
+			assert.NotEmpty(t, message)
+		})
+	}
+}
+
+// pngHeader function returns the beginning of the png image with the provided size.
+// It's enough to decode the size of the image, but not the image itself.
+func pngHeader(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signature is followed by the IHDR chunk: length, type, width, height, the rest of the data and crc.
+	header := buf.Bytes()[:33]
+	binary.BigEndian.PutUint32(header[16:20], uint32(width))
+	binary.BigEndian.PutUint32(header[20:24], uint32(height))
+	binary.BigEndian.PutUint32(header[29:33], crc32.ChecksumIEEE(header[12:29]))
+
+	return header
+}
+
+func TestConvertRequest_ConvertNotStarted(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		startErr  error
+		wantErr   error
+		wantErrAs interface{}
+		wantRetry bool
+	}{
+		{
+			testName: "request is done",
+			startErr: &repository.IllegalTransitionError{ReqID: 12, From: "done", To: "processing"},
+		},
+		{
+			testName: "request is failed",
+			startErr: &repository.IllegalTransitionError{ReqID: 12, From: "failed", To: "processing"},
+		},
+		{
+			testName: "request is cancelled",
+			startErr: &repository.IllegalTransitionError{ReqID: 12, From: "cancelled", To: "processing"},
+		},
+		{
+			testName:  "redelivered while request is processing",
+			startErr:  &repository.IllegalTransitionError{ReqID: 12, From: "processing", To: "processing"},
+			wantErrAs: new(*repository.IllegalTransitionError),
+			wantRetry: true,
+		},
+		{
+			testName:  "request is in unknown status",
+			startErr:  &repository.IllegalTransitionError{ReqID: 12, From: "unknown", To: "processing"},
+			wantErrAs: new(*repository.IllegalTransitionError),
+		},
+		{
+			testName:  "error in repository",
+			startErr:  errRepository,
+			wantErr:   errRepository,
+			wantRetry: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockConvertRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(0, tc.startErr)
+
+			conv := service.NewConvertRequest(mockRepo, mockStorage, &service.ConvertConfig{WorkerID: "converter-1"})
+			gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+			switch {
+			case tc.wantErrAs != nil:
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			default:
+				assert.NoError(t, gotErr)
+			}
+
+			var retryErr *service.RetryError
+			assert.Equal(t, tc.wantRetry, errors.As(gotErr, &retryErr))
+		})
+	}
+}
+
+func TestConvertRequest_ConvertReaped(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().Heartbeat(gomock.Any(), 12, "converter-1", gomock.Any()).
+		Return(&repository.NotSingleRowAffectedError{})
+	// Processing is stopped by the cancelled context and the request isn't failed.
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).
+		DoAndReturn(func(ctx context.Context, _ int) (*model.ConvImageInfo, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		})
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage,
+		&service.ConvertConfig{WorkerID: "converter-1", HeartbeatInterval: time.Millisecond})
+	gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+	assert.NoError(t, gotErr)
+}
+
+func TestConvertRequest_ConvertTimeout(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+	unblock := make(chan struct{})
+
+	defer close(unblock)
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	// Step, which doesn't take the context, is left in background, request is failed without retries.
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).
+		DoAndReturn(func(context.Context, int) (*model.ConvImageInfo, error) {
+			<-unblock
+
+			return nil, errRepository
+		})
+	mockRepo.EXPECT().
+		SetRequestFailed(gomock.Any(), 12, "converter-1", service.FailureTimeout, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage,
+		&service.ConvertConfig{WorkerID: "converter-1", Timeout: time.Millisecond, Concurrency: 1})
+	gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+	assert.ErrorIs(t, gotErr, context.DeadlineExceeded)
+
+	var retryErr *service.RetryError
+	assert.False(t, errors.As(gotErr, &retryErr))
+
+	// Conversion left in background holds the slot, so the next request isn't started.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	gotErr = conv.Convert(ctx, 13, "y.png")
+	assert.ErrorIs(t, gotErr, context.DeadlineExceeded)
+}
+
+func TestConvertRequest_ConvertShutdown(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).
+		DoAndReturn(func(ctx context.Context, _ int) (*model.ConvImageInfo, error) {
+			cancel()
+
+			return nil, ctx.Err()
+		})
+	// Request is returned to the queue with the context, which isn't cancelled.
+	mockRepo.EXPECT().ReleaseRequest(gomock.Any(), 12, "converter-1").
+		DoAndReturn(func(ctx context.Context, _ int, _ string) error {
+			return ctx.Err()
+		})
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage, &service.ConvertConfig{WorkerID: "converter-1"})
+	gotErr := conv.Convert(ctx, 12, "x.png")
+
+	assert.ErrorIs(t, gotErr, context.Canceled)
+
+	var retryErr *service.RetryError
+	assert.False(t, errors.As(gotErr, &retryErr))
+}
+
+func TestConvertRequest_ConvertCancelled(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+
+	pngTestImage := loadImage(t, "test_data/x.png")
+	info := &model.ConvImageInfo{
+		Kind:    "conversion",
+		UserID:  1,
+		OldImID: 2,
+		OldURL:  "x.png",
+		OldType: "png",
+		NewType: "jpeg",
+		Ratio:   1,
+	}
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+	mockStorage.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+	mockRepo.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+	mockStorage.EXPECT().PutFile(gomock.Any(), "processed/12/x.jpeg", gomock.Any()).Return(nil)
+	// Request is cancelled before the next heartbeat, output is deleted and the request isn't failed.
+	mockRepo.EXPECT().AddProcessedImage(gomock.Any(), 1, 12, "converter-1", gomock.Any(), gomock.Any(), gomock.Any(),
+		"done", gomock.Any()).Return(&repository.IllegalTransitionError{ReqID: 12, From: "cancelled", To: "done"})
+	mockStorage.EXPECT().DeleteFile(gomock.Any(), "processed/12/x.jpeg").Return(nil)
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage, &service.ConvertConfig{WorkerID: "converter-1"})
+	gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+	assert.NoError(t, gotErr)
+}
+
+func TestConvertRequest_ConvertLostOwnership(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+
+	pngTestImage := loadImage(t, "test_data/x.png")
+	info := &model.ConvImageInfo{
+		Kind:    "conversion",
+		UserID:  1,
+		OldImID: 2,
+		OldURL:  "x.png",
+		OldType: "png",
+		NewType: "jpeg",
+		Ratio:   1,
+	}
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+	mockStorage.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+	mockRepo.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+	mockStorage.EXPECT().PutFile(gomock.Any(), "processed/12/x.jpeg", gomock.Any()).Return(nil)
+	// Request is reaped and claimed by another worker before the next heartbeat,
+	// output is deleted and the request isn't retried or failed by this worker.
+	mockRepo.EXPECT().AddProcessedImage(gomock.Any(), 1, 12, "converter-1", gomock.Any(), gomock.Any(), gomock.Any(),
+		"done", gomock.Any()).Return(&repository.LostOwnershipError{ReqID: 12, WorkerID: "converter-1"})
+	mockStorage.EXPECT().DeleteFile(gomock.Any(), "processed/12/x.jpeg").Return(nil)
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage, &service.ConvertConfig{WorkerID: "converter-1"})
+	gotErr := conv.Convert(context.Background(), 12, "x.png")
+
+	assert.NoError(t, gotErr)
+}
+
+func TestConvertRequest_ConvertTilesCleanup(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockRepo := mocks.NewMockConvertRepo(mockCtr)
+	mockStorage := mocks.NewMockStorager(mockCtr)
+
+	pngTestImage := loadImage(t, "test_data/x.png")
+	info := &model.ConvImageInfo{
+		Kind:    "conversion",
+		UserID:  1,
+		OldImID: 2,
+		OldURL:  "x.png",
+		OldType: "png",
+		NewType: "dzi",
+		Ratio:   1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockRepo.EXPECT().StartProcessing(gomock.Any(), 12, "converter-1", gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetConvInfo(gomock.Any(), 12).Return(info, nil)
+	mockStorage.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+	mockRepo.EXPECT().SetImageResolution(gomock.Any(), 2, gomock.Any(), gomock.Any()).Return(nil)
+	mockStorage.EXPECT().PutFile(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockRepo.EXPECT().AddProcessedImage(gomock.Any(), 1, 12, "converter-1", gomock.Any(), gomock.Any(), gomock.Any(),
+		"done", gomock.Any()).DoAndReturn(func(context.Context, int, int, string, *model.ReuquestImageInfo, int, int,
+		string, time.Time) error {
+		cancel()
+
+		return errRepository
+	})
+	// Tiles are deleted with the context, which isn't cancelled with the processing.
+	mockStorage.EXPECT().DeleteFiles(gomock.Any(), "dzi/12/").DoAndReturn(func(ctx context.Context, _ string) error {
+		assert.NoError(t, ctx.Err())
+
+		return nil
+	})
+	mockRepo.EXPECT().ReleaseRequest(gomock.Any(), 12, "converter-1").Return(nil)
+
+	conv := service.NewConvertRequest(mockRepo, mockStorage, &service.ConvertConfig{WorkerID: "converter-1"})
+	gotErr := conv.Convert(ctx, 12, "x.png")
+
+	assert.ErrorIs(t, gotErr, context.Canceled)
+}
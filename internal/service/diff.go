@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// Diff compares two user's images and returns the image with the changed pixels highlighted
+// and the metrics of the change. Images with different sizes are compared according to the policy.
+func (t *Transform) Diff(ctx context.Context, userID int, info model.DiffInfo) (*model.DiffResult, error) {
+	if info.Threshold < 0 || info.Threshold > math.MaxUint8 {
+		return nil, fmt.Errorf("diff: %w",
+			&InvalidOptionError{"threshold", fmt.Sprintf("should be between 0 and %v", math.MaxUint8)})
+	}
+
+	firstInfo, err := t.repo.GetImage(ctx, userID, info.FirstID)
+	if err != nil {
+		return nil, fmt.Errorf("diff: image %v: %w", info.FirstID, err)
+	}
+
+	secondInfo, err := t.repo.GetImage(ctx, userID, info.SecondID)
+	if err != nil {
+		return nil, fmt.Errorf("diff: image %v: %w", info.SecondID, err)
+	}
+
+	first, err := t.getImage(ctx, firstInfo)
+	if err != nil {
+		return nil, fmt.Errorf("diff: %w", err)
+	}
+
+	second, err := t.getImage(ctx, secondInfo)
+	if err != nil {
+		return nil, fmt.Errorf("diff: %w", err)
+	}
+
+	diff, err := conversion.Diff(first, second, conversion.DiffPolicy(info.Policy), uint8(info.Threshold))
+	if err != nil {
+		return nil, fmt.Errorf("diff: %w", err)
+	}
+
+	bts, err := encodeImage(diff.Image, pngType)
+	if err != nil {
+		return nil, fmt.Errorf("diff: %w", err)
+	}
+
+	width, height := getResolution(diff.Image)
+
+	return &model.DiffResult{
+		Width:          width,
+		Height:         height,
+		ChangedPixels:  diff.ChangedPixels,
+		ChangedPercent: diff.ChangedPercent(),
+		MaxDelta:       int(diff.MaxDelta),
+		Image:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(bts),
+	}, nil
+}
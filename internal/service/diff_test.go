@@ -0,0 +1,114 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform_Diff(t *testing.T) {
+	pngTestImage := loadImage(t, "test_data/x.png")
+	firstInfo := &model.ReuquestImageInfo{Type: "png", URL: "first.png"}
+	secondInfo := &model.ReuquestImageInfo{Type: "png", URL: "second.png"}
+
+	testCases := []struct {
+		testName     string
+		info         model.DiffInfo
+		initMock     func(*mocks.MockTransformRepo, *mocks.MockStorager)
+		wantChanged  int
+		wantMaxDelta int
+		wantErr      error
+		wantErrAs    interface{}
+	}{
+		{
+			testName: "same images",
+			info:     model.DiffInfo{FirstID: 1, SecondID: 2},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 1).Return(firstInfo, nil)
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 2).Return(secondInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "first.png").Return(pngTestImage, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "second.png").Return(pngTestImage, nil)
+			},
+			wantChanged:  0,
+			wantMaxDelta: 0,
+		},
+		{
+			testName: "image of another user",
+			info:     model.DiffInfo{FirstID: 1, SecondID: 2},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 1).Return(firstInfo, nil)
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 2).Return(nil, errRepository)
+			},
+			wantErr: errRepository,
+		},
+		{
+			testName:  "invalid threshold",
+			info:      model.DiffInfo{FirstID: 1, SecondID: 2, Threshold: 300},
+			initMock:  func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "unknown policy",
+			info:     model.DiffInfo{FirstID: 1, SecondID: 2, Policy: "blend"},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 1).Return(firstInfo, nil)
+				mRep.EXPECT().GetImage(gomock.Any(), 5, 2).Return(secondInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "first.png").Return(pngTestImage, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "second.png").Return(pngTestImage, nil)
+			},
+			wantErrAs: new(*conversion.UnknownDiffPolicyError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockTransformRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			tc.initMock(mockRepo, mockStorage)
+
+			srvc := service.NewTransform(mockRepo, mockStorage)
+
+			got, gotErr := srvc.Diff(context.Background(), 5, tc.info)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+
+				return
+			}
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			assert.Equal(t, tc.wantChanged, got.ChangedPixels)
+			assert.Equal(t, tc.wantMaxDelta, got.MaxDelta)
+
+			prefix := "data:image/png;base64,"
+			require.True(t, strings.HasPrefix(got.Image, prefix))
+
+			bts, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(got.Image, prefix))
+			require.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(bts))
+			require.NoError(t, err)
+			assert.Equal(t, got.Width, img.Bounds().Dx())
+			assert.Equal(t, got.Height, img.Bounds().Dy())
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+	"github.com/Dyleme/image-coverter/internal/storage"
+)
+
+const (
+	// dziType is used to make deep-zoom tile pyramid with its descriptor.
+	dziType = "dzi"
+
+	defaultTileSize = 254
+	maxTileSize     = 4096
+
+	// Name of the descriptor, tiles are stored in the directory with the same name and "_files" suffix.
+	dziName = "image"
+)
+
+var ErrNotTiled = errors.New("request has no tiles")
+
+var ErrTileNotExist = errors.New("tile not exist")
+
+// tileLayout function returns the tile layout and tile format from the options with the defaults applied.
+func tileLayout(opts *model.TileOptions) (conversion.TileLayout, string, error) {
+	layout := conversion.TileLayout{TileSize: defaultTileSize}
+	format := jpegType
+
+	if opts == nil {
+		return layout, format, nil
+	}
+
+	if opts.TileSize != 0 {
+		layout.TileSize = opts.TileSize
+	}
+
+	if opts.Format != "" {
+		format = opts.Format
+	}
+
+	layout.Overlap = opts.Overlap
+
+	switch {
+	case layout.TileSize <= 0 || layout.TileSize > maxTileSize:
+		return layout, "", &InvalidOptionError{"tileSize", fmt.Sprintf("should be between 1 and %v", maxTileSize)}
+	case layout.Overlap < 0 || 2*layout.Overlap > layout.TileSize:
+		return layout, "", &InvalidOptionError{"overlap", "should be between 0 and half of the tile size"}
+	case format != jpegType && format != pngType:
+		return layout, "", &InvalidOptionError{"format", fmt.Sprintf("unsupported tile format %q", format)}
+	}
+
+	return layout, format, nil
+}
+
+// tileExtension function returns the extension of the tile files, which is written to the descriptor.
+func tileExtension(format string) string {
+	if format == jpegType {
+		return "jpg"
+	}
+
+	return format
+}
+
+// dziPrefix function returns the storage prefix where descriptor and tiles of the request are stored.
+func dziPrefix(reqID int) string {
+	return fmt.Sprintf("dzi/%d/", reqID)
+}
+
+// tilePath function returns the storage path of the tile.
+func tilePath(reqID, level, col, row int, format string) string {
+	return fmt.Sprintf("%s%s_files/%d/%d_%d.%s", dziPrefix(reqID), dziName, level, col, row, tileExtension(format))
+}
+
+// isDZIDescriptor function reports whether the url is the path to the deep-zoom descriptor.
+func isDZIDescriptor(url string) bool {
+	return strings.HasPrefix(url, "dzi/") && path.Ext(url) == "."+dziType
+}
+
+// saveTilePyramid method cuts the image to the deep-zoom tiles and puts them to the storage
+// under the prefix of the request. Descriptor is added to the repo as the processed image of the request.
+// Tiles, which are already put to the storage, are deleted, if the pyramid isn't saved.
+func (c *ConvertRequest) saveTilePyramid(ctx context.Context, reqID int,
+	info *model.ConvImageInfo, img image.Image) error {
+	layout, format, err := tileLayout(info.Options.Tiles)
+	if err != nil {
+		return fmt.Errorf("tile pyramid: %w", err)
+	}
+
+	if err := c.putTilePyramid(ctx, reqID, info, img, layout, format); err != nil {
+		return c.cleanupPrefix(ctx, fmt.Errorf("tile pyramid: %w", err), dziPrefix(reqID))
+	}
+
+	return nil
+}
+
+// putTilePyramid method puts the tiles and the descriptor to the storage and adds the descriptor to the repo.
+func (c *ConvertRequest) putTilePyramid(ctx context.Context, reqID int, info *model.ConvImageInfo,
+	img image.Image, layout conversion.TileLayout, format string) error {
+	err := conversion.Tiles(img, layout, func(level, col, row int, tile image.Image) error {
+		bts, err := encodeImage(tile, format)
+		if err != nil {
+			return failure(FailureEncode, err)
+		}
+
+		return failure(FailureStorage, c.storage.PutFile(ctx, tilePath(reqID, level, col, row, format), bts))
+	})
+	if err != nil {
+		return err
+	}
+
+	width, height := getResolution(img)
+
+	descriptor, err := conversion.DZIDescriptor(width, height, layout, tileExtension(format))
+	if err != nil {
+		return failure(FailureEncode, err)
+	}
+
+	descriptorPath := dziPrefix(reqID) + dziName + "." + dziType
+
+	if err := c.storage.PutFile(ctx, descriptorPath, descriptor); err != nil {
+		return failure(FailureStorage, err)
+	}
+
+	descriptorInfo := model.ReuquestImageInfo{
+		URL:  descriptorPath,
+		Type: dziType,
+	}
+
+	err = c.repo.AddProcessedImage(ctx, info.UserID, reqID, c.workerID, &descriptorInfo,
+		width, height, repository.StatusDone, time.Now())
+	if err != nil {
+		return fmt.Errorf("update repo with tiles: %w", failure(FailureDatabase, err))
+	}
+
+	return nil
+}
+
+// GetTile returns the bytes of the deep-zoom tile and its file name.
+// Request should belong to the user and be processed to the tile pyramid, otherwise ErrNotTiled is returned.
+// ErrTileNotExist is returned for the tile out of the pyramid.
+func (s *Request) GetTile(ctx context.Context, userID, reqID, level, col, row int) ([]byte, string, error) {
+	req, err := s.repo.GetRequest(ctx, userID, reqID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get tile: %w", err)
+	}
+
+	if req.ProcessedType != dziType || req.OpStatus != repository.StatusDone {
+		return nil, "", fmt.Errorf("get tile: %w", ErrNotTiled)
+	}
+
+	_, format, err := tileLayout(req.Options.Tiles)
+	if err != nil {
+		return nil, "", fmt.Errorf("get tile: %w", err)
+	}
+
+	tile := tilePath(reqID, level, col, row, format)
+
+	bts, err := s.storage.GetFile(ctx, tile)
+	if errors.Is(err, storage.ErrFileNotExist) {
+		return nil, "", fmt.Errorf("get tile: %w", ErrTileNotExist)
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("get tile: %w", err)
+	}
+
+	return bts, path.Base(tile), nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Codes of the errors, which are saved with the failed request.
+const (
+	FailureDecode   = "decode_error"
+	FailureEncode   = "encode_error"
+	FailureStorage  = "storage_error"
+	FailureDatabase = "database_error"
+	FailureInvalid  = "invalid_request"
+	FailureInternal = "internal_error"
+	FailureTimeout  = "timeout"
+
+	// Code of the requests reaped after the heartbeat of the worker expired.
+	FailureHeartbeat = "heartbeat_expired"
+)
+
+// ProcessingError is an error of the request processing with the code of the failed stage.
+type ProcessingError struct {
+	Code string
+	Err  error
+}
+
+func (e *ProcessingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
+// RetryError is returned if the request processing failed with the transient error
+// and the request should be processed again.
+type RetryError struct {
+	// Attempt is the number of the failed attempt, it's zero if the processing wasn't started.
+	Attempt int
+	Err     error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("attempt %v: %v", e.Attempt, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary method reports that the request should be processed again.
+func (e *RetryError) Temporary() bool {
+	return true
+}
+
+// failure function marks the error with the code, nil error is returned as is.
+func failure(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ProcessingError{Code: code, Err: err}
+}
+
+// failureCode function returns the code of the error. Errors in the options,
+// which are not checked while adding the request, are treated as invalid request.
+func failureCode(err error) string {
+	var (
+		procErr     *ProcessingError
+		optionErr   *InvalidOptionError
+		notOwnedErr *NotOwnedImagesError
+		typeErr     UnsupportedTypeError
+	)
+
+	switch {
+	case errors.As(err, &procErr):
+		return procErr.Code
+	case errors.As(err, &optionErr), errors.As(err, &notOwnedErr), errors.As(err, &typeErr):
+		return FailureInvalid
+	default:
+		return FailureInternal
+	}
+}
+
+// transient function reports if the error could disappear at the next attempt.
+func transient(err error) bool {
+	code := failureCode(err)
+
+	return code == FailureStorage || code == FailureDatabase
+}
+
+// retry method queues the request again and saves the error of the attempt to the repo.
+// Returns RetryError, if the request is queued.
+func (c *ConvertRequest) retry(ctx context.Context, reqID, attempt int, err error) error {
+	if retryErr := c.repo.RetryRequest(ctx, reqID, c.workerID, failureCode(err), err.Error()); retryErr != nil {
+		return fmt.Errorf("%w (queue request again: %v)", err, retryErr)
+	}
+
+	return &RetryError{Attempt: attempt, Err: err}
+}
+
+// release method returns the request, which processing is cancelled, to the queued status.
+// It's done with the separate context, because the context of the processing is done.
+func (c *ConvertRequest) release(ctx context.Context, reqID int, err error) error {
+	settleCtx, cancel := settleContext(ctx)
+	defer cancel()
+
+	if relErr := c.repo.ReleaseRequest(settleCtx, reqID, c.workerID); relErr != nil {
+		return fmt.Errorf("%w (return request to the queue: %v)", err, relErr)
+	}
+
+	return fmt.Errorf("conversion: cancelled: %w", err)
+}
+
+// fail method saves the error of the request processing to the repo.
+// Returns the processing error, error of the saving is added to it.
+func (c *ConvertRequest) fail(ctx context.Context, reqID int, err error) error {
+	failErr := c.repo.SetRequestFailed(ctx, reqID, c.workerID, failureCode(err), err.Error(), time.Now())
+	if failErr != nil {
+		return fmt.Errorf("%w (set failed status: %v)", err, failErr)
+	}
+
+	return err
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// touchIcon is the png icon, which is made in the favicon bundle.
+type touchIcon struct {
+	name       string
+	size       int
+	background color.Color
+}
+
+// Touch icons of the favicon bundle. Apple icon is opaque, because ios doesn't support transparency.
+var touchIcons = []touchIcon{
+	{name: "apple-touch-icon", size: 180, background: color.White},
+	{name: "android-chrome-192x192", size: 192, background: color.Transparent},
+	{name: "android-chrome-512x512", size: 512, background: color.Transparent},
+}
+
+// saveFaviconBundle method makes favicon bundle from the image.
+// Png touch icons are put to the storage and added as artifacts of the request
+// and multi-resolution ico is added as the processed image.
+// Icons, which are already put to the storage or added to the repo, are deleted, if the bundle isn't saved.
+func (c *ConvertRequest) saveFaviconBundle(ctx context.Context, reqID int, filename string,
+	info *model.ConvImageInfo, img image.Image) error {
+	artifacts := make([]model.Artifact, 0, len(touchIcons))
+	urls := make([]string, 0, len(touchIcons))
+
+	for _, icon := range touchIcons {
+		bts, err := encodeImage(conversion.Square(img, icon.size, icon.background), pngType)
+		if err != nil {
+			return c.cleanup(ctx, fmt.Errorf("favicon bundle: %w", failure(FailureEncode, err)), urls...)
+		}
+
+		url := processedPath(reqID, icon.name+"."+pngType)
+
+		if err := c.storage.PutFile(ctx, url, bts); err != nil {
+			return c.cleanup(ctx, fmt.Errorf("favicon bundle: %w", failure(FailureStorage, err)), urls...)
+		}
+
+		urls = append(urls, url)
+		artifacts = append(artifacts, model.Artifact{
+			Name:   icon.name,
+			Type:   pngType,
+			Width:  icon.size,
+			Height: icon.size,
+			URL:    url,
+		})
+	}
+
+	if err := c.repo.AddArtifacts(ctx, info.UserID, reqID, artifacts); err != nil {
+		return c.cleanup(ctx, fmt.Errorf("favicon bundle: %w", failure(FailureDatabase, err)), urls...)
+	}
+
+	if err := c.saveProcessedImage(ctx, reqID, filename, info, img, icoType); err != nil {
+		return c.discardArtifacts(ctx, reqID, err, urls...)
+	}
+
+	return nil
+}
+
+// discardArtifacts method deletes the artifacts of the request, which processed image isn't saved,
+// so they aren't left attached to the failed request. Files are kept, if their rows aren't deleted.
+func (c *ConvertRequest) discardArtifacts(ctx context.Context, reqID int, err error, paths ...string) error {
+	settleCtx, cancel := settleContext(ctx)
+	defer cancel()
+
+	if delErr := c.repo.DeleteArtifacts(settleCtx, reqID); delErr != nil {
+		return fmt.Errorf("%w (delete artifacts: %v)", err, delErr) //nolint:errorlint // making combined error
+	}
+
+	return c.cleanup(ctx, err, paths...)
+}
+
+// addManifest function adds web manifest snippet with the touch icons to the favicon request.
+func addManifest(req *model.Request) {
+	if req.ProcessedType != faviconType || len(req.Artifacts) == 0 {
+		return
+	}
+
+	manifest := &model.WebManifest{}
+
+	for _, a := range req.Artifacts {
+		if a.Type != pngType {
+			continue
+		}
+
+		manifest.Icons = append(manifest.Icons, model.ManifestIcon{
+			Src:   fmt.Sprintf("/download/image/%d", a.ID),
+			Sizes: fmt.Sprintf("%dx%d", a.Width, a.Height),
+			Type:  "image/png",
+		})
+	}
+
+	req.Manifest = manifest
+}
@@ -0,0 +1,208 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Dyleme/image-coverter/internal/service (interfaces: ConvertRepo)
+
+// Package mock_service is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/Dyleme/image-coverter/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockConvertRepo is a mock of ConvertRepo interface.
+type MockConvertRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockConvertRepoMockRecorder
+}
+
+// MockConvertRepoMockRecorder is the mock recorder for MockConvertRepo.
+type MockConvertRepoMockRecorder struct {
+	mock *MockConvertRepo
+}
+
+// NewMockConvertRepo creates a new mock instance.
+func NewMockConvertRepo(ctrl *gomock.Controller) *MockConvertRepo {
+	mock := &MockConvertRepo{ctrl: ctrl}
+	mock.recorder = &MockConvertRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConvertRepo) EXPECT() *MockConvertRepoMockRecorder {
+	return m.recorder
+}
+
+// AddArtifacts mocks base method.
+func (m *MockConvertRepo) AddArtifacts(arg0 context.Context, arg1, arg2 int, arg3 []model.Artifact) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddArtifacts", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddArtifacts indicates an expected call of AddArtifacts.
+func (mr *MockConvertRepoMockRecorder) AddArtifacts(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddArtifacts", reflect.TypeOf((*MockConvertRepo)(nil).AddArtifacts), arg0, arg1, arg2, arg3)
+}
+
+// AddProcessedImage mocks base method.
+func (m *MockConvertRepo) AddProcessedImage(arg0 context.Context, arg1, arg2 int, arg3 string, arg4 *model.ReuquestImageInfo, arg5, arg6 int, arg7 string, arg8 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddProcessedImage", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddProcessedImage indicates an expected call of AddProcessedImage.
+func (mr *MockConvertRepoMockRecorder) AddProcessedImage(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddProcessedImage", reflect.TypeOf((*MockConvertRepo)(nil).AddProcessedImage), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+}
+
+// DeleteArtifacts mocks base method.
+func (m *MockConvertRepo) DeleteArtifacts(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteArtifacts", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteArtifacts indicates an expected call of DeleteArtifacts.
+func (mr *MockConvertRepoMockRecorder) DeleteArtifacts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteArtifacts", reflect.TypeOf((*MockConvertRepo)(nil).DeleteArtifacts), arg0, arg1)
+}
+
+// GetConvInfo mocks base method.
+func (m *MockConvertRepo) GetConvInfo(arg0 context.Context, arg1 int) (*model.ConvImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConvInfo", arg0, arg1)
+	ret0, _ := ret[0].(*model.ConvImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConvInfo indicates an expected call of GetConvInfo.
+func (mr *MockConvertRepoMockRecorder) GetConvInfo(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConvInfo", reflect.TypeOf((*MockConvertRepo)(nil).GetConvInfo), arg0, arg1)
+}
+
+// GetImages mocks base method.
+func (m *MockConvertRepo) GetImages(arg0 context.Context, arg1 int, arg2 []int) (map[int]model.ReuquestImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(map[int]model.ReuquestImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImages indicates an expected call of GetImages.
+func (mr *MockConvertRepoMockRecorder) GetImages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImages", reflect.TypeOf((*MockConvertRepo)(nil).GetImages), arg0, arg1, arg2)
+}
+
+// Heartbeat mocks base method.
+func (m *MockConvertRepo) Heartbeat(arg0 context.Context, arg1 int, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Heartbeat indicates an expected call of Heartbeat.
+func (mr *MockConvertRepoMockRecorder) Heartbeat(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockConvertRepo)(nil).Heartbeat), arg0, arg1, arg2, arg3)
+}
+
+// ReleaseRequest mocks base method.
+func (m *MockConvertRepo) ReleaseRequest(arg0 context.Context, arg1 int, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseRequest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseRequest indicates an expected call of ReleaseRequest.
+func (mr *MockConvertRepoMockRecorder) ReleaseRequest(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseRequest", reflect.TypeOf((*MockConvertRepo)(nil).ReleaseRequest), arg0, arg1, arg2)
+}
+
+// RetryRequest mocks base method.
+func (m *MockConvertRepo) RetryRequest(arg0 context.Context, arg1 int, arg2, arg3, arg4 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryRequest", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetryRequest indicates an expected call of RetryRequest.
+func (mr *MockConvertRepoMockRecorder) RetryRequest(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryRequest", reflect.TypeOf((*MockConvertRepo)(nil).RetryRequest), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SetImageResolution mocks base method.
+func (m *MockConvertRepo) SetImageResolution(arg0 context.Context, arg1, arg2, arg3 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImageResolution", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetImageResolution indicates an expected call of SetImageResolution.
+func (mr *MockConvertRepoMockRecorder) SetImageResolution(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImageResolution", reflect.TypeOf((*MockConvertRepo)(nil).SetImageResolution), arg0, arg1, arg2, arg3)
+}
+
+// SetRequestCrop mocks base method.
+func (m *MockConvertRepo) SetRequestCrop(arg0 context.Context, arg1 int, arg2 *model.CropResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRequestCrop", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRequestCrop indicates an expected call of SetRequestCrop.
+func (mr *MockConvertRepoMockRecorder) SetRequestCrop(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequestCrop", reflect.TypeOf((*MockConvertRepo)(nil).SetRequestCrop), arg0, arg1, arg2)
+}
+
+// SetRequestFailed mocks base method.
+func (m *MockConvertRepo) SetRequestFailed(arg0 context.Context, arg1 int, arg2, arg3, arg4 string, arg5 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRequestFailed", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRequestFailed indicates an expected call of SetRequestFailed.
+func (mr *MockConvertRepoMockRecorder) SetRequestFailed(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRequestFailed", reflect.TypeOf((*MockConvertRepo)(nil).SetRequestFailed), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// StartProcessing mocks base method.
+func (m *MockConvertRepo) StartProcessing(arg0 context.Context, arg1 int, arg2 string, arg3 time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartProcessing", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartProcessing indicates an expected call of StartProcessing.
+func (mr *MockConvertRepoMockRecorder) StartProcessing(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartProcessing", reflect.TypeOf((*MockConvertRepo)(nil).StartProcessing), arg0, arg1, arg2, arg3)
+}
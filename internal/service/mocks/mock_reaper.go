@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Dyleme/image-coverter/internal/service (interfaces: ReaperRepo)
+
+// Package mock_service is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/Dyleme/image-coverter/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockReaperRepo is a mock of ReaperRepo interface.
+type MockReaperRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockReaperRepoMockRecorder
+}
+
+// MockReaperRepoMockRecorder is the mock recorder for MockReaperRepo.
+type MockReaperRepoMockRecorder struct {
+	mock *MockReaperRepo
+}
+
+// NewMockReaperRepo creates a new mock instance.
+func NewMockReaperRepo(ctrl *gomock.Controller) *MockReaperRepo {
+	mock := &MockReaperRepo{ctrl: ctrl}
+	mock.recorder = &MockReaperRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReaperRepo) EXPECT() *MockReaperRepoMockRecorder {
+	return m.recorder
+}
+
+// ReapRequests mocks base method.
+func (m *MockReaperRepo) ReapRequests(arg0 context.Context, arg1 time.Time, arg2 int, arg3 string, arg4 time.Time) (*model.ReapResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReapRequests", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*model.ReapResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReapRequests indicates an expected call of ReapRequests.
+func (mr *MockReaperRepoMockRecorder) ReapRequests(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReapRequests", reflect.TypeOf((*MockReaperRepo)(nil).ReapRequests), arg0, arg1, arg2, arg3, arg4)
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/Dyleme/image-coverter/internal/model"
 	gomock "github.com/golang/mock/gomock"
@@ -36,28 +37,71 @@ func (m *MockRequestRepo) EXPECT() *MockRequestRepoMockRecorder {
 }
 
 // AddImageAndRequest mocks base method.
-func (m *MockRequestRepo) AddImageAndRequest(arg0 context.Context, arg1 int, arg2 *model.ReuquestImageInfo, arg3 *model.Request) (int, error) {
+func (m *MockRequestRepo) AddImageAndRequest(arg0 context.Context, arg1 int, arg2 *model.ReuquestImageInfo, arg3 *model.Request, arg4 string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddImageAndRequest", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "AddImageAndRequest", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AddImageAndRequest indicates an expected call of AddImageAndRequest.
-func (mr *MockRequestRepoMockRecorder) AddImageAndRequest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockRequestRepoMockRecorder) AddImageAndRequest(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImageAndRequest", reflect.TypeOf((*MockRequestRepo)(nil).AddImageAndRequest), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddImageAndRequest", reflect.TypeOf((*MockRequestRepo)(nil).AddImageAndRequest), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AddRequest mocks base method.
+func (m *MockRequestRepo) AddRequest(arg0 context.Context, arg1 int, arg2 *model.Request, arg3 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRequest", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddRequest indicates an expected call of AddRequest.
+func (mr *MockRequestRepoMockRecorder) AddRequest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRequest", reflect.TypeOf((*MockRequestRepo)(nil).AddRequest), arg0, arg1, arg2, arg3)
+}
+
+// CancelRequest mocks base method.
+func (m *MockRequestRepo) CancelRequest(arg0 context.Context, arg1, arg2 int, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRequest", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRequest indicates an expected call of CancelRequest.
+func (mr *MockRequestRepoMockRecorder) CancelRequest(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRequest", reflect.TypeOf((*MockRequestRepo)(nil).CancelRequest), arg0, arg1, arg2, arg3)
+}
+
+// CountImages mocks base method.
+func (m *MockRequestRepo) CountImages(arg0 context.Context, arg1 int, arg2 []int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountImages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountImages indicates an expected call of CountImages.
+func (mr *MockRequestRepoMockRecorder) CountImages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountImages", reflect.TypeOf((*MockRequestRepo)(nil).CountImages), arg0, arg1, arg2)
 }
 
 // DeleteRequestAndImage mocks base method.
-func (m *MockRequestRepo) DeleteRequestAndImage(arg0 context.Context, arg1, arg2 int) (string, string, error) {
+func (m *MockRequestRepo) DeleteRequestAndImage(arg0 context.Context, arg1, arg2 int) ([]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteRequestAndImage", arg0, arg1, arg2)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(string)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteRequestAndImage indicates an expected call of DeleteRequestAndImage.
@@ -66,6 +110,21 @@ func (mr *MockRequestRepoMockRecorder) DeleteRequestAndImage(arg0, arg1, arg2 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRequestAndImage", reflect.TypeOf((*MockRequestRepo)(nil).DeleteRequestAndImage), arg0, arg1, arg2)
 }
 
+// GetImage mocks base method.
+func (m *MockRequestRepo) GetImage(arg0 context.Context, arg1, arg2 int) (*model.ReuquestImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.ReuquestImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImage indicates an expected call of GetImage.
+func (mr *MockRequestRepoMockRecorder) GetImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockRequestRepo)(nil).GetImage), arg0, arg1, arg2)
+}
+
 // GetRequest mocks base method.
 func (m *MockRequestRepo) GetRequest(arg0 context.Context, arg1, arg2 int) (*model.Request, error) {
 	m.ctrl.T.Helper()
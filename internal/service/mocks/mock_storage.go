@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/Dyleme/image-coverter/pkg/service (interfaces: Storager)
+// Source: github.com/Dyleme/image-coverter/internal/service (interfaces: Storager)
 
 // Package mock_service is a generated GoMock package.
 package mocks
@@ -48,6 +48,20 @@ func (mr *MockStoragerMockRecorder) DeleteFile(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockStorager)(nil).DeleteFile), arg0, arg1)
 }
 
+// DeleteFiles mocks base method.
+func (m *MockStorager) DeleteFiles(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFiles", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFiles indicates an expected call of DeleteFiles.
+func (mr *MockStoragerMockRecorder) DeleteFiles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFiles", reflect.TypeOf((*MockStorager)(nil).DeleteFiles), arg0, arg1)
+}
+
 // GetFile mocks base method.
 func (m *MockStorager) GetFile(arg0 context.Context, arg1 string) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -63,6 +77,20 @@ func (mr *MockStoragerMockRecorder) GetFile(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFile", reflect.TypeOf((*MockStorager)(nil).GetFile), arg0, arg1)
 }
 
+// PutFile mocks base method.
+func (m *MockStorager) PutFile(arg0 context.Context, arg1 string, arg2 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutFile", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutFile indicates an expected call of PutFile.
+func (mr *MockStoragerMockRecorder) PutFile(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutFile", reflect.TypeOf((*MockStorager)(nil).PutFile), arg0, arg1, arg2)
+}
+
 // UploadFile mocks base method.
 func (m *MockStorager) UploadFile(arg0 context.Context, arg1 int, arg2 string, arg3 []byte) (string, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/Dyleme/image-coverter/internal/service (interfaces: TransformRepo)
+
+// Package mock_service is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/Dyleme/image-coverter/internal/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTransformRepo is a mock of TransformRepo interface.
+type MockTransformRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransformRepoMockRecorder
+}
+
+// MockTransformRepoMockRecorder is the mock recorder for MockTransformRepo.
+type MockTransformRepoMockRecorder struct {
+	mock *MockTransformRepo
+}
+
+// NewMockTransformRepo creates a new mock instance.
+func NewMockTransformRepo(ctrl *gomock.Controller) *MockTransformRepo {
+	mock := &MockTransformRepo{ctrl: ctrl}
+	mock.recorder = &MockTransformRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransformRepo) EXPECT() *MockTransformRepoMockRecorder {
+	return m.recorder
+}
+
+// AddDerivative mocks base method.
+func (m *MockTransformRepo) AddDerivative(arg0 context.Context, arg1 int, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDerivative", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDerivative indicates an expected call of AddDerivative.
+func (mr *MockTransformRepoMockRecorder) AddDerivative(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDerivative", reflect.TypeOf((*MockTransformRepo)(nil).AddDerivative), arg0, arg1, arg2, arg3)
+}
+
+// GetDerivativeURL mocks base method.
+func (m *MockTransformRepo) GetDerivativeURL(arg0 context.Context, arg1 int, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDerivativeURL", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDerivativeURL indicates an expected call of GetDerivativeURL.
+func (mr *MockTransformRepoMockRecorder) GetDerivativeURL(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDerivativeURL", reflect.TypeOf((*MockTransformRepo)(nil).GetDerivativeURL), arg0, arg1, arg2)
+}
+
+// GetImage mocks base method.
+func (m *MockTransformRepo) GetImage(arg0 context.Context, arg1, arg2 int) (*model.ReuquestImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImage", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*model.ReuquestImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImage indicates an expected call of GetImage.
+func (mr *MockTransformRepoMockRecorder) GetImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockTransformRepo)(nil).GetImage), arg0, arg1, arg2)
+}
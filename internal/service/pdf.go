@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
+)
+
+const (
+	pdfType = "pdf"
+
+	// Maximum amount of the pages in the one document.
+	maxPDFPages = 200
+
+	// Page size which is used if it isn't provided.
+	defaultPageSize = "a4"
+
+	// Resolution of the images on the page, points are 1/72 of inch.
+	pdfImageDPI  = 150
+	pointsInInch = 72
+
+	// Name of the file, which is used for the pdf document.
+	pdfDocumentName = "document"
+)
+
+// pageLayout function converts layout from the request to the layout used to write the pdf.
+func pageLayout(layout *model.PDFLayout) (conversion.PageLayout, error) {
+	name := layout.PageSize
+	if name == "" {
+		name = defaultPageSize
+	}
+
+	size, ok := conversion.PageSizes[name]
+	if !ok {
+		return conversion.PageLayout{}, &InvalidOptionError{"pageSize", fmt.Sprintf("unknown page size %q", name)}
+	}
+
+	width, height := size[0], size[1]
+	if layout.Landscape {
+		width, height = height, width
+	}
+
+	if layout.Margin < 0 || 2*layout.Margin >= math.Min(width, height) {
+		return conversion.PageLayout{}, &InvalidOptionError{"margin", "margins should fit the page"}
+	}
+
+	fit := conversion.PageFit(layout.Fit)
+
+	switch fit {
+	case "":
+		fit = conversion.PageFitContain
+	case conversion.PageFitContain, conversion.PageFitFill, conversion.PageFitStretch:
+	default:
+		return conversion.PageLayout{}, &InvalidOptionError{"fit", fmt.Sprintf("unknown fit %q", layout.Fit)}
+	}
+
+	return conversion.PageLayout{Width: width, Height: height, Margin: layout.Margin, Fit: fit}, nil
+}
+
+// AddPDFRequest returns the id of the added request or error if any occurs.
+// Request is made from the user's images, which were uploaded before.
+// Document is written by the converter like the other requests,
+// so the message to the converter is added with the request.
+func (s *Request) AddPDFRequest(ctx context.Context, userID int, info model.PDFInfo) (int, error) {
+	if len(info.ImageIDs) == 0 || len(info.ImageIDs) > maxPDFPages {
+		return 0, fmt.Errorf("add pdf: %w", &InvalidOptionError{"imageIDs",
+			fmt.Sprintf("amount of images should be between 1 and %v", maxPDFPages)})
+	}
+
+	if _, err := pageLayout(&info.PDFLayout); err != nil {
+		return 0, fmt.Errorf("add pdf: %w", err)
+	}
+
+	priority, err := requestPriority(info.Priority)
+	if err != nil {
+		return 0, fmt.Errorf("add pdf: %w", err)
+	}
+
+	if err := s.checkImagesOwner(ctx, userID, info.ImageIDs); err != nil {
+		return 0, fmt.Errorf("add pdf: %w", err)
+	}
+
+	layout := info.PDFLayout
+
+	req := model.Request{
+		Kind:          repository.KindPDF,
+		OpStatus:      repository.StatusQueued,
+		Priority:      priority,
+		RequestTime:   time.Now(),
+		Ratio:         1,
+		ProcessedType: pdfType,
+		Options: model.ConversionOptions{
+			ImageIDs: info.ImageIDs,
+			PDF:      &layout,
+		},
+	}
+
+	reqID, err := s.repo.AddRequest(ctx, userID, &req, pdfDocumentName+"."+pdfType)
+	if err != nil {
+		return 0, fmt.Errorf("repo add request: %w", err)
+	}
+
+	return reqID, nil
+}
+
+// savePDF method writes the images of the request to the pdf document,
+// uploads it to the storage and adds it to the repo as processed file of the request.
+// Width and height of the processed file are the page size in points.
+func (c *ConvertRequest) savePDF(ctx context.Context, reqID int, filename string, info *model.ConvImageInfo) error {
+	if info.Options.PDF == nil {
+		return &InvalidOptionError{"pdf", "layout is missing"}
+	}
+
+	layout, err := pageLayout(info.Options.PDF)
+	if err != nil {
+		return fmt.Errorf("pdf: %w", err)
+	}
+
+	// Images are downscaled to the resolution they have on the page.
+	box := image.Pt(int((layout.Width-2*layout.Margin)*pdfImageDPI/pointsInInch),
+		int((layout.Height-2*layout.Margin)*pdfImageDPI/pointsInInch))
+
+	images, err := c.getUserImages(ctx, info.UserID, info.Options.ImageIDs, box)
+	if err != nil {
+		return fmt.Errorf("pdf: %w", err)
+	}
+
+	doc, err := conversion.PDF(images, layout)
+	if err != nil {
+		return fmt.Errorf("pdf: %w", failure(FailureEncode, err))
+	}
+
+	newURL := processedPath(reqID, replaceExtension(filename, pdfType))
+
+	if err := c.storage.PutFile(ctx, newURL, doc); err != nil {
+		return fmt.Errorf("pdf: %w", failure(FailureStorage, err))
+	}
+
+	docInfo := model.ReuquestImageInfo{
+		URL:  newURL,
+		Type: pdfType,
+	}
+
+	err = c.repo.AddProcessedImage(ctx, info.UserID, reqID, c.workerID, &docInfo,
+		int(math.Round(layout.Width)), int(math.Round(layout.Height)), repository.StatusDone, time.Now())
+	if err != nil {
+		return c.cleanup(ctx, fmt.Errorf("update repo with pdf: %w", failure(FailureDatabase, err)), newURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// ReaperRepo is an interface which provides method to reap the requests abandoned by the workers.
+type ReaperRepo interface {
+	ReapRequests(ctx context.Context, expiredBefore time.Time, maxAttempts int,
+		code string, t time.Time) (*model.ReapResult, error)
+}
+
+const (
+	defaultReapInterval     = 30 * time.Second
+	defaultHeartbeatTimeout = time.Minute
+)
+
+// ReaperConfig is a configuration of the reaper of the stuck requests.
+type ReaperConfig struct {
+	// Interval is the time between checks of the processing requests, 30 seconds by default.
+	Interval time.Duration
+
+	// HeartbeatTimeout is the time after the last heartbeat, when the request is treated as abandoned.
+	// It's 1 minute by default.
+	HeartbeatTimeout time.Duration
+
+	// MaxAttempts is the amount of attempts, after which the abandoned request is failed, 5 by default.
+	MaxAttempts int
+}
+
+// Reaper is a struct which requeues or fails the requests, which workers stopped sending heartbeats.
+type Reaper struct {
+	repo ReaperRepo
+	conf *ReaperConfig
+}
+
+// NewReaper is a constructor to the Reaper.
+func NewReaper(repo ReaperRepo, conf *ReaperConfig) *Reaper {
+	return &Reaper{repo: repo, conf: conf}
+}
+
+// Reap method reaps the requests with the expired heartbeat once.
+func (r *Reaper) Reap(ctx context.Context) (*model.ReapResult, error) {
+	timeout := r.conf.HeartbeatTimeout
+	if timeout <= 0 {
+		timeout = defaultHeartbeatTimeout
+	}
+
+	maxAttempts := r.conf.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	now := time.Now()
+
+	return r.repo.ReapRequests(ctx, now.Add(-timeout), maxAttempts, FailureHeartbeat, now)
+}
+
+// Run method reaps the requests every interval till the context is done.
+func (r *Reaper) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	interval := r.conf.Interval
+	if interval <= 0 {
+		interval = defaultReapInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := r.Reap(ctx)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warnf("reaper: %s", err)
+		case err == nil && (len(result.Requeued) != 0 || len(result.Failed) != 0):
+			logger.Warnf("reaper: requests %v are queued again, requests %v are failed", result.Requeued, result.Failed)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaper_Reap(t *testing.T) {
+	testCases := []struct {
+		testName        string
+		conf            *service.ReaperConfig
+		wantTimeout     time.Duration
+		wantMaxAttempts int
+	}{
+		{
+			testName:        "configured",
+			conf:            &service.ReaperConfig{HeartbeatTimeout: 10 * time.Second, MaxAttempts: 3},
+			wantTimeout:     10 * time.Second,
+			wantMaxAttempts: 3,
+		},
+		{
+			testName:        "defaults",
+			conf:            &service.ReaperConfig{},
+			wantTimeout:     time.Minute,
+			wantMaxAttempts: 5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockReaperRepo(mockCtr)
+			ctx := context.Background()
+			want := &model.ReapResult{Leader: true, Requeued: []int{12}}
+
+			mockRepo.EXPECT().ReapRequests(ctx, gomock.Any(), tc.wantMaxAttempts, service.FailureHeartbeat, gomock.Any()).
+				DoAndReturn(func(_ context.Context, expiredBefore time.Time, _ int, _ string,
+					now time.Time) (*model.ReapResult, error) {
+					assert.Equal(t, tc.wantTimeout, now.Sub(expiredBefore))
+
+					return want, nil
+				})
+
+			reaper := service.NewReaper(mockRepo, tc.conf)
+			got, err := reaper.Reap(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/logging"
+	"github.com/Dyleme/image-coverter/internal/model"
+)
+
+// ImageProcesser is an interface which is provides method to send the request to the converter.
+type ImageProcesser interface {
+	ProcessImage(ctx context.Context, data *model.RequestToProcess) error
+}
+
+// OutboxRepo is an interface which provides method to relay messages from the outbox.
+type OutboxRepo interface {
+	RelayMessages(ctx context.Context, limit, userLimit int, inFlightTimeout time.Duration,
+		send func(context.Context, *model.RequestToProcess) error) (int, error)
+}
+
+const (
+	defaultRelayInterval  = time.Second
+	defaultRelayBatchSize = 100
+	defaultRelayUserLimit = 10
+
+	defaultRelayInFlightTimeout = 10 * time.Minute
+)
+
+// RelayConfig is a configuration of the outbox relay.
+type RelayConfig struct {
+	// Interval is the time between checks of the outbox, when it's empty, 1 second by default.
+	Interval time.Duration
+
+	// BatchSize is the amount of messages sent in one transaction, 100 by default.
+	BatchSize int
+
+	// UserLimit is the amount of the user's requests, which could be in the queue at the same time,
+	// 10 by default. It keeps the user with many requests from taking all the workers.
+	UserLimit int
+
+	// InFlightTimeout is the time, while the queued request is counted toward the user limit
+	// after its message is sent, 10 minutes by default. Message could be lost by the queue,
+	// so such requests stop blocking the user after the timeout.
+	InFlightTimeout time.Duration
+}
+
+// Relay is a struct which sends messages written to the outbox with the requests to the queue.
+// Message is marked sent only after it's published, so it's delivered at least once.
+type Relay struct {
+	repo      OutboxRepo
+	processor ImageProcesser
+	conf      *RelayConfig
+}
+
+// NewRelay is a constructor to the Relay.
+func NewRelay(repo OutboxRepo, proc ImageProcesser, conf *RelayConfig) *Relay {
+	return &Relay{repo: repo, processor: proc, conf: conf}
+}
+
+// RelayBatch method sends one batch of the pending messages and returns the amount of sent messages.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	userLimit := r.conf.UserLimit
+	if userLimit <= 0 {
+		userLimit = defaultRelayUserLimit
+	}
+
+	inFlightTimeout := r.conf.InFlightTimeout
+	if inFlightTimeout <= 0 {
+		inFlightTimeout = defaultRelayInFlightTimeout
+	}
+
+	return r.repo.RelayMessages(ctx, r.batchSize(), userLimit, inFlightTimeout, r.processor.ProcessImage)
+}
+
+func (r *Relay) batchSize() int {
+	if r.conf.BatchSize <= 0 {
+		return defaultRelayBatchSize
+	}
+
+	return r.conf.BatchSize
+}
+
+// Run method relays messages till the context is done.
+// Full batches are sent one after another, otherwise the outbox is checked again after the interval.
+func (r *Relay) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	interval := r.conf.Interval
+	if interval <= 0 {
+		interval = defaultRelayInterval
+	}
+
+	for {
+		sent, err := r.RelayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Warnf("relay: %s", err)
+		}
+
+		if err == nil && sent == r.batchSize() {
+			continue
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var errQueue = errors.New("queue error")
+
+func TestRelay_RelayBatch(t *testing.T) {
+	testCases := []struct {
+		testName      string
+		conf          *service.RelayConfig
+		wantLimit     int
+		wantUserLimit int
+		wantTimeout   time.Duration
+		publishErr    error
+		wantN         int
+		wantErr       error
+	}{
+		{
+			testName:      "all is good",
+			conf:          &service.RelayConfig{BatchSize: 20, UserLimit: 3, InFlightTimeout: time.Minute},
+			wantLimit:     20,
+			wantUserLimit: 3,
+			wantTimeout:   time.Minute,
+			wantN:         1,
+		},
+		{
+			testName:      "default batch size, user limit and in-flight timeout",
+			conf:          &service.RelayConfig{},
+			wantLimit:     100,
+			wantUserLimit: 10,
+			wantTimeout:   10 * time.Minute,
+			wantN:         1,
+		},
+		{
+			testName:      "error in queue",
+			conf:          &service.RelayConfig{BatchSize: 20, UserLimit: 3, InFlightTimeout: time.Minute},
+			wantLimit:     20,
+			wantUserLimit: 3,
+			wantTimeout:   time.Minute,
+			publishErr:    errQueue,
+			wantErr:       errQueue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockOutbox := mocks.NewMockOutboxRepo(mockCtr)
+			mockProcess := mocks.NewMockImageProcesser(mockCtr)
+			ctx := context.Background()
+			msg := &model.RequestToProcess{ReqID: 12, FileName: "x.png"}
+
+			mockOutbox.EXPECT().RelayMessages(ctx, tc.wantLimit, tc.wantUserLimit, tc.wantTimeout, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _, _ int, _ time.Duration,
+					send func(context.Context, *model.RequestToProcess) error) (int, error) {
+					if err := send(ctx, msg); err != nil {
+						return 0, err
+					}
+
+					return 1, nil
+				})
+			mockProcess.EXPECT().ProcessImage(ctx, msg).Return(tc.publishErr)
+
+			relay := service.NewRelay(mockOutbox, mockProcess, tc.conf)
+			gotN, gotErr := relay.RelayBatch(ctx)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantN, gotN)
+		})
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	mockCtr := gomock.NewController(t)
+	defer mockCtr.Finish()
+	mockOutbox := mocks.NewMockOutboxRepo(mockCtr)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Full batch is followed by the next one immediately, the outbox is checked after the error again.
+	gomock.InOrder(
+		mockOutbox.EXPECT().RelayMessages(gomock.Any(), 2, gomock.Any(), gomock.Any(), gomock.Any()).Return(2, nil),
+		mockOutbox.EXPECT().RelayMessages(gomock.Any(), 2, gomock.Any(), gomock.Any(), gomock.Any()).Return(0, errQueue),
+		mockOutbox.EXPECT().RelayMessages(gomock.Any(), 2, gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, int, int, time.Duration,
+				func(context.Context, *model.RequestToProcess) error) (int, error) {
+				cancel()
+
+				return 1, nil
+			}),
+	)
+
+	relay := service.NewRelay(mockOutbox, mocks.NewMockImageProcesser(mockCtr),
+		&service.RelayConfig{Interval: time.Millisecond, BatchSize: 2})
+
+	done := make(chan struct{})
+
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay is not stopped")
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"path"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/storage"
+)
+
+const (
+	// fitContain scales image to fit into the box keeping aspect ratio.
+	fitContain = "contain"
+
+	// fitStretch resizes image to the box without keeping aspect ratio.
+	fitStretch = "stretch"
+
+	maxRenderSize        = 4096
+	defaultRenderQuality = 85
+	maxJPEGQuality       = 100
+)
+
+// renderParams function validates parameters of the rendering and applies defaults.
+// Format of the original image is used if it's not provided, svg images are rendered to png.
+func renderParams(params model.RenderParams, originalType string) (model.RenderParams, error) {
+	switch {
+	case params.Width < 0 || params.Width > maxRenderSize:
+		return params, &InvalidOptionError{"w", fmt.Sprintf("should be between 0 and %v", maxRenderSize)}
+	case params.Height < 0 || params.Height > maxRenderSize:
+		return params, &InvalidOptionError{"h", fmt.Sprintf("should be between 0 and %v", maxRenderSize)}
+	case params.Width == 0 && params.Height == 0:
+		return params, &InvalidOptionError{"w", "width or height should be provided"}
+	}
+
+	if params.Fit == "" {
+		params.Fit = fitContain
+	}
+
+	switch params.Fit {
+	case fitContain:
+	case fitFill, fitCrop, fitStretch:
+		if params.Width == 0 || params.Height == 0 {
+			return params, &InvalidOptionError{"fit", fmt.Sprintf("%q requires both width and height", params.Fit)}
+		}
+	default:
+		return params, &InvalidOptionError{"fit", fmt.Sprintf("unknown fit %q", params.Fit)}
+	}
+
+	anchor, err := conversion.ParseAnchor(params.Anchor)
+	if err != nil {
+		return params, &InvalidOptionError{"anchor", err.Error()}
+	}
+
+	switch params.Fit {
+	case fitFill, fitCrop:
+		params.Anchor = string(anchor)
+	default:
+		if params.Anchor != "" {
+			return params, &InvalidOptionError{"anchor", "anchor is supported only by fill and crop"}
+		}
+	}
+
+	if params.Format == "" {
+		params.Format = originalType
+		if originalType != jpegType {
+			params.Format = pngType
+		}
+	}
+
+	switch params.Format {
+	case jpegType:
+		if params.Quality == 0 {
+			params.Quality = defaultRenderQuality
+		}
+
+		if params.Quality < 1 || params.Quality > maxJPEGQuality {
+			return params, &InvalidOptionError{"q", fmt.Sprintf("should be between 1 and %v", maxJPEGQuality)}
+		}
+	case pngType:
+		if params.Quality != 0 {
+			return params, &InvalidOptionError{"q", "quality is supported only by jpeg"}
+		}
+	default:
+		return params, &InvalidOptionError{"fmt", fmt.Sprintf("unsupported format %q", params.Format)}
+	}
+
+	return params, nil
+}
+
+// renderKey function returns the key of the rendered image, which is unique for the validated parameters.
+// Key is used as the file name of the cached image. Center anchor isn't included in the key,
+// so the images cached before the anchor was supported are still used.
+func renderKey(params model.RenderParams) string {
+	fit := params.Fit
+	if params.Anchor != "" && params.Anchor != string(conversion.AnchorCenter) {
+		fit += "-" + params.Anchor
+	}
+
+	return fmt.Sprintf("%dx%d_%s_q%d.%s", params.Width, params.Height, fit, params.Quality,
+		tileExtension(params.Format))
+}
+
+// renderImage function places the image in the box from the parameters.
+func renderImage(img image.Image, params model.RenderParams) (image.Image, error) {
+	var err error
+
+	switch params.Fit {
+	case fitFill:
+		img, _, err = conversion.Fill(img, params.Width, params.Height, conversion.Anchor(params.Anchor))
+	case fitCrop:
+		img, _, err = conversion.Crop(img, params.Width, params.Height, conversion.Anchor(params.Anchor))
+	case fitStretch:
+		img = conversion.Stretch(img, params.Width, params.Height)
+	default:
+		img = conversion.Contain(img, params.Width, params.Height)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("render image: %w", err)
+	}
+
+	return img, nil
+}
+
+// Render returns bytes of the user's image rendered with provided parameters and its file name.
+// Rendered images are cached in the storage and indexed in the repo by the image id and parameters,
+// so repeated renders are served from the storage.
+func (t *Transform) Render(ctx context.Context, userID, imageID int,
+	params model.RenderParams) ([]byte, string, error) {
+	imgInfo, err := t.repo.GetImage(ctx, userID, imageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	params, err = renderParams(params, imgInfo.Type)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	key := renderKey(params)
+
+	cachedURL, err := t.repo.GetDerivativeURL(ctx, imageID, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	if cachedURL != "" {
+		bts, err := t.storage.GetFile(ctx, cachedURL)
+		if err == nil {
+			return bts, key, nil
+		}
+
+		// If the file is lost from the storage, the image is rendered again.
+		if !errors.Is(err, storage.ErrFileNotExist) {
+			return nil, "", fmt.Errorf("render: %w", err)
+		}
+	}
+
+	img, err := t.getImage(ctx, imgInfo)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	img, err = renderImage(img, params)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	bts, err := encodeImageQuality(img, params.Format, params.Quality)
+	if err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	url := path.Join(derivedPrefix(imgInfo.URL), "render", key)
+
+	if err := t.storage.PutFile(ctx, url, bts); err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	if err := t.repo.AddDerivative(ctx, imageID, key, url); err != nil {
+		return nil, "", fmt.Errorf("render: %w", err)
+	}
+
+	return bts, key, nil
+}
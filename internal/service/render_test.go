@@ -0,0 +1,185 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/Dyleme/image-coverter/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform_Render(t *testing.T) {
+	pngTestImage := loadImage(t, "test_data/x.png")
+	imgInfo := &model.ReuquestImageInfo{Type: "png", URL: "x.png"}
+	params := model.RenderParams{Width: 30, Height: 20, Fit: "fill", Format: "jpeg", Quality: 80}
+	key := "30x20_fill_q80.jpg"
+	cachePath := "derived/x.png/render/" + key
+
+	testCases := []struct {
+		testName   string
+		params     model.RenderParams
+		initMock   func(*mocks.MockTransformRepo, *mocks.MockStorager)
+		wantKey    string
+		wantCached bool
+		wantErr    error
+		wantErrAs  interface{}
+	}{
+		{
+			testName: "cached image",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, key).Return(cachePath, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return([]byte("cached"), nil)
+			},
+			wantCached: true,
+		},
+		{
+			testName: "image is rendered and cached",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, key).Return("", nil)
+				mStor.EXPECT().GetFile(gomock.Any(), imgInfo.URL).Return(pngTestImage, nil)
+				mStor.EXPECT().PutFile(gomock.Any(), cachePath, gomock.Any()).Return(nil)
+				mRep.EXPECT().AddDerivative(gomock.Any(), 2, key, cachePath).Return(nil)
+			},
+		},
+		{
+			testName: "cached file is lost",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, key).Return(cachePath, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return(nil, fmt.Errorf("get: %w", storage.ErrFileNotExist))
+				mStor.EXPECT().GetFile(gomock.Any(), imgInfo.URL).Return(pngTestImage, nil)
+				mStor.EXPECT().PutFile(gomock.Any(), cachePath, gomock.Any()).Return(nil)
+				mRep.EXPECT().AddDerivative(gomock.Any(), 2, key, cachePath).Return(nil)
+			},
+		},
+		{
+			testName: "error in getting cached image",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, key).Return(cachePath, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return(nil, errStorage)
+			},
+			wantErr: errStorage,
+		},
+		{
+			testName: "cached image with anchor",
+			params:   model.RenderParams{Width: 30, Height: 20, Fit: "crop", Anchor: "top", Format: "jpeg", Quality: 80},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, "30x20_crop-top_q80.jpg").Return(cachePath, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return([]byte("cached"), nil)
+			},
+			wantKey:    "30x20_crop-top_q80.jpg",
+			wantCached: true,
+		},
+		{
+			testName: "unknown anchor",
+			params:   model.RenderParams{Width: 30, Height: 20, Fit: "fill", Anchor: "middle"},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "anchor of contain",
+			params:   model.RenderParams{Width: 30, Anchor: "top"},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "fill without height",
+			params:   model.RenderParams{Width: 30, Fit: "fill"},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "quality of png",
+			params:   model.RenderParams{Width: 30, Quality: 80},
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "image of another user",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(nil, errRepository)
+			},
+			wantErr: errRepository,
+		},
+		{
+			testName: "error in saving index",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mRep.EXPECT().GetDerivativeURL(gomock.Any(), 2, key).Return("", nil)
+				mStor.EXPECT().GetFile(gomock.Any(), imgInfo.URL).Return(pngTestImage, nil)
+				mStor.EXPECT().PutFile(gomock.Any(), cachePath, gomock.Any()).Return(nil)
+				mRep.EXPECT().AddDerivative(gomock.Any(), 2, key, cachePath).Return(errRepository)
+			},
+			wantErr: errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockTransformRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			tc.initMock(mockRepo, mockStorage)
+
+			srvc := service.NewTransform(mockRepo, mockStorage)
+
+			gotBytes, gotFilename, gotErr := srvc.Render(context.Background(), 1, 2, tc.params)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+
+				return
+			}
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			wantKey := key
+			if tc.wantKey != "" {
+				wantKey = tc.wantKey
+			}
+
+			assert.Equal(t, wantKey, gotFilename)
+
+			if tc.wantCached {
+				assert.Equal(t, []byte("cached"), gotBytes)
+			} else {
+				img, err := jpeg.Decode(bytes.NewReader(gotBytes))
+				require.NoError(t, err)
+				assert.Equal(t, 30, img.Bounds().Dx())
+				assert.Equal(t, 20, img.Bounds().Dy())
+			}
+		})
+	}
+}
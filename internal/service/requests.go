@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/Dyleme/image-coverter/internal/conversion"
 	"github.com/Dyleme/image-coverter/internal/model"
 	"github.com/Dyleme/image-coverter/internal/repository"
 )
@@ -16,25 +19,24 @@ type RequestRepo interface {
 	GetRequests(ctx context.Context, id int) ([]model.Request, error)
 	GetRequest(ctx context.Context, userID, reqID int) (*model.Request, error)
 	AddImageAndRequest(ctx context.Context, userID int, imageInfo *model.ReuquestImageInfo,
-		req *model.Request) (int, error)
-	DeleteRequestAndImage(ctx context.Context, userID, reqID int) (im1url, im2url string, err error)
+		req *model.Request, fileName string) (int, error)
+	AddRequest(ctx context.Context, userID int, req *model.Request, fileName string) (int, error)
+	GetImage(ctx context.Context, userID, imageID int) (*model.ReuquestImageInfo, error)
+	CountImages(ctx context.Context, userID int, imageIDs []int) (int, error)
+	DeleteRequestAndImage(ctx context.Context, userID, reqID int) (urls []string, err error)
+	CancelRequest(ctx context.Context, userID, reqID int, t time.Time) error
 }
 
 // Request is a struct provides the abitility to get, add, delete and update requests.
+// Requests are sent to the converter by the Relay from the outbox, which is written with the request.
 type Request struct {
-	repo      RequestRepo
-	storage   Storager
-	processor ImageProcesser
-}
-
-// ImageProcesser is an interface which is provides method to save image to the repo.
-type ImageProcesser interface {
-	ProcessImage(ctx context.Context, data *model.RequestToProcess) error
+	repo    RequestRepo
+	storage Storager
 }
 
 // NewRequest is a constructor to the RequestService.
-func NewRequest(repo RequestRepo, stor Storager, proc ImageProcesser) *Request {
-	return &Request{repo: repo, storage: stor, processor: proc}
+func NewRequest(repo RequestRepo, stor Storager) *Request {
+	return &Request{repo: repo, storage: stor}
 }
 
 // GetRequests returns requsts, or error if any occurs.
@@ -45,6 +47,10 @@ func (s *Request) GetRequests(ctx context.Context, userID int) ([]model.Request,
 		return nil, fmt.Errorf("get reqeusts: %w", err)
 	}
 
+	for i := range reqs {
+		addManifest(&reqs[i])
+	}
+
 	return reqs, nil
 }
 
@@ -64,16 +70,97 @@ func (e *FilenameWithoutPotintError) Error() string {
 	return fmt.Sprintf("filename should include point, filename is %s", e.filename)
 }
 
+type NotOwnedImagesError struct {
+	imageIDs []int
+}
+
+func (e *NotOwnedImagesError) Error() string {
+	return fmt.Sprintf("some of the images %v don't exist or belong to another user", e.imageIDs)
+}
+
+// RequestFinishedError is returned if the request can't be cancelled, because it's already processed.
+type RequestFinishedError struct {
+	status string
+}
+
+func (e *RequestFinishedError) Error() string {
+	return fmt.Sprintf("request is already %s", e.status)
+}
+
+type InvalidOptionError struct {
+	option string
+	reason string
+}
+
+func (e *InvalidOptionError) Error() string {
+	return fmt.Sprintf("invalid option %q: %s", e.option, e.reason)
+}
+
+// validateOptions function checks that the conversion options could be applied
+// to the conversion to the newType.
+func validateOptions(opts *model.ConversionOptions, newType string) error {
+	if _, err := conversion.ParseAnchor(opts.Anchor); err != nil {
+		return &InvalidOptionError{"anchor", err.Error()}
+	}
+
+	if _, _, err := tileLayout(opts.Tiles); err != nil {
+		return err
+	}
+
+	if opts.ChromaKey != nil {
+		if newType != pngType {
+			return &InvalidOptionError{"chromaKey", "could be used only with png type"}
+		}
+
+		if _, err := chromaKey(opts.ChromaKey); err != nil {
+			return err
+		}
+	}
+
+	switch opts.Fit {
+	case "":
+		return nil
+	case fitFill, fitCrop:
+		if opts.Width <= 0 || opts.Height <= 0 {
+			return &InvalidOptionError{"fit", "width and height should be positive"}
+		}
+
+		return nil
+	default:
+		return &InvalidOptionError{"fit", fmt.Sprintf("unknown fit %q", opts.Fit)}
+	}
+}
+
+// requestPriority function returns the priority of the request, it's interactive by default.
+func requestPriority(priority string) (string, error) {
+	switch priority {
+	case "":
+		return repository.PriorityInteractive, nil
+	case repository.PriorityInteractive, repository.PriorityBulk:
+		return priority, nil
+	default:
+		return "", &InvalidOptionError{"priority", fmt.Sprintf("unknown priority %q", priority)}
+	}
+}
+
 // AddRequest return the id of the added request or error if any occurs.
-// Also this function calls processor.ProcessImgae to convert the image.
 // Function decode file as image and upload this image using stor.UploadFile,
-// add request to the repo with repo.AddRequest.
+// add request to the repo with repo.AddImageAndRequest, which also queues the image to convert.
 func (s *Request) AddRequest(ctx context.Context, userID int, file io.Reader,
 	fileName string, convInfo model.ConversionInfo) (int, error) {
 	if convInfo.Ratio > 1 || convInfo.Ratio <= 0 {
 		return 0, &RatioNotInRangeError{convInfo.Ratio}
 	}
 
+	if err := validateOptions(&convInfo.ConversionOptions, convInfo.Type); err != nil {
+		return 0, fmt.Errorf("add request: %w", err)
+	}
+
+	priority, err := requestPriority(convInfo.Priority)
+	if err != nil {
+		return 0, fmt.Errorf("add request: %w", err)
+	}
+
 	reqTime := time.Now()
 
 	pointIndex := strings.LastIndex(fileName, ".")
@@ -82,7 +169,7 @@ func (s *Request) AddRequest(ctx context.Context, userID int, file io.Reader,
 	}
 
 	oldType := fileName[pointIndex+1:]
-	if oldType != jpegType && oldType != pngType {
+	if oldType != jpegType && oldType != pngType && oldType != svgType {
 		return 0, fmt.Errorf("add request: %w", UnsupportedTypeError{oldType})
 	}
 
@@ -91,6 +178,12 @@ func (s *Request) AddRequest(ctx context.Context, userID int, file io.Reader,
 		return 0, err
 	}
 
+	if oldType == svgType {
+		if err := conversion.CheckSVG(fileData); err != nil {
+			return 0, fmt.Errorf("add request: %w", err)
+		}
+	}
+
 	url, err := s.uploadFile(ctx, fileData, fileName, userID)
 	if err != nil {
 		return 0, fmt.Errorf("add request: %w", err)
@@ -102,26 +195,66 @@ func (s *Request) AddRequest(ctx context.Context, userID int, file io.Reader,
 	}
 
 	req := model.Request{
+		Kind:          repository.KindConversion,
 		OpStatus:      repository.StatusQueued,
+		Priority:      priority,
 		RequestTime:   reqTime,
 		Ratio:         convInfo.Ratio,
 		OriginalType:  oldType,
 		ProcessedType: convInfo.Type,
+		Options:       convInfo.ConversionOptions,
 	}
 
-	reqID, err := s.repo.AddImageAndRequest(ctx, userID, &imageInfo, &req)
+	reqID, err := s.repo.AddImageAndRequest(ctx, userID, &imageInfo, &req, fileName)
 	if err != nil {
 		return 0, fmt.Errorf("repo add image and request: %w", err)
 	}
 
-	convertImageData := &model.RequestToProcess{
-		ReqID:    reqID,
-		FileName: fileName,
+	return reqID, nil
+}
+
+// AddImageRequest returns the id of the request to convert the already stored user's image
+// or error if any occurs. Image could be original or processed one, it's not uploaded again.
+// Request keeps the link to the image as its source.
+func (s *Request) AddImageRequest(ctx context.Context, userID, imageID int, convInfo model.ConversionInfo) (int,
+	error) {
+	if convInfo.Ratio > 1 || convInfo.Ratio <= 0 {
+		return 0, &RatioNotInRangeError{convInfo.Ratio}
+	}
+
+	if err := validateOptions(&convInfo.ConversionOptions, convInfo.Type); err != nil {
+		return 0, fmt.Errorf("add image request: %w", err)
+	}
+
+	priority, err := requestPriority(convInfo.Priority)
+	if err != nil {
+		return 0, fmt.Errorf("add image request: %w", err)
+	}
+
+	img, err := s.repo.GetImage(ctx, userID, imageID)
+	if err != nil {
+		return 0, fmt.Errorf("repo get image: %w", err)
+	}
+
+	if img.Type != jpegType && img.Type != pngType && img.Type != svgType {
+		return 0, fmt.Errorf("add image request: %w", UnsupportedTypeError{img.Type})
 	}
 
-	err = s.processor.ProcessImage(ctx, convertImageData)
+	req := model.Request{
+		Kind:          repository.KindConversion,
+		OpStatus:      repository.StatusQueued,
+		Priority:      priority,
+		RequestTime:   time.Now(),
+		SourceID:      imageID,
+		Ratio:         convInfo.Ratio,
+		OriginalType:  img.Type,
+		ProcessedType: convInfo.Type,
+		Options:       convInfo.ConversionOptions,
+	}
+
+	reqID, err := s.repo.AddRequest(ctx, userID, &req, path.Base(img.URL))
 	if err != nil {
-		return 0, fmt.Errorf("prcoess image: %w", err)
+		return 0, fmt.Errorf("repo add request: %w", err)
 	}
 
 	return reqID, nil
@@ -135,31 +268,88 @@ func (s *Request) GetRequest(ctx context.Context, userID, reqID int) (*model.Req
 		return nil, err
 	}
 
+	addManifest(req)
+
 	return req, nil
 }
 
 // DeleteRequest method deletes request.
-// At first it deletes request using repo.DeleteRequest, than delete image from database using.DeleteImage
-// and finally it deletes images from the storage using storage.DeletFile.
+// At first it deletes request with its images from the repo using repo.DeleteRequestAndImage
+// and then it deletes images from the storage using storage.DeletFile.
+// Deep-zoom descriptor is deleted with all the tiles using storage.DeleteFiles,
+// images derived from the deleted images are also removed from the cache.
 func (s *Request) DeleteRequest(ctx context.Context, userID, reqID int) error {
-	url1, url2, err := s.repo.DeleteRequestAndImage(ctx, userID, reqID)
+	urls, err := s.repo.DeleteRequestAndImage(ctx, userID, reqID)
 	if err != nil {
 		return err
 	}
 
-	err = s.storage.DeleteFile(ctx, url1)
-	if err != nil {
-		return err
-	}
+	for _, url := range urls {
+		if isDZIDescriptor(url) {
+			err = s.storage.DeleteFiles(ctx, path.Dir(url)+"/")
+		} else {
+			err = s.storage.DeleteFile(ctx, url)
+		}
+
+		if err != nil {
+			return err
+		}
 
-	if url2 != "" {
-		err = s.storage.DeleteFile(ctx, url2)
+		err = s.storage.DeleteFiles(ctx, derivedPrefix(url))
 		if err != nil {
 			return err
 		}
 	}
 
-	return err
+	return nil
+}
+
+// CancelRequest method cancels the queued or processing request.
+// Cancelled requests are skipped by the converter, processing of the request,
+// which is already started, is stopped at the next heartbeat.
+// Returns RequestFinishedError if the request is already done, failed or cancelled.
+func (s *Request) CancelRequest(ctx context.Context, userID, reqID int) error {
+	if err := s.repo.CancelRequest(ctx, userID, reqID, time.Now()); err != nil {
+		var transitionErr *repository.IllegalTransitionError
+		if errors.As(err, &transitionErr) {
+			return fmt.Errorf("cancel request: %w", &RequestFinishedError{transitionErr.From})
+		}
+
+		return fmt.Errorf("cancel request: %w", err)
+	}
+
+	return nil
+}
+
+// uniqueIDs function returns ids without duplicates.
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// checkImagesOwner method returns NotOwnedImagesError if some of the images don't belong to the user.
+func (s *Request) checkImagesOwner(ctx context.Context, userID int, imageIDs []int) error {
+	ids := uniqueIDs(imageIDs)
+
+	count, err := s.repo.CountImages(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	if count != len(ids) {
+		return &NotOwnedImagesError{imageIDs}
+	}
+
+	return nil
 }
 
 func (s *Request) uploadFile(ctx context.Context, bts []byte,
@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/repository"
 	"github.com/Dyleme/image-coverter/internal/service"
 	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/Dyleme/image-coverter/internal/storage"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -99,7 +103,7 @@ func TestRequest_GetRequests(t *testing.T) {
 			mockRequest := mocks.NewMockRequestRepo(mockCtr)
 			mockStorage := mocks.NewMockStorager(mockCtr)
 
-			srvc := service.NewRequest(mockRequest, mockStorage, mocks.NewMockImageProcesser(mockCtr))
+			srvc := service.NewRequest(mockRequest, mockStorage)
 			ctx := context.Background()
 
 			mockRequest.EXPECT().GetRequests(ctx, tc.userID).Return(tc.repReqs, tc.repErr)
@@ -167,7 +171,7 @@ func TestRequest_GetRequest(t *testing.T) {
 			mockRequest := mocks.NewMockRequestRepo(mockCtr)
 			mockStorage := mocks.NewMockStorager(mockCtr)
 
-			srvc := service.NewRequest(mockRequest, mockStorage, &mocks.MockImageProcesser{})
+			srvc := service.NewRequest(mockRequest, mockStorage)
 			ctx := context.Background()
 
 			mockRequest.EXPECT().GetRequest(ctx, tc.userID, tc.reqID).Return(tc.repReq, tc.repErr).Times(1)
@@ -195,23 +199,23 @@ func TestRequest_AddReqeust(t *testing.T) {
 	pngTestImage := loadImage(t, "test_data/x.png")
 
 	testCases := []struct {
-		testName        string
-		userID          int
-		file            *bytes.Buffer
-		fileName        string
-		imageID         int
-		imageRepoErr    error
-		imageURL        string
-		storageErr      error
-		convInfo        model.ConversionInfo
-		runUploadFile   bool
-		runAddImage     bool
-		runAddRequest   bool
-		repoReqID       int
-		reqRepoErr      error
-		runProcessImage bool
-		wantReqID       int
-		wantErr         error
+		testName      string
+		userID        int
+		file          *bytes.Buffer
+		fileName      string
+		imageID       int
+		imageRepoErr  error
+		imageURL      string
+		storageErr    error
+		convInfo      model.ConversionInfo
+		runUploadFile bool
+		runAddImage   bool
+		runAddRequest bool
+		repoReqID     int
+		reqRepoErr    error
+		wantReqID     int
+		wantErr       error
+		wantErrAs     interface{}
 	}{
 		{
 			testName: "all is good",
@@ -222,14 +226,13 @@ func TestRequest_AddReqeust(t *testing.T) {
 				Ratio: 0.5,
 				Type:  "png",
 			},
-			runUploadFile:   true,
-			runAddImage:     true,
-			runAddRequest:   true,
-			reqRepoErr:      nil,
-			repoReqID:       15,
-			runProcessImage: true,
-			wantReqID:       15,
-			wantErr:         nil,
+			runUploadFile: true,
+			runAddImage:   true,
+			runAddRequest: true,
+			reqRepoErr:    nil,
+			repoReqID:     15,
+			wantReqID:     15,
+			wantErr:       nil,
 		},
 		{
 			testName: "unknow file type",
@@ -245,6 +248,96 @@ func TestRequest_AddReqeust(t *testing.T) {
 			wantReqID:  0,
 			wantErr:    &service.UnsupportedTypeError{"webm"},
 		},
+		{
+			testName: "placeholder",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio:             1,
+				Type:              "png",
+				ConversionOptions: model.ConversionOptions{Placeholder: true},
+			},
+			runUploadFile: true,
+			runAddImage:   true,
+			runAddRequest: true,
+			repoReqID:     15,
+			wantReqID:     15,
+		},
+		{
+			testName: "chroma key",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio: 1,
+				Type:  "png",
+				ConversionOptions: model.ConversionOptions{
+					ChromaKey: &model.ChromaKeyOptions{Color: "#ffffff", Tolerance: 5, Feather: 10},
+				},
+			},
+			runUploadFile: true,
+			runAddImage:   true,
+			runAddRequest: true,
+			repoReqID:     15,
+			wantReqID:     15,
+		},
+		{
+			testName: "chroma key to jpeg",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio: 1,
+				Type:  "jpeg",
+				ConversionOptions: model.ConversionOptions{
+					ChromaKey: &model.ChromaKeyOptions{Color: "#ffffff"},
+				},
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "chroma key with alpha",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio: 1,
+				Type:  "png",
+				ConversionOptions: model.ConversionOptions{
+					ChromaKey: &model.ChromaKeyOptions{Color: "#00ff0080"},
+				},
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "chroma key without hash",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio: 1,
+				Type:  "png",
+				ConversionOptions: model.ConversionOptions{
+					ChromaKey: &model.ChromaKeyOptions{Color: "00ff00"},
+				},
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "invalid chroma key tolerance",
+			userID:   123,
+			file:     bytes.NewBuffer(pngTestImage),
+			fileName: "filename.png",
+			convInfo: model.ConversionInfo{
+				Ratio: 1,
+				Type:  "png",
+				ConversionOptions: model.ConversionOptions{
+					ChromaKey: &model.ChromaKeyOptions{Color: "#00ff00", Tolerance: 120},
+				},
+			},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
 		{
 			testName: "storage error",
 			userID:   123,
@@ -268,9 +361,8 @@ func TestRequest_AddReqeust(t *testing.T) {
 			defer mockCtr.Finish()
 			mockRequest := mocks.NewMockRequestRepo(mockCtr)
 			mockStorage := mocks.NewMockStorager(mockCtr)
-			mockProcess := mocks.NewMockImageProcesser(mockCtr)
 
-			srvc := service.NewRequest(mockRequest, mockStorage, mockProcess)
+			srvc := service.NewRequest(mockRequest, mockStorage)
 			ctx := context.Background()
 
 			if tc.runUploadFile {
@@ -279,18 +371,25 @@ func TestRequest_AddReqeust(t *testing.T) {
 
 			if tc.runAddImage {
 				mockRequest.EXPECT().
-					AddImageAndRequest(ctx, tc.userID, gomock.Any(), gomock.Any()).
-					Return(tc.repoReqID, tc.imageRepoErr)
-			}
+					AddImageAndRequest(ctx, tc.userID, gomock.Any(), gomock.Any(), tc.fileName).
+					DoAndReturn(func(_ context.Context, _ int, _ *model.ReuquestImageInfo,
+						req *model.Request, _ string) (int, error) {
+						// Options are saved with the request to be applied by the converter.
+						assert.Equal(t, tc.convInfo.ConversionOptions, req.Options)
 
-			if tc.runProcessImage {
-				mockProcess.EXPECT().ProcessImage(ctx, gomock.Any())
+						return tc.repoReqID, tc.imageRepoErr
+					})
 			}
 
 			gotReqID, gotErr := srvc.AddRequest(ctx, tc.userID, tc.file,
 				tc.fileName, tc.convInfo)
 
-			assert.ErrorIs(t, gotErr, tc.wantErr)
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+
 			assert.Equal(t, gotReqID, tc.wantReqID)
 		})
 	}
@@ -301,21 +400,21 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 		testName string
 		userID   int
 		reqID    int
-		url1     string
-		url2     string
-		initMock func(*mocks.MockRequestRepo, *mocks.MockStorager, int, int, string, string)
+		urls     []string
+		initMock func(*mocks.MockRequestRepo, *mocks.MockStorager, int, int, []string)
 		wantErr  error
 	}{
 		{
 			testName: "all is good",
 			userID:   1,
 			reqID:    2,
-			url1:     "first image url",
-			url2:     "second image url",
-			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, url1, url2 string) {
-				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(url1, url2, nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url1).Return(nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url2).Return(nil)
+			urls:     []string{"first image url", "second image url"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[0]+"/").Return(nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[1]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[1]+"/").Return(nil)
 			},
 			wantErr: nil,
 		},
@@ -323,10 +422,9 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 			testName: "error while deleting request and images",
 			userID:   1,
 			reqID:    2,
-			url1:     "",
-			url2:     "",
-			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, url1, url2 string) {
-				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(url1, url2, errRepository)
+			urls:     nil,
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(nil, errRepository)
 			},
 			wantErr: errRepository,
 		},
@@ -334,11 +432,10 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 			testName: "error while deleting first file",
 			userID:   1,
 			reqID:    2,
-			url1:     "first image url",
-			url2:     "second image url",
-			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, url1, url2 string) {
-				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(url1, url2, nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url1).Return(errStorage)
+			urls:     []string{"first image url", "second image url"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(errStorage)
 			},
 			wantErr: errStorage,
 		},
@@ -346,11 +443,11 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 			testName: "second image not exists",
 			userID:   1,
 			reqID:    2,
-			url1:     "first image id",
-			url2:     "",
-			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, url1, url2 string) {
-				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(url1, url2, nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url1).Return(nil)
+			urls:     []string{"first image id"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[0]+"/").Return(nil)
 			},
 			wantErr: nil,
 		},
@@ -358,15 +455,45 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 			testName: "error while deleting second file",
 			userID:   1,
 			reqID:    2,
-			url1:     "first image url",
-			url2:     "second image url",
-			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, url1, url2 string) {
-				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(url1, url2, nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url1).Return(nil)
-				mStor.EXPECT().DeleteFile(gomock.Any(), url2).Return(errStorage)
+			urls:     []string{"first image url", "second image url"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[0]+"/").Return(nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[1]).Return(errStorage)
 			},
 			wantErr: errStorage,
 		},
+		{
+			testName: "request with artifacts",
+			userID:   1,
+			reqID:    2,
+			urls:     []string{"first image url", "second image url", "artifact url"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[0]+"/").Return(nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[1]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[1]+"/").Return(nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[2]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[2]+"/").Return(nil)
+			},
+			wantErr: nil,
+		},
+		{
+			testName: "request with tile pyramid",
+			userID:   1,
+			reqID:    2,
+			urls:     []string{"first image url", "dzi/2/image.dzi"},
+			initMock: func(mRep *mocks.MockRequestRepo, mStor *mocks.MockStorager, userID, reqID int, urls []string) {
+				mRep.EXPECT().DeleteRequestAndImage(gomock.Any(), userID, reqID).Return(urls, nil)
+				mStor.EXPECT().DeleteFile(gomock.Any(), urls[0]).Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/"+urls[0]+"/").Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "dzi/2/").Return(nil)
+				mStor.EXPECT().DeleteFiles(gomock.Any(), "derived/dzi/2/image.dzi/").Return(nil)
+			},
+			wantErr: nil,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
@@ -375,9 +502,9 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 			mockRequest := mocks.NewMockRequestRepo(mockCtr)
 			mockStorage := mocks.NewMockStorager(mockCtr)
 
-			tc.initMock(mockRequest, mockStorage, tc.userID, tc.reqID, tc.url1, tc.url2)
+			tc.initMock(mockRequest, mockStorage, tc.userID, tc.reqID, tc.urls)
 
-			srvc := service.NewRequest(mockRequest, mockStorage, &mocks.MockImageProcesser{})
+			srvc := service.NewRequest(mockRequest, mockStorage)
 			ctx := context.Background()
 
 			gotErr := srvc.DeleteRequest(ctx, tc.userID, tc.reqID)
@@ -386,3 +513,545 @@ func TestRequest_DeleteReqeust(t *testing.T) {
 		})
 	}
 }
+
+func TestRequest_CancelRequest(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		repoErr   error
+		wantErr   error
+		wantErrAs interface{}
+	}{
+		{
+			testName: "all is good",
+		},
+		{
+			testName:  "request is finished",
+			repoErr:   &repository.IllegalTransitionError{ReqID: 2, From: "done", To: "cancelled"},
+			wantErrAs: new(*service.RequestFinishedError),
+		},
+		{
+			testName: "error in repository",
+			repoErr:  errRepository,
+			wantErr:  errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRequest := mocks.NewMockRequestRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			mockRequest.EXPECT().CancelRequest(gomock.Any(), 1, 2, gomock.Any()).Return(tc.repoErr)
+
+			srvc := service.NewRequest(mockRequest, mockStorage)
+			gotErr := srvc.CancelRequest(context.Background(), 1, 2)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequest_AddContactSheetRequest(t *testing.T) {
+	validInfo := model.ContactSheetInfo{
+		ImageIDs: []int{3, 5, 3},
+		Type:     "png",
+		SheetLayout: model.SheetLayout{
+			Columns:    2,
+			CellWidth:  100,
+			CellHeight: 80,
+			Background: "#202020",
+			Captions:   []string{"first", "second"},
+		},
+	}
+
+	testCases := []struct {
+		testName       string
+		userID         int
+		info           model.ContactSheetInfo
+		runCountImages bool
+		countImages    int
+		countErr       error
+		runAddRequest  bool
+		repoReqID      int
+		reqRepoErr     error
+		wantReqID      int
+		wantErr        error
+		wantErrAs      interface{}
+	}{
+		{
+			testName:       "all is good",
+			userID:         123,
+			info:           validInfo,
+			runCountImages: true,
+			countImages:    2,
+			runAddRequest:  true,
+			repoReqID:      15,
+			wantReqID:      15,
+		},
+		{
+			testName: "bulk contact sheet",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.Priority = "bulk"
+
+				return info
+			}(),
+			runCountImages: true,
+			countImages:    2,
+			runAddRequest:  true,
+			repoReqID:      15,
+			wantReqID:      15,
+		},
+		{
+			testName: "unknown priority",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.Priority = "urgent"
+
+				return info
+			}(),
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "invalid background",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.Background = "red"
+
+				return info
+			}(),
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "no images",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.ImageIDs = nil
+
+				return info
+			}(),
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "too long caption",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.Captions = []string{strings.Repeat("a", 301)}
+
+				return info
+			}(),
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName: "too large sheet",
+			userID:   123,
+			info: func() model.ContactSheetInfo {
+				info := validInfo
+				info.ImageIDs = make([]int, 100)
+				info.Columns = 10
+				info.CellWidth = 2000
+				info.CellHeight = 2000
+
+				return info
+			}(),
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName:       "image of another user",
+			userID:         123,
+			info:           validInfo,
+			runCountImages: true,
+			countImages:    1,
+			wantErrAs:      new(*service.NotOwnedImagesError),
+		},
+		{
+			testName:       "repository error",
+			userID:         123,
+			info:           validInfo,
+			runCountImages: true,
+			countErr:       errRepository,
+			wantErr:        errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRequest := mocks.NewMockRequestRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			srvc := service.NewRequest(mockRequest, mockStorage)
+			ctx := context.Background()
+
+			if tc.runCountImages {
+				mockRequest.EXPECT().CountImages(ctx, tc.userID, []int{3, 5}).
+					Return(tc.countImages, tc.countErr)
+			}
+
+			if tc.runAddRequest {
+				mockRequest.EXPECT().AddRequest(ctx, tc.userID, gomock.Any(), "contact-sheet.png").
+					DoAndReturn(func(_ context.Context, _ int, req *model.Request, _ string) (int, error) {
+						assert.Equal(t, "contact_sheet", req.Kind)
+						assert.Equal(t, wantPriority(tc.info.Priority), req.Priority)
+						assert.Equal(t, tc.info.ImageIDs, req.Options.ImageIDs)
+						assert.Equal(t, tc.info.SheetLayout, *req.Options.Sheet)
+
+						return tc.repoReqID, tc.reqRepoErr
+					})
+			}
+
+			gotReqID, gotErr := srvc.AddContactSheetRequest(ctx, tc.userID, tc.info)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+
+			assert.Equal(t, tc.wantReqID, gotReqID)
+		})
+	}
+}
+
+func TestRequest_AddImageRequest(t *testing.T) {
+	convInfo := model.ConversionInfo{Ratio: 0.5, Type: "jpeg"}
+
+	testCases := []struct {
+		testName      string
+		userID        int
+		imageID       int
+		info          model.ConversionInfo
+		runGetImage   bool
+		repoImage     *model.ReuquestImageInfo
+		getImageErr   error
+		runAddRequest bool
+		repoReqID     int
+		reqRepoErr    error
+		wantPriority  string
+		wantReqID     int
+		wantErr       error
+		wantErrAs     interface{}
+	}{
+		{
+			testName:      "all is good",
+			userID:        123,
+			imageID:       7,
+			info:          convInfo,
+			runGetImage:   true,
+			repoImage:     &model.ReuquestImageInfo{Type: "png", URL: "users/123/abc.png"},
+			runAddRequest: true,
+			repoReqID:     15,
+			wantPriority:  "interactive",
+			wantReqID:     15,
+		},
+		{
+			testName:      "bulk request",
+			userID:        123,
+			imageID:       7,
+			info:          model.ConversionInfo{Ratio: 0.5, Type: "jpeg", Priority: "bulk"},
+			runGetImage:   true,
+			repoImage:     &model.ReuquestImageInfo{Type: "png", URL: "users/123/abc.png"},
+			runAddRequest: true,
+			repoReqID:     15,
+			wantPriority:  "bulk",
+			wantReqID:     15,
+		},
+		{
+			testName:  "unknown priority",
+			userID:    123,
+			imageID:   7,
+			info:      model.ConversionInfo{Ratio: 0.5, Type: "jpeg", Priority: "urgent"},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName:  "ratio not in range",
+			userID:    123,
+			imageID:   7,
+			info:      model.ConversionInfo{Ratio: 2, Type: "jpeg"},
+			wantErrAs: new(*service.RatioNotInRangeError),
+		},
+		{
+			testName:    "image of another user",
+			userID:      123,
+			imageID:     7,
+			info:        convInfo,
+			runGetImage: true,
+			getImageErr: errRepository,
+			wantErr:     errRepository,
+		},
+		{
+			testName:    "unsupported source type",
+			userID:      123,
+			imageID:     7,
+			info:        convInfo,
+			runGetImage: true,
+			repoImage:   &model.ReuquestImageInfo{Type: "ico", URL: "users/123/abc.ico"},
+			wantErrAs:   new(service.UnsupportedTypeError),
+		},
+		{
+			testName:      "repository error",
+			userID:        123,
+			imageID:       7,
+			info:          convInfo,
+			runGetImage:   true,
+			repoImage:     &model.ReuquestImageInfo{Type: "png", URL: "users/123/abc.png"},
+			runAddRequest: true,
+			reqRepoErr:    errRepository,
+			wantPriority:  "interactive",
+			wantErr:       errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRequest := mocks.NewMockRequestRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			srvc := service.NewRequest(mockRequest, mockStorage)
+			ctx := context.Background()
+
+			if tc.runGetImage {
+				mockRequest.EXPECT().GetImage(ctx, tc.userID, tc.imageID).Return(tc.repoImage, tc.getImageErr)
+			}
+
+			if tc.runAddRequest {
+				mockRequest.EXPECT().AddRequest(ctx, tc.userID, gomock.Any(), "abc.png").
+					DoAndReturn(func(_ context.Context, _ int, req *model.Request, _ string) (int, error) {
+						assert.Equal(t, "conversion", req.Kind)
+						assert.Equal(t, tc.imageID, req.SourceID)
+						assert.Equal(t, 0, req.OriginalID)
+						assert.Equal(t, tc.repoImage.Type, req.OriginalType)
+						assert.Equal(t, tc.info.Type, req.ProcessedType)
+						assert.Equal(t, tc.wantPriority, req.Priority)
+
+						return tc.repoReqID, tc.reqRepoErr
+					})
+			}
+
+			gotReqID, gotErr := srvc.AddImageRequest(ctx, tc.userID, tc.imageID, tc.info)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			} else {
+				assert.ErrorIs(t, gotErr, tc.wantErr)
+			}
+
+			assert.Equal(t, tc.wantReqID, gotReqID)
+		})
+	}
+}
+
+// wantPriority function returns the priority of the request, which is added with provided priority.
+func wantPriority(priority string) string {
+	if priority == "" {
+		return "interactive"
+	}
+
+	return priority
+}
+
+func TestRequest_AddPDFRequest(t *testing.T) {
+	testCases := []struct {
+		testName       string
+		info           model.PDFInfo
+		runCountImages bool
+		countImages    int
+		runAddRequest  bool
+		wantReqID      int
+		wantErrAs      interface{}
+	}{
+		{
+			testName: "all is good",
+			info: model.PDFInfo{
+				ImageIDs:  []int{4, 7},
+				PDFLayout: model.PDFLayout{PageSize: "letter", Landscape: true, Margin: 36, Fit: "fill"},
+			},
+			runCountImages: true,
+			countImages:    2,
+			runAddRequest:  true,
+			wantReqID:      21,
+		},
+		{
+			testName:       "bulk document",
+			info:           model.PDFInfo{ImageIDs: []int{4, 7}, Priority: "bulk"},
+			runCountImages: true,
+			countImages:    2,
+			runAddRequest:  true,
+			wantReqID:      21,
+		},
+		{
+			testName:  "unknown priority",
+			info:      model.PDFInfo{ImageIDs: []int{4, 7}, Priority: "urgent"},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName:  "unknown page size",
+			info:      model.PDFInfo{ImageIDs: []int{4, 7}, PDFLayout: model.PDFLayout{PageSize: "b5"}},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName:  "margins are bigger than page",
+			info:      model.PDFInfo{ImageIDs: []int{4, 7}, PDFLayout: model.PDFLayout{Margin: 300}},
+			wantErrAs: new(*service.InvalidOptionError),
+		},
+		{
+			testName:       "image of another user",
+			info:           model.PDFInfo{ImageIDs: []int{4, 7}},
+			runCountImages: true,
+			countImages:    1,
+			wantErrAs:      new(*service.NotOwnedImagesError),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRequest := mocks.NewMockRequestRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			srvc := service.NewRequest(mockRequest, mockStorage)
+			ctx := context.Background()
+			userID := 123
+
+			if tc.runCountImages {
+				mockRequest.EXPECT().CountImages(ctx, userID, tc.info.ImageIDs).Return(tc.countImages, nil)
+			}
+
+			if tc.runAddRequest {
+				mockRequest.EXPECT().AddRequest(ctx, userID, gomock.Any(), "document.pdf").
+					DoAndReturn(func(_ context.Context, _ int, req *model.Request, _ string) (int, error) {
+						assert.Equal(t, "pdf", req.Kind)
+						assert.Equal(t, wantPriority(tc.info.Priority), req.Priority)
+						assert.Equal(t, "pdf", req.ProcessedType)
+						assert.Equal(t, tc.info.PDFLayout, *req.Options.PDF)
+
+						return tc.wantReqID, nil
+					})
+			}
+
+			gotReqID, gotErr := srvc.AddPDFRequest(ctx, userID, tc.info)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+
+			assert.Equal(t, tc.wantReqID, gotReqID)
+		})
+	}
+}
+
+func TestRequest_GetTile(t *testing.T) {
+	testCases := []struct {
+		testName     string
+		req          *model.Request
+		repoErr      error
+		runGetFile   bool
+		getFileErr   error
+		wantPath     string
+		wantFilename string
+		wantErr      error
+	}{
+		{
+			testName: "jpeg tiles",
+			req: &model.Request{
+				OpStatus:      "done",
+				ProcessedType: "dzi",
+			},
+			runGetFile:   true,
+			wantPath:     "dzi/7/image_files/10/2_3.jpg",
+			wantFilename: "2_3.jpg",
+		},
+		{
+			testName: "png tiles",
+			req: &model.Request{
+				OpStatus:      "done",
+				ProcessedType: "dzi",
+				Options:       model.ConversionOptions{Tiles: &model.TileOptions{Format: "png"}},
+			},
+			runGetFile:   true,
+			wantPath:     "dzi/7/image_files/10/2_3.png",
+			wantFilename: "2_3.png",
+		},
+		{
+			testName: "tile out of the pyramid",
+			req: &model.Request{
+				OpStatus:      "done",
+				ProcessedType: "dzi",
+			},
+			runGetFile: true,
+			getFileErr: fmt.Errorf("get file: %w", storage.ErrFileNotExist),
+			wantPath:   "dzi/7/image_files/10/2_3.jpg",
+			wantErr:    service.ErrTileNotExist,
+		},
+		{
+			testName: "request is not processed",
+			req: &model.Request{
+				OpStatus:      "queued",
+				ProcessedType: "dzi",
+			},
+			wantErr: service.ErrNotTiled,
+		},
+		{
+			testName: "request without tiles",
+			req: &model.Request{
+				OpStatus:      "done",
+				ProcessedType: "png",
+			},
+			wantErr: service.ErrNotTiled,
+		},
+		{
+			testName: "repository error",
+			repoErr:  errRepository,
+			wantErr:  errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRequest := mocks.NewMockRequestRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			srvc := service.NewRequest(mockRequest, mockStorage)
+			ctx := context.Background()
+
+			mockRequest.EXPECT().GetRequest(ctx, 1, 7).Return(tc.req, tc.repoErr)
+
+			if tc.runGetFile {
+				tile := []byte("tile")
+				if tc.getFileErr != nil {
+					tile = nil
+				}
+
+				mockStorage.EXPECT().GetFile(ctx, tc.wantPath).Return(tile, tc.getFileErr)
+			}
+
+			gotTile, gotFilename, gotErr := srvc.GetTile(ctx, 1, 7, 10, 2, 3)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantFilename, gotFilename)
+
+			if tc.wantErr == nil {
+				assert.Equal(t, []byte("tile"), gotTile)
+			}
+		})
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"strings"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
 )
 
 // Storager is an interface to interact with the file storage.
 type Storager interface {
 	// GetFile is used to take file from the storage.
+	// Missing file is reported with the storage.ErrFileNotExist.
 	GetFile(ctx context.Context, path string) ([]byte, error)
 
 	// UploadFile is used to add the file to the storage.
@@ -21,17 +25,36 @@ type Storager interface {
 
 	// DeleteFile is used to delete file from the storage.
 	DeleteFile(ctx context.Context, path string) error
+
+	// PutFile is used to add the file to the storage with the provided path.
+	PutFile(ctx context.Context, path string, data []byte) error
+
+	// DeleteFiles is used to delete all files which paths start with the prefix.
+	DeleteFiles(ctx context.Context, prefix string) error
 }
 
 const (
 	jpegType = "jpeg"
 	pngType  = "png"
+	svgType  = "svg"
+	icoType  = "ico"
+
+	// faviconType is used to make ico with the set of the png touch icons.
+	faviconType = "favicon"
 )
 
 const (
 	jpegQuality = 100
 )
 
+const (
+	// fitFill resizes image to cover the box and crops it.
+	fitFill = "fill"
+
+	// fitCrop crops the box from the image without resizing.
+	fitCrop = "crop"
+)
+
 type UnsupportedTypeError struct {
 	UnType string
 }
@@ -62,6 +85,19 @@ func decodeImage(r io.Reader, imgType string) (image.Image, error) {
 	}
 }
 
+// decodeImageConfig decodes the size of the image from the r without decoding the image itself.
+// Decoding supports only jpeg and png types.
+func decodeImageConfig(r io.Reader, imgType string) (image.Config, error) {
+	switch imgType {
+	case pngType:
+		return png.DecodeConfig(r)
+	case jpegType:
+		return jpeg.DecodeConfig(r)
+	default:
+		return image.Config{}, &UnsupportedTypeError{imgType}
+	}
+}
+
 // getResolution function returns the resolution of the image.
 func getResolution(i image.Image) (width, height int) {
 	return i.Bounds().Dx(), i.Bounds().Dy()
@@ -69,6 +105,11 @@ func getResolution(i image.Image) (width, height int) {
 
 // encodeImage encode image with the provided image type, returns bytes of the encoded image.
 func encodeImage(i image.Image, imgType string) ([]byte, error) {
+	return encodeImageQuality(i, imgType, jpegQuality)
+}
+
+// encodeImageQuality encode image with the provided image type, quality is used only by jpeg.
+func encodeImageQuality(i image.Image, imgType string, quality int) ([]byte, error) {
 	bf := new(bytes.Buffer)
 
 	switch imgType {
@@ -78,13 +119,25 @@ func encodeImage(i image.Image, imgType string) ([]byte, error) {
 		}
 
 	case jpegType:
-		if err := jpeg.Encode(bf, i, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		if err := jpeg.Encode(bf, i, &jpeg.Options{Quality: quality}); err != nil {
 			return nil, err
 		}
 
+	case icoType:
+		return conversion.EncodeICO(i, conversion.DefaultICOSizes)
+
 	default:
 		return nil, &UnsupportedTypeError{imgType}
 	}
 
 	return bf.Bytes(), nil
 }
+
+// replaceExtension function returns the filename with the extension changed to the ext.
+func replaceExtension(filename, ext string) string {
+	if dotPos := strings.LastIndex(filename, "."); dotPos != -1 {
+		filename = filename[:dotPos]
+	}
+
+	return filename + "." + ext
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"path"
+	"strings"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/storage"
+)
+
+// TransformRepo is an interface which provides methods to get the user's image
+// and to index the images derived from it.
+type TransformRepo interface {
+	GetImage(ctx context.Context, userID, imageID int) (*model.ReuquestImageInfo, error)
+	GetDerivativeURL(ctx context.Context, imageID int, params string) (string, error)
+	AddDerivative(ctx context.Context, imageID int, params, url string) error
+}
+
+// Transform struct provides the ability to make derived images synchronously.
+// Derived images are cached in the storage.
+type Transform struct {
+	repo    TransformRepo
+	storage Storager
+}
+
+// NewTransform is the constructor to the Transform.
+func NewTransform(repo TransformRepo, stor Storager) *Transform {
+	return &Transform{repo: repo, storage: stor}
+}
+
+const (
+	iiifContext  = "http://iiif.io/api/image/3/context.json"
+	iiifProtocol = "http://iiif.io/api/image"
+	iiifType     = "ImageService3"
+	iiifProfile  = "level2"
+)
+
+// derivedPrefix function returns the storage prefix of the images derived from the image with provided url.
+func derivedPrefix(url string) string {
+	return "derived/" + strings.TrimPrefix(url, "/") + "/"
+}
+
+// IIIFImage returns bytes of the image made with IIIF Image API parameters and its file name.
+// Image should belong to the user. Result is taken from the cache in the storage,
+// if it was made before. Otherwise it's made from the original image and put to the cache.
+func (t *Transform) IIIFImage(ctx context.Context, userID, imageID int,
+	params model.IIIFParams) ([]byte, string, error) {
+	req, err := conversion.ParseIIIF(params.Region, params.Size, params.Rotation, params.Quality, params.Format)
+	if err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	imgInfo, err := t.repo.GetImage(ctx, userID, imageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	cachePath := derivedPrefix(imgInfo.URL) + "iiif/" + req.Path()
+
+	bts, err := t.storage.GetFile(ctx, cachePath)
+	if err == nil {
+		return bts, path.Base(cachePath), nil
+	}
+
+	if !errors.Is(err, storage.ErrFileNotExist) {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	img, err := t.getImage(ctx, imgInfo)
+	if err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	img, err = req.Apply(img)
+	if err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	bts, err = encodeImage(img, req.Type())
+	if err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	if err := t.storage.PutFile(ctx, cachePath, bts); err != nil {
+		return nil, "", fmt.Errorf("iiif: %w", err)
+	}
+
+	return bts, path.Base(cachePath), nil
+}
+
+// IIIFInfo returns the IIIF image information of the user's image.
+// ID is the base URI of the image service. Stored resolution is used,
+// the image is decoded only if its resolution is unknown.
+func (t *Transform) IIIFInfo(ctx context.Context, userID, imageID int, id string) (*model.IIIFInfo, error) {
+	imgInfo, err := t.repo.GetImage(ctx, userID, imageID)
+	if err != nil {
+		return nil, fmt.Errorf("iiif info: %w", err)
+	}
+
+	width, height := imgInfo.Width, imgInfo.Height
+	if width <= 0 || height <= 0 {
+		img, err := t.getImage(ctx, imgInfo)
+		if err != nil {
+			return nil, fmt.Errorf("iiif info: %w", err)
+		}
+
+		width, height = getResolution(img)
+	}
+
+	return &model.IIIFInfo{
+		Context:        iiifContext,
+		ID:             id,
+		Type:           iiifType,
+		Protocol:       iiifProtocol,
+		Profile:        iiifProfile,
+		Width:          width,
+		Height:         height,
+		ExtraQualities: []string{"color", "gray", "bitonal"},
+		ExtraFeatures:  []string{"mirroring", "rotationArbitrary", "sizeUpscaling"},
+	}, nil
+}
+
+// getImage method gets the image from the storage and decodes it.
+// Svg images are rasterized with their intrinsic size.
+func (t *Transform) getImage(ctx context.Context, imgInfo *model.ReuquestImageInfo) (image.Image, error) {
+	bts, err := t.storage.GetFile(ctx, imgInfo.URL)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	if imgInfo.Type == svgType {
+		return conversion.RasterizeSVG(bts, 0, 0, 0)
+	}
+
+	return decodeImage(bytes.NewBuffer(bts), imgInfo.Type)
+}
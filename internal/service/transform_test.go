@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+	"testing"
+
+	"github.com/Dyleme/image-coverter/internal/conversion"
+	"github.com/Dyleme/image-coverter/internal/model"
+	"github.com/Dyleme/image-coverter/internal/service"
+	"github.com/Dyleme/image-coverter/internal/service/mocks"
+	"github.com/Dyleme/image-coverter/internal/storage"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform_IIIFImage(t *testing.T) {
+	pngTestImage := loadImage(t, "test_data/x.png")
+	imgInfo := &model.ReuquestImageInfo{Type: "png", URL: "x.png"}
+	params := model.IIIFParams{Region: "full", Size: "pct:50", Rotation: "0", Quality: "gray", Format: "png"}
+	cachePath := "derived/x.png/iiif/full/pct:50/0/gray.png"
+
+	testCases := []struct {
+		testName     string
+		params       model.IIIFParams
+		initMock     func(*mocks.MockTransformRepo, *mocks.MockStorager)
+		wantFilename string
+		wantCached   bool
+		wantErr      error
+		wantErrAs    interface{}
+	}{
+		{
+			testName: "cached image",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return([]byte("cached"), nil)
+			},
+			wantFilename: "gray.png",
+			wantCached:   true,
+		},
+		{
+			testName: "image is made and cached",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return(nil, fmt.Errorf("get: %w", storage.ErrFileNotExist))
+				mStor.EXPECT().GetFile(gomock.Any(), imgInfo.URL).Return(pngTestImage, nil)
+				mStor.EXPECT().PutFile(gomock.Any(), cachePath, gomock.Any()).Return(nil)
+			},
+			wantFilename: "gray.png",
+		},
+		{
+			testName: "error in getting cached image",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(imgInfo, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), cachePath).Return(nil, errStorage)
+			},
+			wantErr: errStorage,
+		},
+		{
+			testName:  "invalid parameters",
+			params:    model.IIIFParams{Region: "full", Size: "max", Rotation: "400", Quality: "gray", Format: "png"},
+			initMock:  func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {},
+			wantErrAs: new(*conversion.IIIFParamError),
+		},
+		{
+			testName: "image of another user",
+			params:   params,
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(nil, errRepository)
+			},
+			wantErr: errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockTransformRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			tc.initMock(mockRepo, mockStorage)
+
+			srvc := service.NewTransform(mockRepo, mockStorage)
+
+			gotBytes, gotFilename, gotErr := srvc.IIIFImage(context.Background(), 1, 2, tc.params)
+
+			if tc.wantErrAs != nil {
+				assert.ErrorAs(t, gotErr, tc.wantErrAs)
+
+				return
+			}
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			assert.Equal(t, tc.wantFilename, gotFilename)
+
+			if tc.wantCached {
+				assert.Equal(t, []byte("cached"), gotBytes)
+			} else {
+				_, err := png.Decode(bytes.NewReader(gotBytes))
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTransform_IIIFInfo(t *testing.T) {
+	pngTestImage := loadImage(t, "test_data/x.png")
+
+	decoded, err := png.Decode(bytes.NewReader(pngTestImage))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		testName   string
+		initMock   func(*mocks.MockTransformRepo, *mocks.MockStorager)
+		wantWidth  int
+		wantHeight int
+		wantErr    error
+	}{
+		{
+			testName: "stored resolution",
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).
+					Return(&model.ReuquestImageInfo{Type: "png", URL: "x.png", Width: 30, Height: 20}, nil)
+			},
+			wantWidth:  30,
+			wantHeight: 20,
+		},
+		{
+			testName: "unknown resolution",
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(&model.ReuquestImageInfo{Type: "png", URL: "x.png"}, nil)
+				mStor.EXPECT().GetFile(gomock.Any(), "x.png").Return(pngTestImage, nil)
+			},
+			wantWidth:  decoded.Bounds().Dx(),
+			wantHeight: decoded.Bounds().Dy(),
+		},
+		{
+			testName: "image of another user",
+			initMock: func(mRep *mocks.MockTransformRepo, mStor *mocks.MockStorager) {
+				mRep.EXPECT().GetImage(gomock.Any(), 1, 2).Return(nil, errRepository)
+			},
+			wantErr: errRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			mockCtr := gomock.NewController(t)
+			defer mockCtr.Finish()
+			mockRepo := mocks.NewMockTransformRepo(mockCtr)
+			mockStorage := mocks.NewMockStorager(mockCtr)
+
+			tc.initMock(mockRepo, mockStorage)
+
+			srvc := service.NewTransform(mockRepo, mockStorage)
+
+			gotInfo, gotErr := srvc.IIIFInfo(context.Background(), 1, 2, "http://host/iiif/2")
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			assert.Equal(t, "http://host/iiif/2", gotInfo.ID)
+			assert.Equal(t, tc.wantWidth, gotInfo.Width)
+			assert.Equal(t, tc.wantHeight, gotInfo.Height)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// Query parameters added to the signed url.
+	UserParam      = "user"
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+
+	defaultMaxTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrNoKey            = errors.New("url signing key is not configured")
+	ErrMissingSignature = errors.New("url is not signed")
+	ErrInvalidSignature = errors.New("invalid url signature")
+	ErrExpired          = errors.New("signed url is expired")
+)
+
+// InvalidTTLError is returned if the requested life time of the url is not allowed.
+type InvalidTTLError struct {
+	TTL    time.Duration
+	MaxTTL time.Duration
+}
+
+func (e *InvalidTTLError) Error() string {
+	return fmt.Sprintf("ttl %v should be positive and not greater than %v", e.TTL, e.MaxTTL)
+}
+
+// Config of the url signer.
+type Config struct {
+	// Key is the secret key of the HMAC.
+	Key string
+
+	// MaxTTL is the maximum life time of the signed url, 7 days by default.
+	MaxTTL time.Duration
+}
+
+// Signer signs urls with HMAC-SHA256, so they could be used without authorization header.
+// Signature covers the path and all query parameters including the user and the expiry time.
+type Signer struct {
+	key    []byte
+	maxTTL time.Duration
+}
+
+// NewSigner is a constructor for the Signer.
+func NewSigner(config *Config) *Signer {
+	maxTTL := config.MaxTTL
+	if maxTTL == 0 {
+		maxTTL = defaultMaxTTL
+	}
+
+	return &Signer{key: []byte(config.Key), maxTTL: maxTTL}
+}
+
+// Sign method returns the query of the path signed on behalf of the user, which expires after ttl.
+// Provided query is not changed.
+func (s *Signer) Sign(path string, query url.Values, userID int, ttl time.Duration, now time.Time) (url.Values,
+	time.Time, error) {
+	if len(s.key) == 0 {
+		return nil, time.Time{}, ErrNoKey
+	}
+
+	if ttl <= 0 || ttl > s.maxTTL {
+		return nil, time.Time{}, &InvalidTTLError{TTL: ttl, MaxTTL: s.maxTTL}
+	}
+
+	expires := now.Add(ttl).Truncate(time.Second)
+
+	signed := make(url.Values, len(query)+3) //nolint:gomnd // user, expires and signature
+	for k, v := range query {
+		signed[k] = append([]string(nil), v...)
+	}
+
+	signed.Set(UserParam, strconv.Itoa(userID))
+	signed.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	signed.Del(SignatureParam)
+	signed.Set(SignatureParam, s.signature(path, signed))
+
+	return signed, expires, nil
+}
+
+// Verify method checks the signature of the path with query and returns the user, on behalf of whom it was signed.
+// Urls are never valid, if the key is not configured.
+func (s *Signer) Verify(path string, query url.Values, now time.Time) (int, error) {
+	if len(s.key) == 0 {
+		return 0, ErrNoKey
+	}
+
+	signature := query.Get(SignatureParam)
+	if signature == "" {
+		return 0, ErrMissingSignature
+	}
+
+	unsigned := make(url.Values, len(query))
+	for k, v := range query {
+		if k != SignatureParam {
+			unsigned[k] = v
+		}
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+
+	want, err := base64.RawURLEncoding.DecodeString(s.signature(path, unsigned))
+	if err != nil || !hmac.Equal(got, want) {
+		return 0, ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(unsigned.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+
+	if now.Unix() >= expires {
+		return 0, ErrExpired
+	}
+
+	userID, err := strconv.Atoi(unsigned.Get(UserParam))
+	if err != nil {
+		return 0, ErrInvalidSignature
+	}
+
+	return userID, nil
+}
+
+// signature method returns the HMAC of the path and the query without signature.
+// Query is encoded with the keys sorted, so the order of the parameters doesn't matter.
+func (s *Signer) signature(path string, query url.Values) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(query.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
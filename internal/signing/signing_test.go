@@ -0,0 +1,142 @@
+package signing_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Dyleme/image-coverter/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_Verify(t *testing.T) {
+	signer := signing.NewSigner(&signing.Config{Key: "key"})
+	now := time.Unix(1600000000, 0)
+
+	signed, expires, err := signer.Sign("/render/12", url.Values{"w": {"300"}, "fit": {"fill"}}, 2, time.Hour, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), expires)
+
+	testCases := []struct {
+		testName string
+		path     string
+		query    func() url.Values
+		now      time.Time
+		wantUser int
+		wantErr  error
+	}{
+		{
+			testName: "ok",
+			path:     "/render/12",
+			query:    func() url.Values { return signed },
+			now:      now.Add(time.Minute),
+			wantUser: 2,
+		},
+		{
+			testName: "expired",
+			path:     "/render/12",
+			query:    func() url.Values { return signed },
+			now:      now.Add(time.Hour),
+			wantErr:  signing.ErrExpired,
+		},
+		{
+			testName: "other path",
+			path:     "/render/13",
+			query:    func() url.Values { return signed },
+			now:      now,
+			wantErr:  signing.ErrInvalidSignature,
+		},
+		{
+			testName: "changed parameter",
+			path:     "/render/12",
+			query: func() url.Values {
+				q := url.Values{}
+				for k, v := range signed {
+					q[k] = v
+				}
+				q.Set("w", "3000")
+				return q
+			},
+			now:     now,
+			wantErr: signing.ErrInvalidSignature,
+		},
+		{
+			testName: "changed user",
+			path:     "/render/12",
+			query: func() url.Values {
+				q := url.Values{}
+				for k, v := range signed {
+					q[k] = v
+				}
+				q.Set(signing.UserParam, "3")
+				return q
+			},
+			now:     now,
+			wantErr: signing.ErrInvalidSignature,
+		},
+		{
+			testName: "prolonged expiry",
+			path:     "/render/12",
+			query: func() url.Values {
+				q := url.Values{}
+				for k, v := range signed {
+					q[k] = v
+				}
+				q.Set(signing.ExpiresParam, "2000000000")
+				return q
+			},
+			now:     now,
+			wantErr: signing.ErrInvalidSignature,
+		},
+		{
+			testName: "not signed",
+			path:     "/render/12",
+			query:    func() url.Values { return url.Values{"w": {"300"}} },
+			now:      now,
+			wantErr:  signing.ErrMissingSignature,
+		},
+		{
+			testName: "signed with other key",
+			path:     "/render/12",
+			query: func() url.Values {
+				other := signing.NewSigner(&signing.Config{Key: "other key"})
+				q, _, _ := other.Sign("/render/12", url.Values{"w": {"300"}, "fit": {"fill"}}, 2, time.Hour, now)
+				return q
+			},
+			now:     now,
+			wantErr: signing.ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			gotUser, gotErr := signer.Verify(tc.path, tc.query(), tc.now)
+
+			assert.ErrorIs(t, gotErr, tc.wantErr)
+			assert.Equal(t, tc.wantUser, gotUser)
+		})
+	}
+}
+
+func TestSigner_SignTTL(t *testing.T) {
+	signer := signing.NewSigner(&signing.Config{Key: "key", MaxTTL: time.Hour})
+
+	_, _, err := signer.Sign("/render/12", nil, 2, 2*time.Hour, time.Now())
+
+	var ttlErr *signing.InvalidTTLError
+	assert.ErrorAs(t, err, &ttlErr)
+
+	_, _, err = signer.Sign("/render/12", nil, 2, -time.Minute, time.Now())
+	assert.ErrorAs(t, err, &ttlErr)
+}
+
+func TestSigner_NoKey(t *testing.T) {
+	signer := signing.NewSigner(&signing.Config{})
+
+	_, _, err := signer.Sign("/render/12", nil, 2, time.Hour, time.Now())
+	assert.ErrorIs(t, err, signing.ErrNoKey)
+
+	_, err = signer.Verify("/render/12", url.Values{signing.SignatureParam: {"signature"}}, time.Now())
+	assert.ErrorIs(t, err, signing.ErrNoKey)
+}
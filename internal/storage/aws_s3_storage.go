@@ -3,10 +3,12 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/Dyleme/image-coverter/internal/logging"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -43,7 +45,12 @@ func (a *AwsStorage) GetFile(ctx context.Context, path string) ([]byte, error) {
 
 	var b []byte
 	buf := aws.NewWriteAtBuffer(b)
-	_, err := a.downloader.Download(buf, downParams)
+	_, err := a.downloader.DownloadWithContext(ctx, buf, downParams)
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+		return nil, fmt.Errorf("get file %v: %w", path, ErrFileNotExist)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("get file: %w", err)
@@ -74,12 +81,43 @@ func (a *AwsStorage) UploadFile(ctx context.Context, userID int, fileName string
 	return fileName, nil
 }
 
+// PutFile uploads a file to s3 storage with the provided key.
+// Unlike UploadFile the name isn't generated, so existing file is overwritten.
+func (a *AwsStorage) PutFile(ctx context.Context, path string, data []byte) error {
+	_, err := a.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(a.bucketName),
+		Key:    aws.String(path),
+		Body:   bytes.NewBuffer(data),
+	})
+	if err != nil {
+		return fmt.Errorf("put file: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFiles deletes all files which keys start with the prefix from s3 storage.
+func (a *AwsStorage) DeleteFiles(ctx context.Context, prefix string) error {
+	svc := s3.New(a.session)
+
+	iter := s3manager.NewDeleteListIterator(svc, &s3.ListObjectsInput{
+		Bucket: aws.String(a.bucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	if err := s3manager.NewBatchDeleteWithClient(svc).Delete(ctx, iter); err != nil {
+		return fmt.Errorf("delete files: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteFile delet a file from s3 storage.
 // Return an error if any occurs.
 func (a *AwsStorage) DeleteFile(ctx context.Context, path string) error {
 	svc := s3.New(a.session)
 
-	_, err := svc.DeleteObject(&s3.DeleteObjectInput{
+	_, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: &a.bucketName,
 		Key:    &path,
 	})
@@ -2,8 +2,15 @@ package storage
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+)
+
+const (
+	dirPermissions  = 0o755
+	filePermissions = 0o644
 )
 
 // LocalSTorage provides methods to store files localy.
@@ -19,6 +26,10 @@ func NewLocalStorage(path string) *LocalStorage {
 // GetFile takes file from the fullPath and returns it's bytes.
 func (s *LocalStorage) GetFile(fullPath string) ([]byte, error) {
 	file, err := os.Open(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("can not get file %v: %w", fullPath, ErrFileNotExist)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("can not get file: %w", err)
 	}
@@ -51,6 +62,27 @@ func (s *LocalStorage) UploadFile(userID int, fileName string, data []byte) (str
 	return fullPath, nil
 }
 
+// PutFile writes file to the path relative to the storage directory.
+// Unlike UploadFile the name isn't generated, so existing file is overwritten.
+func (s *LocalStorage) PutFile(path string, data []byte) error {
+	fullPath := filepath.Join(s.path, path)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), dirPermissions); err != nil {
+		return fmt.Errorf("can not put file: %w", err)
+	}
+
+	if err := os.WriteFile(fullPath, data, filePermissions); err != nil {
+		return fmt.Errorf("can not put file: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFiles deletes all files in the directory prefix relative to the storage directory.
+func (s *LocalStorage) DeleteFiles(prefix string) error {
+	return os.RemoveAll(filepath.Join(s.path, prefix))
+}
+
 // DeleteFile delte file whick path is fullPath.
 func (s *LocalStorage) DeleteFile(fullPath string) error {
 	return os.Remove(fullPath)
@@ -11,6 +11,9 @@ import (
 
 var ErrBucketNotExist = errors.New("bucket not exist")
 
+// ErrFileNotExist is returned by GetFile, when there is no file with the path.
+var ErrFileNotExist = errors.New("file not exist")
+
 // MinioStorage is a struct that provides methods to store files in minio storage.
 type MinioStorage struct {
 	client minio.Client
@@ -36,7 +39,7 @@ func NewMinioStorage(conf MinioConfig) (*MinioStorage, error) {
 }
 
 // GetFile method get file from minio storage and return it's bytes.
-func (m *MinioStorage) GetFile(_ context.Context, path string) ([]byte, error) {
+func (m *MinioStorage) GetFile(ctx context.Context, path string) ([]byte, error) {
 	exist, err := m.client.BucketExists("images")
 
 	if err != nil {
@@ -47,7 +50,7 @@ func (m *MinioStorage) GetFile(_ context.Context, path string) ([]byte, error) {
 		return nil, ErrBucketNotExist
 	}
 
-	obj, err := m.client.GetObject("images", path, minio.GetObjectOptions{})
+	obj, err := m.client.GetObjectWithContext(ctx, "images", path, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("cant not get file: %w", err)
 	}
@@ -55,6 +58,10 @@ func (m *MinioStorage) GetFile(_ context.Context, path string) ([]byte, error) {
 	var bf bytes.Buffer
 	_, err = bf.ReadFrom(obj)
 
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return nil, fmt.Errorf("cant not get file %v: %w", path, ErrFileNotExist)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("cant not get file: %w", err)
 	}
@@ -89,6 +96,87 @@ func (m *MinioStorage) UploadFile(_ context.Context, userID int, fileName string
 	return fileName, nil
 }
 
+// PutFile method uploads provided file to the minio storage with the provided path.
+// Unlike UploadFile the name isn't generated, so existing file is overwritten.
+func (m *MinioStorage) PutFile(_ context.Context, path string, data []byte) error {
+	exist, err := m.client.BucketExists("images")
+	if err != nil {
+		return fmt.Errorf("can not put file: %w", err)
+	}
+
+	if !exist {
+		if err := m.client.MakeBucket("images", "eu-central-1"); err != nil {
+			return fmt.Errorf("can not put file: %w", err)
+		}
+	}
+
+	bf := bytes.NewBuffer(data)
+
+	_, err = m.client.PutObject("images", path, bf, int64(bf.Len()), minio.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("can not put file: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteFiles method deletes all files which paths start with the prefix from the minio storage.
+// Listing and removing are stopped at the first error, which is returned.
+func (m *MinioStorage) DeleteFiles(ctx context.Context, prefix string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	objectsCh := make(chan string)
+	listErr := make(chan error, 1)
+
+	go func() {
+		defer close(objectsCh)
+
+		for obj := range m.client.ListObjectsV2("images", prefix, true, doneCh) {
+			if obj.Err != nil {
+				listErr <- obj.Err
+
+				return
+			}
+
+			select {
+			case objectsCh <- obj.Key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var err error
+
+	// Errors are drained till the end, so the removing goroutine isn't blocked.
+	for removeErr := range m.client.RemoveObjectsWithContext(ctx, "images", objectsCh) {
+		if err == nil {
+			err = removeErr.Err
+			cancel()
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("can not delete files: %w", err)
+	}
+
+	select {
+	case err := <-listErr:
+		return fmt.Errorf("can not list files: %w", err)
+	default:
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("can not delete files: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteFile method delete file from the minio storage and return an error if any occurs.
 func (m *MinioStorage) DeleteFile(_ context.Context, path string) error {
 	exist, err := m.client.BucketExists("images")